@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Nuxij/goch8p/aot"
+)
+
+// aotCommand translates a ROM into a Go program
+func aotCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("aot", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	machineFlags := newMachineFlags(flags)
+	pkg := flags.String("package", "main", "package to write, main for a program running the ROM")
+	out := flags.String("o", "", "Go file to write, the ROM with a .go extension if empty")
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: goch8p aot [flags] rom [-o game.go]")
+		flags.PrintDefaults()
+		return 2
+	}
+	m, err := machineFlags.parse()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	rom := positional[0]
+	data, err := os.ReadFile(rom)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	source, err := aot.Translate(data, aot.Options{Mode: m.Mode, Quirks: m.Quirks, Origin: m.Origin, Package: *pkg, Name: filepath.Base(rom)})
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if *out == "" {
+		*out = strings.TrimSuffix(rom, filepath.Ext(rom)) + ".go"
+	}
+	if err := os.WriteFile(*out, source, 0644); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
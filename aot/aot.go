@@ -0,0 +1,198 @@
+// Package aot translates ROMs ahead of time into Go programs. Every
+// instruction found by tracing control flow from the entry point becomes
+// a cpu.Native, and the program runs those on a CPU, which falls back to
+// interpreting whatever could not be found statically, such as BNNN
+// jumps into code never traced or code the ROM writes for itself.
+package aot
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+
+	"github.com/Nuxij/goch8p/cpu"
+	"github.com/Nuxij/goch8p/disasm"
+)
+
+// Options describe the machine the ROM is written for and the Go to write
+type Options struct {
+	// Mode picks the instruction set, ModeChip8 being the default
+	Mode cpu.Mode
+	// Quirks decide how the ambiguous instructions are translated
+	Quirks cpu.Quirks
+	// Origin is where the ROM is loaded, cpu.DefaultOrigin if zero
+	Origin uint16
+	// Package names the Go package written, main if empty. A main package
+	// runs the ROM, and any other exports it as Program.
+	Package string
+	// Name is what the ROM is called in the generated code
+	Name string
+}
+
+// modes names the constant for each mode in the generated code
+var modes = map[cpu.Mode]string{
+	cpu.ModeChip8:     "cpu.ModeChip8",
+	cpu.ModeSuperChip: "cpu.ModeSuperChip",
+	cpu.ModeXOChip:    "cpu.ModeXOChip",
+}
+
+// presets names the variable for each quirk preset in the generated code
+var presets = map[cpu.Quirks]string{
+	cpu.QuirksCOSMACVIP: "cpu.QuirksCOSMACVIP",
+	cpu.QuirksCHIP48:    "cpu.QuirksCHIP48",
+	cpu.QuirksSuperChip: "cpu.QuirksSuperChip",
+	cpu.QuirksXOChip:    "cpu.QuirksXOChip",
+}
+
+// Translate writes a Go source file running rom, formatted by gofmt
+func Translate(rom []byte, options Options) ([]byte, error) {
+	if options.Origin == 0 {
+		options.Origin = cpu.DefaultOrigin
+	}
+	if options.Package == "" {
+		options.Package = "main"
+	}
+	if options.Name == "" {
+		options.Name = "rom"
+	}
+	if int(options.Origin)+len(rom) > options.Mode.MemorySize() {
+		return nil, RomTooLarge{len(rom), options.Mode.MemorySize() - int(options.Origin)}
+	}
+	listing := disasm.Disassemble(rom, disasm.Options{Mode: options.Mode, Origin: options.Origin})
+	var code []disasm.Line
+	for _, line := range listing.Lines {
+		if line.Instruction != nil {
+			code = append(code, line)
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by goch8p aot from %s; DO NOT EDIT.\n\n", options.Name)
+	fmt.Fprintf(&b, "package %s\n\n", options.Package)
+	b.WriteString("import (\n\"github.com/Nuxij/goch8p/aot\"\n\"github.com/Nuxij/goch8p/cpu\"\n)\n\n")
+	program := "Program"
+	if options.Package == "main" {
+		program = "program"
+		fmt.Fprintf(&b, "func main() {\naot.Main(%s)\n}\n\n", program)
+	}
+	fmt.Fprintf(&b, "// %s is %s, translated for %s\n", program, options.Name, options.Mode)
+	fmt.Fprintf(&b, "var %s = aot.Program{\nName: %q,\nMode: %s,\nQuirks: %s,\nOrigin: 0x%03X,\nROM: rom,\nNatives: natives,\n}\n\n",
+		program, options.Name, modes[options.Mode], quirks(options.Quirks), options.Origin)
+	b.WriteString("var rom = []byte{")
+	for n, v := range rom {
+		if n%12 == 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "0x%02X, ", v)
+	}
+	b.WriteString("\n}\n\n")
+	b.WriteString("// natives holds an instruction for every address traced to code\n")
+	b.WriteString("var natives = map[uint16]cpu.Native{\n")
+	for _, line := range code {
+		fmt.Fprintf(&b, "0x%03X: {Opcode: 0x%02X%02X, Run: %s},\n", line.Addr, line.Bytes[0], line.Bytes[1], name(line.Addr))
+	}
+	b.WriteString("}\n")
+	for _, line := range code {
+		fmt.Fprintf(&b, "\n// %s is %s\nfunc %s(c *cpu.CPU) error {\n", name(line.Addr), line.Text, name(line.Addr))
+		b.WriteString(translate(line, options))
+		b.WriteString("}\n")
+	}
+	return format.Source(b.Bytes())
+}
+
+// name names the function for the instruction at addr
+func name(addr uint16) string {
+	return fmt.Sprintf("op%03X", addr)
+}
+
+// quirks writes q as Go, by name if it is a preset
+func quirks(q cpu.Quirks) string {
+	if name, ok := presets[q]; ok {
+		return name
+	}
+	return fmt.Sprintf("%#v", q)
+}
+
+// translate writes the body of the function executing line. Anything
+// beyond registers and control flow, such as drawing, the timers and the
+// stack, is left to the instruction's handler.
+func translate(line disasm.Line, options Options) string {
+	opcode := uint16(line.Bytes[0])<<8 | uint16(line.Bytes[1])
+	x, y := opcode>>8&0xF, opcode>>4&0xF
+	nn, nnn := opcode&0xFF, opcode&0xFFF
+	var b bytes.Buffer
+	switch line.Instruction.Code() {
+	case 0x1000:
+		fmt.Fprintf(&b, "c.SetPC(0x%03X)\n", nnn)
+	case 0x3000, 0x4000, 0x5000, 0x9000:
+		// XO-CHIP skips F000 NNNN whole, so how far depends on memory
+		if options.Mode >= cpu.ModeXOChip {
+			return handled(opcode)
+		}
+		operand := fmt.Sprintf("0x%02X", nn)
+		if line.Instruction.Code() == 0x5000 || line.Instruction.Code() == 0x9000 {
+			operand = fmt.Sprintf("c.V(0x%X)", y)
+		}
+		compare := "=="
+		if line.Instruction.Code() == 0x4000 || line.Instruction.Code() == 0x9000 {
+			compare = "!="
+		}
+		fmt.Fprintf(&b, "if c.V(0x%X) %s %s {\nc.IncrementPC(2)\n}\n", x, compare, operand)
+	case 0x6000:
+		fmt.Fprintf(&b, "c.SetV(0x%X, 0x%02X)\n", x, nn)
+	case 0x7000:
+		fmt.Fprintf(&b, "c.SetV(0x%X, c.V(0x%X)+0x%02X)\n", x, x, nn)
+	case 0x8000:
+		fmt.Fprintf(&b, "c.SetV(0x%X, c.V(0x%X))\n", x, y)
+	case 0x8001, 0x8002, 0x8003:
+		operator := map[uint16]string{0x8001: "|", 0x8002: "&", 0x8003: "^"}[line.Instruction.Code()]
+		fmt.Fprintf(&b, "c.SetV(0x%X, c.V(0x%X)%sc.V(0x%X))\n", x, x, operator, y)
+		if options.Quirks.LogicResetsVF {
+			b.WriteString("c.SetV(0xF, 0)\n")
+		}
+	case 0x8004:
+		fmt.Fprintf(&b, "sum := uint16(c.V(0x%X)) + uint16(c.V(0x%X))\n", x, y)
+		fmt.Fprintf(&b, "c.SetV(0x%X, byte(sum))\nc.SetV(0xF, byte(sum>>8))\n", x)
+	case 0x8005, 0x8007:
+		a, s := x, y
+		if line.Instruction.Code() == 0x8007 {
+			a, s = y, x
+		}
+		fmt.Fprintf(&b, "a, b := c.V(0x%X), c.V(0x%X)\nc.SetV(0x%X, a-b)\n", a, s, x)
+		b.WriteString("if a >= b {\nc.SetV(0xF, 1)\n} else {\nc.SetV(0xF, 0)\n}\n")
+	case 0x8006, 0x800E:
+		source := y
+		if options.Quirks.ShiftVX {
+			source = x
+		}
+		fmt.Fprintf(&b, "value := c.V(0x%X)\n", source)
+		if line.Instruction.Code() == 0x8006 {
+			fmt.Fprintf(&b, "c.SetV(0x%X, value>>1)\nc.SetV(0xF, value&0x1)\n", x)
+		} else {
+			fmt.Fprintf(&b, "c.SetV(0x%X, value<<1)\nc.SetV(0xF, value>>7)\n", x)
+		}
+	case 0xA000:
+		fmt.Fprintf(&b, "c.SetIndex(0x%03X)\n", nnn)
+	case 0xF01E:
+		fmt.Fprintf(&b, "c.SetIndex(c.Index() + uint16(c.V(0x%X)))\n", x)
+	default:
+		return handled(opcode)
+	}
+	b.WriteString("return nil\n")
+	return b.String()
+}
+
+// handled executes opcode through its handler
+func handled(opcode uint16) string {
+	return fmt.Sprintf("return c.ExecuteInstruction(0x%04X)\n", opcode)
+}
+
+// RomTooLarge is an error Translate returns when a ROM does not fit in memory
+type RomTooLarge struct {
+	size int
+	max  int
+}
+
+func (r RomTooLarge) Error() string {
+	return fmt.Sprintf("ROM is %d bytes, but only %d fit", r.size, r.max)
+}
@@ -0,0 +1,61 @@
+package aot
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Nuxij/goch8p/cpu"
+)
+
+func TestTranslate_demo(t *testing.T) {
+	rom, err := os.ReadFile("testdata/demo.ch8")
+	assert.NoError(t, err)
+	source, err := Translate(rom, Options{Quirks: cpu.QuirksCOSMACVIP, Package: "demo", Name: "demo.ch8"})
+	assert.NoError(t, err)
+	generated, err := os.ReadFile("internal/demo/demo.go")
+	assert.NoError(t, err)
+	assert.Equal(t, string(generated), string(source), "internal/demo is stale, run go generate ./aot/...")
+}
+
+// body returns the function Translate writes for the instruction at addr
+func body(t *testing.T, rom []byte, options Options, addr string) string {
+	source, err := Translate(rom, options)
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	s := string(source)
+	start := strings.Index(s, "func op"+addr+"(")
+	if !assert.NotEqual(t, -1, start, "no function for %s", addr) {
+		return ""
+	}
+	end := strings.Index(s[start:], "\n}\n")
+	return s[strings.Index(s[start:], "\n")+start+1 : start+end]
+}
+
+func TestTranslate_quirks(t *testing.T) {
+	// SHR V1, V2; OR V1, V2; SE V1, 5
+	rom := []byte{0x81, 0x26, 0x81, 0x21, 0x31, 0x05}
+	vip := Options{Quirks: cpu.QuirksCOSMACVIP}
+	chip48 := Options{Quirks: cpu.QuirksCHIP48}
+	assert.Equal(t, "\tvalue := c.V(0x2)\n\tc.SetV(0x1, value>>1)\n\tc.SetV(0xF, value&0x1)\n\treturn nil", body(t, rom, vip, "200"))
+	assert.Equal(t, "\tvalue := c.V(0x1)\n\tc.SetV(0x1, value>>1)\n\tc.SetV(0xF, value&0x1)\n\treturn nil", body(t, rom, chip48, "200"))
+	assert.Equal(t, "\tc.SetV(0x1, c.V(0x1)|c.V(0x2))\n\tc.SetV(0xF, 0)\n\treturn nil", body(t, rom, vip, "202"))
+	assert.Equal(t, "\tc.SetV(0x1, c.V(0x1)|c.V(0x2))\n\treturn nil", body(t, rom, chip48, "202"))
+	assert.Equal(t, "\tif c.V(0x1) == 0x05 {\n\t\tc.IncrementPC(2)\n\t}\n\treturn nil", body(t, rom, vip, "204"))
+	assert.Equal(t, "\treturn c.ExecuteInstruction(0x3105)", body(t, rom, Options{Mode: cpu.ModeXOChip}, "204"),
+		"XO-CHIP skips should be left to the handler, which knows how long F000 NNNN is")
+
+	source, err := Translate(rom, Options{Quirks: cpu.Quirks{ShiftVX: true}})
+	assert.NoError(t, err)
+	assert.Contains(t, string(source), "Quirks:  cpu.Quirks{ShiftVX: true, Index: 0, JumpVX: false, LogicResetsVF: false, ClipSprites: false},")
+	assert.Contains(t, string(source), "func main() {\n\taot.Main(program)\n}")
+}
+
+func TestTranslate_tooLarge(t *testing.T) {
+	_, err := Translate(make([]byte, 0xE01), Options{})
+	assert.Equal(t, RomTooLarge{0xE01, 0xE00}, err)
+	assert.EqualError(t, err, "ROM is 3585 bytes, but only 3584 fit")
+}
@@ -0,0 +1,367 @@
+// Code generated by goch8p aot from demo.ch8; DO NOT EDIT.
+
+package demo
+
+import (
+	"github.com/Nuxij/goch8p/aot"
+	"github.com/Nuxij/goch8p/cpu"
+)
+
+// Program is demo.ch8, translated for CHIP-8
+var Program = aot.Program{
+	Name:    "demo.ch8",
+	Mode:    cpu.ModeChip8,
+	Quirks:  cpu.QuirksCOSMACVIP,
+	Origin:  0x200,
+	ROM:     rom,
+	Natives: natives,
+}
+
+var rom = []byte{
+	0x6C, 0x00, 0x6A, 0x01, 0x00, 0xE0, 0xFC, 0x29, 0x61, 0x0A, 0x62, 0x0A,
+	0xD1, 0x25, 0x84, 0xC0, 0x84, 0xC4, 0x84, 0xA5, 0x84, 0xC7, 0x84, 0x4E,
+	0x85, 0x46, 0x85, 0xA1, 0x85, 0x42, 0x85, 0xC3, 0x22, 0x54, 0x3C, 0x03,
+	0x12, 0x2A, 0x60, 0x02, 0xB2, 0x4E, 0xFC, 0x15, 0xF9, 0x07, 0xE9, 0xA1,
+	0x79, 0x01, 0x99, 0xC0, 0x73, 0x01, 0x5C, 0x90, 0x73, 0x02, 0x9C, 0x90,
+	0x73, 0x04, 0xA2, 0x5A, 0xFA, 0x1E, 0x80, 0xC0, 0xF0, 0x55, 0x7C, 0x01,
+	0x66, 0x0F, 0x8C, 0x62, 0x12, 0x04, 0x12, 0x2A, 0x78, 0x01, 0x12, 0x2A,
+	0xA2, 0x5E, 0xF4, 0x33, 0xF2, 0x65, 0x7B, 0x00, 0x00, 0xEE, 0x00, 0x00,
+	0x00,
+}
+
+// natives holds an instruction for every address traced to code
+var natives = map[uint16]cpu.Native{
+	0x200: {Opcode: 0x6C00, Run: op200},
+	0x202: {Opcode: 0x6A01, Run: op202},
+	0x204: {Opcode: 0x00E0, Run: op204},
+	0x206: {Opcode: 0xFC29, Run: op206},
+	0x208: {Opcode: 0x610A, Run: op208},
+	0x20A: {Opcode: 0x620A, Run: op20A},
+	0x20C: {Opcode: 0xD125, Run: op20C},
+	0x20E: {Opcode: 0x84C0, Run: op20E},
+	0x210: {Opcode: 0x84C4, Run: op210},
+	0x212: {Opcode: 0x84A5, Run: op212},
+	0x214: {Opcode: 0x84C7, Run: op214},
+	0x216: {Opcode: 0x844E, Run: op216},
+	0x218: {Opcode: 0x8546, Run: op218},
+	0x21A: {Opcode: 0x85A1, Run: op21A},
+	0x21C: {Opcode: 0x8542, Run: op21C},
+	0x21E: {Opcode: 0x85C3, Run: op21E},
+	0x220: {Opcode: 0x2254, Run: op220},
+	0x222: {Opcode: 0x3C03, Run: op222},
+	0x224: {Opcode: 0x122A, Run: op224},
+	0x226: {Opcode: 0x6002, Run: op226},
+	0x228: {Opcode: 0xB24E, Run: op228},
+	0x22A: {Opcode: 0xFC15, Run: op22A},
+	0x22C: {Opcode: 0xF907, Run: op22C},
+	0x22E: {Opcode: 0xE9A1, Run: op22E},
+	0x230: {Opcode: 0x7901, Run: op230},
+	0x232: {Opcode: 0x99C0, Run: op232},
+	0x234: {Opcode: 0x7301, Run: op234},
+	0x236: {Opcode: 0x5C90, Run: op236},
+	0x238: {Opcode: 0x7302, Run: op238},
+	0x23A: {Opcode: 0x9C90, Run: op23A},
+	0x23C: {Opcode: 0x7304, Run: op23C},
+	0x23E: {Opcode: 0xA25A, Run: op23E},
+	0x240: {Opcode: 0xFA1E, Run: op240},
+	0x242: {Opcode: 0x80C0, Run: op242},
+	0x244: {Opcode: 0xF055, Run: op244},
+	0x246: {Opcode: 0x7C01, Run: op246},
+	0x248: {Opcode: 0x660F, Run: op248},
+	0x24A: {Opcode: 0x8C62, Run: op24A},
+	0x24C: {Opcode: 0x1204, Run: op24C},
+	0x24E: {Opcode: 0x122A, Run: op24E},
+	0x254: {Opcode: 0xA25E, Run: op254},
+	0x256: {Opcode: 0xF433, Run: op256},
+	0x258: {Opcode: 0xF265, Run: op258},
+	0x25A: {Opcode: 0x7B00, Run: op25A},
+	0x25C: {Opcode: 0x00EE, Run: op25C},
+}
+
+// op200 is LD VC, 0x00
+func op200(c *cpu.CPU) error {
+	c.SetV(0xC, 0x00)
+	return nil
+}
+
+// op202 is LD VA, 0x01
+func op202(c *cpu.CPU) error {
+	c.SetV(0xA, 0x01)
+	return nil
+}
+
+// op204 is CLS
+func op204(c *cpu.CPU) error {
+	return c.ExecuteInstruction(0x00E0)
+}
+
+// op206 is LD F, VC
+func op206(c *cpu.CPU) error {
+	return c.ExecuteInstruction(0xFC29)
+}
+
+// op208 is LD V1, 0x0A
+func op208(c *cpu.CPU) error {
+	c.SetV(0x1, 0x0A)
+	return nil
+}
+
+// op20A is LD V2, 0x0A
+func op20A(c *cpu.CPU) error {
+	c.SetV(0x2, 0x0A)
+	return nil
+}
+
+// op20C is DRW V1, V2, 5
+func op20C(c *cpu.CPU) error {
+	return c.ExecuteInstruction(0xD125)
+}
+
+// op20E is LD V4, VC
+func op20E(c *cpu.CPU) error {
+	c.SetV(0x4, c.V(0xC))
+	return nil
+}
+
+// op210 is ADD V4, VC
+func op210(c *cpu.CPU) error {
+	sum := uint16(c.V(0x4)) + uint16(c.V(0xC))
+	c.SetV(0x4, byte(sum))
+	c.SetV(0xF, byte(sum>>8))
+	return nil
+}
+
+// op212 is SUB V4, VA
+func op212(c *cpu.CPU) error {
+	a, b := c.V(0x4), c.V(0xA)
+	c.SetV(0x4, a-b)
+	if a >= b {
+		c.SetV(0xF, 1)
+	} else {
+		c.SetV(0xF, 0)
+	}
+	return nil
+}
+
+// op214 is SUBN V4, VC
+func op214(c *cpu.CPU) error {
+	a, b := c.V(0xC), c.V(0x4)
+	c.SetV(0x4, a-b)
+	if a >= b {
+		c.SetV(0xF, 1)
+	} else {
+		c.SetV(0xF, 0)
+	}
+	return nil
+}
+
+// op216 is SHL V4, V4
+func op216(c *cpu.CPU) error {
+	value := c.V(0x4)
+	c.SetV(0x4, value<<1)
+	c.SetV(0xF, value>>7)
+	return nil
+}
+
+// op218 is SHR V5, V4
+func op218(c *cpu.CPU) error {
+	value := c.V(0x4)
+	c.SetV(0x5, value>>1)
+	c.SetV(0xF, value&0x1)
+	return nil
+}
+
+// op21A is OR V5, VA
+func op21A(c *cpu.CPU) error {
+	c.SetV(0x5, c.V(0x5)|c.V(0xA))
+	c.SetV(0xF, 0)
+	return nil
+}
+
+// op21C is AND V5, V4
+func op21C(c *cpu.CPU) error {
+	c.SetV(0x5, c.V(0x5)&c.V(0x4))
+	c.SetV(0xF, 0)
+	return nil
+}
+
+// op21E is XOR V5, VC
+func op21E(c *cpu.CPU) error {
+	c.SetV(0x5, c.V(0x5)^c.V(0xC))
+	c.SetV(0xF, 0)
+	return nil
+}
+
+// op220 is CALL sub_254
+func op220(c *cpu.CPU) error {
+	return c.ExecuteInstruction(0x2254)
+}
+
+// op222 is SE VC, 0x03
+func op222(c *cpu.CPU) error {
+	if c.V(0xC) == 0x03 {
+		c.IncrementPC(2)
+	}
+	return nil
+}
+
+// op224 is JP label_22A
+func op224(c *cpu.CPU) error {
+	c.SetPC(0x22A)
+	return nil
+}
+
+// op226 is LD V0, 0x02
+func op226(c *cpu.CPU) error {
+	c.SetV(0x0, 0x02)
+	return nil
+}
+
+// op228 is JP V0, label_24E
+func op228(c *cpu.CPU) error {
+	return c.ExecuteInstruction(0xB24E)
+}
+
+// op22A is LD DT, VC
+func op22A(c *cpu.CPU) error {
+	return c.ExecuteInstruction(0xFC15)
+}
+
+// op22C is LD V9, DT
+func op22C(c *cpu.CPU) error {
+	return c.ExecuteInstruction(0xF907)
+}
+
+// op22E is SKNP V9
+func op22E(c *cpu.CPU) error {
+	return c.ExecuteInstruction(0xE9A1)
+}
+
+// op230 is ADD V9, 0x01
+func op230(c *cpu.CPU) error {
+	c.SetV(0x9, c.V(0x9)+0x01)
+	return nil
+}
+
+// op232 is SNE V9, VC
+func op232(c *cpu.CPU) error {
+	if c.V(0x9) != c.V(0xC) {
+		c.IncrementPC(2)
+	}
+	return nil
+}
+
+// op234 is ADD V3, 0x01
+func op234(c *cpu.CPU) error {
+	c.SetV(0x3, c.V(0x3)+0x01)
+	return nil
+}
+
+// op236 is SE VC, V9
+func op236(c *cpu.CPU) error {
+	if c.V(0xC) == c.V(0x9) {
+		c.IncrementPC(2)
+	}
+	return nil
+}
+
+// op238 is ADD V3, 0x02
+func op238(c *cpu.CPU) error {
+	c.SetV(0x3, c.V(0x3)+0x02)
+	return nil
+}
+
+// op23A is SNE VC, V9
+func op23A(c *cpu.CPU) error {
+	if c.V(0xC) != c.V(0x9) {
+		c.IncrementPC(2)
+	}
+	return nil
+}
+
+// op23C is ADD V3, 0x04
+func op23C(c *cpu.CPU) error {
+	c.SetV(0x3, c.V(0x3)+0x04)
+	return nil
+}
+
+// op23E is LD I, label_25A
+func op23E(c *cpu.CPU) error {
+	c.SetIndex(0x25A)
+	return nil
+}
+
+// op240 is ADD I, VA
+func op240(c *cpu.CPU) error {
+	c.SetIndex(c.Index() + uint16(c.V(0xA)))
+	return nil
+}
+
+// op242 is LD V0, VC
+func op242(c *cpu.CPU) error {
+	c.SetV(0x0, c.V(0xC))
+	return nil
+}
+
+// op244 is LD [I], V0
+func op244(c *cpu.CPU) error {
+	return c.ExecuteInstruction(0xF055)
+}
+
+// op246 is ADD VC, 0x01
+func op246(c *cpu.CPU) error {
+	c.SetV(0xC, c.V(0xC)+0x01)
+	return nil
+}
+
+// op248 is LD V6, 0x0F
+func op248(c *cpu.CPU) error {
+	c.SetV(0x6, 0x0F)
+	return nil
+}
+
+// op24A is AND VC, V6
+func op24A(c *cpu.CPU) error {
+	c.SetV(0xC, c.V(0xC)&c.V(0x6))
+	c.SetV(0xF, 0)
+	return nil
+}
+
+// op24C is JP label_204
+func op24C(c *cpu.CPU) error {
+	c.SetPC(0x204)
+	return nil
+}
+
+// op24E is JP label_22A
+func op24E(c *cpu.CPU) error {
+	c.SetPC(0x22A)
+	return nil
+}
+
+// op254 is LD I, data_25E
+func op254(c *cpu.CPU) error {
+	c.SetIndex(0x25E)
+	return nil
+}
+
+// op256 is LD B, V4
+func op256(c *cpu.CPU) error {
+	return c.ExecuteInstruction(0xF433)
+}
+
+// op258 is LD V2, [I]
+func op258(c *cpu.CPU) error {
+	return c.ExecuteInstruction(0xF265)
+}
+
+// op25A is ADD VB, 0x00
+func op25A(c *cpu.CPU) error {
+	c.SetV(0xB, c.V(0xB)+0x00)
+	return nil
+}
+
+// op25C is RET
+func op25C(c *cpu.CPU) error {
+	return c.ExecuteInstruction(0x00EE)
+}
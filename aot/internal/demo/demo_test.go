@@ -0,0 +1,47 @@
+package demo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Nuxij/goch8p/cpu"
+)
+
+func TestProgram(t *testing.T) {
+	interpreted, err := Program.NewCPU()
+	assert.NoError(t, err)
+	translated, err := Program.NewCPU(cpu.WithNatives(Program.Natives))
+	assert.NoError(t, err)
+	assert.NoError(t, cpu.Lockstep(interpreted, translated, 20000))
+
+	ran, fellBack := translated.NativeCounts()
+	assert.NotZero(t, ran)
+	// the code jumped into through V0, and the ADD patched with a count
+	// above zero
+	assert.NotZero(t, fellBack)
+	assert.Greater(t, ran, 10*fellBack, "most instructions should run natively")
+}
+
+func BenchmarkProgram(b *testing.B) {
+	for _, natives := range []bool{false, true} {
+		name := "interpreted"
+		var options []cpu.Option
+		if natives {
+			name = "translated"
+			options = append(options, cpu.WithNatives(Program.Natives))
+		}
+		b.Run(name, func(b *testing.B) {
+			c, err := Program.NewCPU(options...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				if err := c.RunFrame(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
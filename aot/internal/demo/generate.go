@@ -0,0 +1,5 @@
+// Package demo is the demo ROM in aot/testdata, translated, for testing
+// translations against the interpreter
+package demo
+
+//go:generate go run ../../.. aot -package demo -o demo.go ../../testdata/demo.ch8
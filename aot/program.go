@@ -0,0 +1,70 @@
+package aot
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Nuxij/goch8p/cpu"
+	"github.com/Nuxij/goch8p/headless"
+	"github.com/Nuxij/goch8p/terminal"
+)
+
+// Program is a ROM translated by Translate, as the generated code holds it
+type Program struct {
+	Name   string
+	Mode   cpu.Mode
+	Quirks cpu.Quirks
+	Origin uint16
+	ROM    []byte
+	// Natives are the instructions translated, by address
+	Natives map[uint16]cpu.Native
+}
+
+// NewCPU builds a CPU for the machine p was translated for with the ROM
+// loaded, interpreting it unless the natives are added with
+// cpu.WithNatives(p.Natives)
+func (p Program) NewCPU(options ...cpu.Option) (*cpu.CPU, error) {
+	options = append([]cpu.Option{cpu.WithMode(p.Mode), cpu.WithQuirks(p.Quirks)}, options...)
+	c := cpu.NewCPU(cpu.NewRAM(p.Mode.MemorySize()), options...)
+	if err := c.Memory().Poke(p.Origin, p.ROM); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Main runs p as a command. Given -frames it runs that many frames and
+// prints the screen, otherwise it plays in the terminal until quit.
+func Main(p Program) {
+	frames := flag.Int("frames", 0, "frames to run headless before printing the screen, 0 to play in the terminal")
+	speed := flag.Int("speed", cpu.DefaultInstructionsPerFrame, "instructions per frame")
+	flag.Parse()
+	c, err := p.NewCPU(cpu.WithNatives(p.Natives), cpu.WithInstructionsPerFrame(*speed))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *frames > 0 {
+		os.Exit(runHeadless(c, *frames))
+	}
+	err = terminal.Play(c, terminal.Options{Title: p.Name, Status: func() string {
+		ran, interpreted := c.NativeCounts()
+		return fmt.Sprintf("%d native, %d interpreted", ran, interpreted)
+	}})
+	if err != nil && !cpu.IsHalted(err) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// runHeadless runs frames frames and prints the screen, returning 1 if
+// the program faulted
+func runHeadless(c *cpu.CPU, frames int) int {
+	err := headless.Run(c, frames)
+	fmt.Print(c.Frame())
+	if err != nil && !cpu.IsHalted(err) {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
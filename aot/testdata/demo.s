@@ -0,0 +1,58 @@
+; demo counts through the hex digits in VC, working each through the ALU,
+; jumping through V0 into code only reached that way and patching an
+; instruction of its own, so translation leaves some to the interpreter
+start:
+    LD VC, 0
+    LD VA, 1
+loop:
+    CLS
+    LD F, VC
+    LD V1, 10
+    LD V2, 10
+    DRW V1, V2, 5
+    LD V4, VC
+    ADD V4, VC
+    SUB V4, VA
+    SUBN V4, VC
+    SHL V4, V4
+    SHR V5, V4
+    OR V5, VA
+    AND V5, V4
+    XOR V5, VC
+    CALL store
+    SE VC, 3
+    JP next
+    LD V0, 2
+    JP V0, targets
+next:
+    LD DT, VC
+    LD V9, DT
+    SKNP V9
+    ADD V9, 1
+    SNE V9, VC
+    ADD V3, 1
+    SE VC, V9
+    ADD V3, 2
+    SNE VC, V9
+    ADD V3, 4
+    LD I, patch
+    ADD I, VA
+    LD V0, VC
+    LD [I], V0
+    ADD VC, 1
+    LD V6, 0x0F
+    AND VC, V6
+    JP loop
+targets:
+    JP next
+    ADD V8, 1
+    JP next
+store:
+    LD I, scratch
+    LD B, V4
+    LD V2, [I]
+patch:
+    ADD VB, 0
+    RET
+scratch:
+    db 0, 0, 0
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAotCommand(t *testing.T) {
+	dir := t.TempDir()
+	rom := filepath.Join(dir, "spin.ch8")
+	out := filepath.Join(dir, "spin.go")
+	// JP 0x200
+	assert.NoError(t, os.WriteFile(rom, []byte{0x12, 0x00}, 0644))
+
+	var stdout, stderr strings.Builder
+	assert.Equal(t, 0, aotCommand([]string{rom, "-o", out}, &stdout, &stderr), stderr.String())
+	source, err := os.ReadFile(out)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(source), "// Code generated by goch8p aot from spin.ch8; DO NOT EDIT.\n\npackage main\n"))
+	assert.Contains(t, string(source), "0x200: {Opcode: 0x1200, Run: op200},")
+
+	assert.Equal(t, 2, aotCommand([]string{"-quirks", "octo", rom}, &stdout, &stderr))
+	assert.Equal(t, "unknown quirk preset \"octo\"\n", stderr.String())
+}
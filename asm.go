@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Nuxij/goch8p/asm"
+	"github.com/Nuxij/goch8p/octo"
+)
+
+// asmCommand assembles a source file into a ROM, compiling it as Octo if
+// it has a .8o extension. It exits 1 if the source has mistakes in it.
+func asmCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("asm", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	machineFlags := newMachineFlags(flags)
+	out := flags.String("o", "", "ROM to write, the source with a .ch8 extension if empty")
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: goch8p asm [flags] source [-o game.ch8]")
+		flags.PrintDefaults()
+		return 2
+	}
+	m, err := machineFlags.parse()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	source := positional[0]
+	var p *asm.Program
+	if filepath.Ext(source) == ".8o" {
+		var compiled *octo.Program
+		if compiled, err = octo.CompileFile(source); err == nil {
+			p = &compiled.Program
+		}
+	} else {
+		p, err = asm.AssembleFile(source, asm.Options{Mode: m.Mode, Origin: m.Origin})
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if *out == "" {
+		*out = strings.TrimSuffix(source, filepath.Ext(source)) + ".ch8"
+	}
+	if err := os.WriteFile(*out, p.ROM, 0644); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,510 @@
+// Package asm assembles source into ROMs, reading the syntax the disasm
+// package writes along with labels, constants, data and includes
+package asm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Nuxij/goch8p/cpu"
+)
+
+// Options tune an assembly
+type Options struct {
+	// Mode picks the instruction set, ModeChip8 being the default
+	Mode cpu.Mode
+	// Origin is where the ROM is loaded, cpu.DefaultOrigin if zero
+	Origin uint16
+	// ReadFile reads included files, os.ReadFile if nil
+	ReadFile func(name string) ([]byte, error)
+}
+
+// Program is an assembled ROM
+type Program struct {
+	Origin uint16
+	ROM    []byte
+	// Labels maps each label to its address
+	Labels map[string]uint16
+}
+
+// SourceInvalid is returned assembling source that has a mistake in it
+type SourceInvalid struct {
+	at     position
+	reason string
+}
+
+func (s SourceInvalid) Error() string {
+	return fmt.Sprintf("%s: %s", s.at, s.reason)
+}
+
+// position is a line of a source file
+type position struct {
+	file string
+	line int
+}
+
+func (p position) String() string {
+	return fmt.Sprintf("%s:%d", p.file, p.line)
+}
+
+// statement is a line that assembles to size bytes at addr
+type statement struct {
+	at   position
+	addr int
+	size int
+	emit func(s *scope, out []byte) error
+}
+
+// form is one way of writing an instruction, such as SE Vx, byte
+type form struct {
+	instruction cpu.Instruction
+	operands    []string
+}
+
+type assembler struct {
+	options Options
+	origin  int
+	// forms holds the forms of each mnemonic in the mode, and every holds
+	// them across all modes to tell unknown instructions from unavailable
+	forms      map[string][]form
+	every      map[string][]form
+	symbols    map[string]*symbol
+	statements []statement
+	addr       int
+	// including is the chain of files being read, to catch include cycles
+	including []string
+}
+
+// Assemble assembles source, naming it name in errors. Each line holds
+// an optional label, then an instruction, a directive or a constant,
+// then an optional ; comment:
+//
+//	SPEED = 2                  ; constants may be expressions
+//	start: LD V0, SPEED * 4    ; instructions use Cowgod's mnemonics
+//	    LD I, sprite
+//	    DRW V0, V0, sprite.end - sprite
+//	    JP $                   ; $ is the current address
+//	sprite:
+//	    db ..####.., 0b01000010 ; data as sprite rows or numbers
+//	    dw 0x1234, start
+//	sprite.end:
+//	    include "font.s"       ; relative to the including file
+//	    org 0x300              ; pads with zeros up to the address
+func Assemble(name string, source []byte, options Options) (*Program, error) {
+	a := &assembler{
+		options: options,
+		origin:  int(options.Origin),
+		forms:   forms(options.Mode),
+		every:   forms(cpu.ModeXOChip),
+		symbols: make(map[string]*symbol),
+	}
+	if a.origin == 0 {
+		a.origin = cpu.DefaultOrigin
+	}
+	a.addr = a.origin
+	if err := a.file(name, source); err != nil {
+		return nil, err
+	}
+	return a.emit()
+}
+
+// AssembleFile assembles the source file at path
+func AssembleFile(path string, options Options) (*Program, error) {
+	readFile := options.ReadFile
+	if readFile == nil {
+		readFile = os.ReadFile
+	}
+	source, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Assemble(path, source, options)
+}
+
+// MustAssemble assembles source, panicking if it is invalid. It suits
+// tests embedding programs.
+func MustAssemble(source string, options Options) *Program {
+	p, err := Assemble("source", []byte(source), options)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// forms indexes the instructions in mode by mnemonic
+func forms(mode cpu.Mode) map[string][]form {
+	forms := make(map[string][]form)
+	for _, i := range mode.Instructions() {
+		mnemonic, operands := cpu.SplitSyntax(cpu.Syntax[i.Code()])
+		forms[mnemonic] = append(forms[mnemonic], form{i, operands})
+	}
+	return forms
+}
+
+// file reads the statements in a source file
+func (a *assembler) file(name string, source []byte) error {
+	a.including = append(a.including, name)
+	defer func() {
+		a.including = a.including[:len(a.including)-1]
+	}()
+	for n, text := range strings.Split(string(source), "\n") {
+		if err := a.line(position{name, n + 1}, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	labelPattern    = regexp.MustCompile(`^([A-Za-z_][\w.]*):`)
+	constantPattern = regexp.MustCompile(`^([A-Za-z_][\w.]*)(?:\s*=|\s+(?i:equ)\s)(.*)$`)
+)
+
+// line reads the labels and statement on a line
+func (a *assembler) line(at position, text string) error {
+	text = strings.TrimSpace(uncomment(text))
+	for {
+		label := labelPattern.FindStringSubmatch(text)
+		if label == nil {
+			break
+		}
+		if err := a.define(at, label[1], &symbol{value: a.addr, resolved: true}); err != nil {
+			return err
+		}
+		text = strings.TrimSpace(text[len(label[0]):])
+	}
+	if text == "" {
+		return nil
+	}
+	if constant := constantPattern.FindStringSubmatch(text); constant != nil {
+		expr, err := parseExpression(strings.TrimSpace(constant[2]))
+		if err != nil {
+			return SourceInvalid{at, err.Error()}
+		}
+		return a.define(at, constant[1], &symbol{expr: expr, here: a.addr})
+	}
+	mnemonic, rest := text, ""
+	if n := strings.IndexAny(text, " \t"); n >= 0 {
+		mnemonic, rest = text[:n], strings.TrimSpace(text[n:])
+	}
+	var operands []string
+	if rest != "" {
+		operands = split(rest)
+	}
+	switch mnemonic = strings.ToUpper(mnemonic); mnemonic {
+	case "DB", "DW":
+		return a.data(at, mnemonic, operands)
+	case "ORG":
+		return a.org(at, operands)
+	case "INCLUDE":
+		return a.include(at, operands)
+	}
+	return a.instruction(at, mnemonic, operands)
+}
+
+// uncomment strips a ; comment from a line, minding strings
+func uncomment(text string) string {
+	quoted := false
+	for n := 0; n < len(text); n++ {
+		switch text[n] {
+		case '\\':
+			n++
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				return text[:n]
+			}
+		}
+	}
+	return text
+}
+
+// split splits operands on commas, minding strings
+func split(text string) []string {
+	var operands []string
+	quoted, start := false, 0
+	for n := 0; n < len(text); n++ {
+		switch text[n] {
+		case '\\':
+			n++
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				operands = append(operands, strings.TrimSpace(text[start:n]))
+				start = n + 1
+			}
+		}
+	}
+	return append(operands, strings.TrimSpace(text[start:]))
+}
+
+func (a *assembler) define(at position, name string, sym *symbol) error {
+	if reserved(name) {
+		return SourceInvalid{at, fmt.Sprintf("%s is a register and cannot be defined", name)}
+	}
+	if existing, ok := a.symbols[name]; ok {
+		return SourceInvalid{at, fmt.Sprintf("%s is already defined at %s", name, existing.at)}
+	}
+	sym.at = at
+	a.symbols[name] = sym
+	return nil
+}
+
+// add places a statement of size bytes at the current address
+func (a *assembler) add(at position, size int, emit func(s *scope, out []byte) error) error {
+	if a.addr+size > a.options.Mode.MemorySize() {
+		return SourceInvalid{at, fmt.Sprintf("%X is past the end of %s memory", a.addr+size-1, a.options.Mode)}
+	}
+	a.statements = append(a.statements, statement{at, a.addr, size, emit})
+	a.addr += size
+	return nil
+}
+
+// data reads a db or dw directive, whose operands are expressions or,
+// for db, strings
+func (a *assembler) data(at position, directive string, operands []string) error {
+	if len(operands) == 0 {
+		return SourceInvalid{at, fmt.Sprintf("%s needs at least one value", strings.ToLower(directive))}
+	}
+	width, min, max := 1, -0x80, 0xFF
+	if directive == "DW" {
+		width, min, max = 2, -0x8000, 0xFFFF
+	}
+	for _, operand := range operands {
+		if strings.HasPrefix(operand, `"`) && directive == "DB" {
+			text, err := strconv.Unquote(operand)
+			if err != nil {
+				return SourceInvalid{at, fmt.Sprintf("bad string %s", operand)}
+			}
+			if err := a.add(at, len(text), func(s *scope, out []byte) error {
+				copy(out, text)
+				return nil
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+		expr, err := parseExpression(operand)
+		if err != nil {
+			return SourceInvalid{at, err.Error()}
+		}
+		if err := a.add(at, width, func(s *scope, out []byte) error {
+			value, err := bounded(s, expr, operand, min, max)
+			if width == 2 {
+				out[0], out = byte(value>>8), out[1:]
+			}
+			out[0] = byte(value)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// org moves on to an address, padding the ROM up to it with zeros
+func (a *assembler) org(at position, operands []string) error {
+	if len(operands) != 1 {
+		return SourceInvalid{at, "org needs an address"}
+	}
+	expr, err := parseExpression(operands[0])
+	if err != nil {
+		return SourceInvalid{at, err.Error()}
+	}
+	addr, err := expr(&scope{symbols: a.symbols, here: a.addr})
+	if err != nil {
+		return SourceInvalid{at, err.Error()}
+	}
+	if addr < a.addr {
+		return SourceInvalid{at, fmt.Sprintf("org %X is behind the current address %X", addr, a.addr)}
+	}
+	return a.add(at, addr-a.addr, func(s *scope, out []byte) error {
+		return nil
+	})
+}
+
+// include reads another file's statements in place, the file being found
+// relative to the one including it
+func (a *assembler) include(at position, operands []string) error {
+	if len(operands) != 1 {
+		return SourceInvalid{at, "include needs a file name"}
+	}
+	name, err := strconv.Unquote(operands[0])
+	if err != nil {
+		return SourceInvalid{at, fmt.Sprintf("include needs a quoted file name, not %s", operands[0])}
+	}
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(filepath.Dir(at.file), name)
+	}
+	for _, file := range a.including {
+		if file == name {
+			return SourceInvalid{at, fmt.Sprintf("%s includes itself", name)}
+		}
+	}
+	readFile := a.options.ReadFile
+	if readFile == nil {
+		readFile = os.ReadFile
+	}
+	source, err := readFile(name)
+	if err != nil {
+		return SourceInvalid{at, err.Error()}
+	}
+	return a.file(name, source)
+}
+
+// instruction reads an instruction, matching its operands against the
+// forms of its mnemonic
+func (a *assembler) instruction(at position, mnemonic string, operands []string) error {
+	for _, f := range a.forms[mnemonic] {
+		if encode, ok := f.bind(operands); ok {
+			return a.encoder(at, f, encode)
+		}
+	}
+	for _, f := range a.every[mnemonic] {
+		if _, ok := f.bind(operands); ok {
+			return SourceInvalid{at, fmt.Sprintf("%s is not available in %s", f.instruction.Name(), a.options.Mode)}
+		}
+	}
+	if _, ok := a.every[mnemonic]; ok {
+		return SourceInvalid{at, fmt.Sprintf("no form of %s takes %q", mnemonic, strings.Join(operands, ", "))}
+	}
+	return SourceInvalid{at, fmt.Sprintf("unknown instruction %q", mnemonic)}
+}
+
+// field is an operand bound to the placeholder it fills
+type field struct {
+	placeholder string
+	source      string
+}
+
+// bind matches operands against the form, returning the fields that
+// encode them
+func (f form) bind(operands []string) ([]field, bool) {
+	if len(operands) != len(f.operands) {
+		return nil, false
+	}
+	var fields []field
+	for n, want := range f.operands {
+		got := operands[n]
+		switch want {
+		case "Vx", "Vy":
+			if register(got) < 0 {
+				return nil, false
+			}
+		case "byte", "nibble", "addr", "n":
+			if reserved(got) || got == "[I]" || strings.HasPrefix(strings.ToUpper(got), "LONG ") {
+				return nil, false
+			}
+		case "LONG long":
+			if !strings.HasPrefix(strings.ToUpper(got), "LONG ") {
+				return nil, false
+			}
+			want, got = "long", strings.TrimSpace(got[len("LONG "):])
+		default:
+			if !strings.EqualFold(strings.ReplaceAll(got, " ", ""), want) {
+				return nil, false
+			}
+			continue
+		}
+		fields = append(fields, field{want, got})
+	}
+	return fields, true
+}
+
+// bounds gives the range of each placeholder taking an expression
+var bounds = map[string][2]int{
+	"byte":   {-0x80, 0xFF},
+	"nibble": {0, 0xF},
+	"n":      {0, 0xF},
+	"addr":   {0, 0xFFF},
+	"long":   {0, 0xFFFF},
+}
+
+// encoder adds an instruction, parsing its expressions now and
+// evaluating them once every label is known
+func (a *assembler) encoder(at position, f form, fields []field) error {
+	exprs := make([]expression, len(fields))
+	for n, fl := range fields {
+		if _, ok := bounds[fl.placeholder]; !ok {
+			continue
+		}
+		expr, err := parseExpression(fl.source)
+		if err != nil {
+			return SourceInvalid{at, err.Error()}
+		}
+		exprs[n] = expr
+	}
+	return a.add(at, int(cpu.Length(f.instruction)), func(s *scope, out []byte) error {
+		opcode, long := int(f.instruction.Code()), 0
+		for n, fl := range fields {
+			var value int
+			if exprs[n] == nil {
+				value = register(fl.source)
+			} else {
+				var err error
+				bound := bounds[fl.placeholder]
+				if value, err = bounded(s, exprs[n], fl.source, bound[0], bound[1]); err != nil {
+					return err
+				}
+			}
+			switch fl.placeholder {
+			case "Vx", "n":
+				opcode |= value << 8
+			case "Vy":
+				opcode |= value << 4
+			case "byte":
+				opcode |= value & 0xFF
+			case "nibble", "addr":
+				opcode |= value
+			case "long":
+				long = value
+			}
+		}
+		out[0], out[1] = byte(opcode>>8), byte(opcode)
+		if len(out) == 4 {
+			out[2], out[3] = byte(long>>8), byte(long)
+		}
+		return nil
+	})
+}
+
+// bounded evaluates an expression, failing if it falls outside min to max
+func bounded(s *scope, expr expression, source string, min, max int) (int, error) {
+	value, err := expr(s)
+	if err != nil {
+		return 0, err
+	}
+	if value < min || value > max {
+		return 0, fmt.Errorf("%s is %d, out of range %d to %d", source, value, min, max)
+	}
+	return value, nil
+}
+
+// emit evaluates every statement into the ROM
+func (a *assembler) emit() (*Program, error) {
+	p := &Program{
+		Origin: uint16(a.origin),
+		ROM:    make([]byte, a.addr-a.origin),
+		Labels: make(map[string]uint16),
+	}
+	s := &scope{symbols: a.symbols}
+	for _, st := range a.statements {
+		s.here = st.addr
+		at := st.addr - a.origin
+		if err := st.emit(s, p.ROM[at:at+st.size]); err != nil {
+			return nil, SourceInvalid{st.at, err.Error()}
+		}
+	}
+	for name, sym := range a.symbols {
+		if sym.expr == nil {
+			p.Labels[name] = uint16(sym.value)
+		}
+	}
+	return p, nil
+}
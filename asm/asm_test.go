@@ -0,0 +1,140 @@
+package asm
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/Nuxij/goch8p/cpu"
+	"github.com/Nuxij/goch8p/disasm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssemble(t *testing.T) {
+	source := `
+SPEED = 2                       ; constants may refer forwards
+HEIGHT equ sprite.end - sprite
+
+start:
+    cls
+    LD V0, SPEED * 4
+    LD I, sprite
+    DRW V0, V0, HEIGHT
+    ADD V1, -1
+    SE V0, V1
+loop: JP $
+sprite:
+    db ..####.., 0b01000010
+    db #, "AB"
+sprite.end:
+    dw 0x1234, start
+    org 0x21A
+    LD [I], V3
+`
+	p, err := Assemble("test.s", []byte(source), Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x00, 0xE0, // 200: CLS
+		0x60, 0x08, // 202: LD V0, 8
+		0xA2, 0x0E, // 204: LD I, 20E
+		0xD0, 0x05, // 206: DRW V0, V0, 5
+		0x71, 0xFF, // 208: ADD V1, -1
+		0x50, 0x10, // 20A: SE V0, V1
+		0x12, 0x0C, // 20C: JP 20C
+		0x3C, 0x42, 0x80, 'A', 'B', // 20E: sprite
+		0x12, 0x34, 0x02, 0x00, // 213: words
+		0x00, 0x00, 0x00, // 217: padding
+		0xF3, 0x55, // 21A: LD [I], V3
+	}, p.ROM)
+	assert.EqualValues(t, 0x200, p.Origin)
+	assert.Equal(t, map[string]uint16{"start": 0x200, "loop": 0x20C, "sprite": 0x20E, "sprite.end": 0x213}, p.Labels)
+}
+
+func TestAssemble_include(t *testing.T) {
+	files := map[string]string{
+		"game/main.s":       "CALL draw\ninclude \"lib/draw.s\"",
+		"game/lib/draw.s":   "draw: DRW V0, V1, 5\n    RET\ninclude \"font.s\"",
+		"game/lib/font.s":   "db 0xF0",
+		"game/loop.s":       "include \"loop.s\"",
+		"game/undefined.s":  "\n\ninclude \"missing.s\"",
+		"game/lib/broken.s": "JP nowhere",
+	}
+	options := Options{ReadFile: func(name string) ([]byte, error) {
+		if source, ok := files[name]; ok {
+			return []byte(source), nil
+		}
+		return nil, os.ErrNotExist
+	}}
+	p, err := AssembleFile("game/main.s", options)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x22, 0x02, 0xD0, 0x15, 0x00, 0xEE, 0xF0}, p.ROM)
+
+	_, err = AssembleFile("game/loop.s", options)
+	assert.EqualError(t, err, "game/loop.s:1: game/loop.s includes itself")
+	_, err = AssembleFile("game/undefined.s", options)
+	assert.EqualError(t, err, "game/undefined.s:3: file does not exist")
+	_, err = Assemble("game/main.s", []byte("\ninclude \"lib/broken.s\""), options)
+	assert.EqualError(t, err, `game/lib/broken.s:1: undefined symbol "nowhere"`)
+}
+
+func TestAssemble_errors(t *testing.T) {
+	tests := []struct {
+		source string
+		mode   cpu.Mode
+		want   string
+	}{
+		{"CLS\nFOO V0", cpu.ModeChip8, `x.s:2: unknown instruction "FOO"`},
+		{"LD I, V0", cpu.ModeChip8, `x.s:1: no form of LD takes "I, V0"`},
+		{"LD V0, 256", cpu.ModeChip8, "x.s:1: 256 is 256, out of range -128 to 255"},
+		{"DRW V0, V1, 16", cpu.ModeChip8, "x.s:1: 16 is 16, out of range 0 to 15"},
+		{"JP 0x1000", cpu.ModeXOChip, "x.s:1: 0x1000 is 4096, out of range 0 to 4095"},
+		{"\n\nHIGH", cpu.ModeChip8, "x.s:3: High Resolution is not available in CHIP-8"},
+		{"a: CLS\na: CLS", cpu.ModeChip8, "x.s:2: a is already defined at x.s:1"},
+		{"I = 3", cpu.ModeChip8, "x.s:1: I is a register and cannot be defined"},
+		{"X = Y\nY = X\ndb X", cpu.ModeChip8, `x.s:3: X, defined at x.s:1: Y, defined at x.s:2: symbol "X" is defined in terms of itself`},
+		{"db (1", cpu.ModeChip8, `x.s:1: invalid expression "(1": want )`},
+		{"db 1 / 0", cpu.ModeChip8, "x.s:1: division by zero"},
+		{"db", cpu.ModeChip8, "x.s:1: db needs at least one value"},
+		{"org 0x300\norg 0x200", cpu.ModeChip8, "x.s:2: org 200 is behind the current address 300"},
+		{"org 0xFFF\nCLS", cpu.ModeChip8, "x.s:2: 1000 is past the end of CHIP-8 memory"},
+	}
+	for _, test := range tests {
+		_, err := Assemble("x.s", []byte(test.source), Options{Mode: test.mode})
+		assert.EqualError(t, err, test.want, test.source)
+	}
+}
+
+// Anything the disassembler writes should assemble back to the same ROM
+func TestAssemble_disassembly(t *testing.T) {
+	for _, i := range cpu.ModeXOChip.Instructions() {
+		rom := []byte{byte(i.Code()>>8 | 0x0A5B>>8&^i.Mask()>>8), byte(i.Code() | 0x0A5B&^i.Mask()), 0x12, 0x34}
+		rom = rom[:cpu.Length(i)]
+		listing := disasm.Disassemble(rom, disasm.Options{Mode: cpu.ModeXOChip})
+		p, err := Assemble(i.Name(), []byte(listing.String()), Options{Mode: cpu.ModeXOChip})
+		if assert.NoError(t, err, listing.String()) {
+			assert.Equal(t, rom, p.ROM, listing.String())
+		}
+	}
+
+	rom := []byte{
+		0x00, 0xE0, 0xA2, 0x10, 0x22, 0x0A, 0x12, 0x04, 0xFF, 0xFF,
+		0x30, 0x01, 0x60, 0x05, 0x00, 0xEE, 0xF0, 0x90, 0x90,
+	}
+	listing := disasm.Disassemble(rom, disasm.Options{})
+	p := MustAssemble(listing.String(), Options{})
+	assert.Equal(t, rom, p.ROM)
+	assert.Equal(t, uint16(0x20A), p.Labels["sub_20A"])
+}
+
+func ExampleMustAssemble() {
+	p := MustAssemble(`
+    LD V0, 1
+    CALL double
+    JP $
+double:
+    ADD V0, V0
+    RET
+`, Options{})
+	fmt.Printf("% X\n", p.ROM)
+	// Output: 60 01 22 06 12 04 80 04 00 EE
+}
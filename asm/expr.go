@@ -0,0 +1,318 @@
+package asm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// expression is a parsed operand, evaluated once every label is known
+type expression func(s *scope) (int, error)
+
+// scope resolves the names an expression refers to
+type scope struct {
+	symbols map[string]*symbol
+	// here is the address of the statement being evaluated, read as $
+	here int
+}
+
+// symbol is a label or constant. Constants are evaluated the first time
+// they are used, so they may refer to labels defined after them.
+type symbol struct {
+	value    int
+	resolved bool
+	// resolving catches constants defined in terms of themselves
+	resolving bool
+	expr      expression
+	here      int
+	at        position
+}
+
+func (s *scope) lookup(name string) (int, error) {
+	sym, ok := s.symbols[name]
+	if !ok {
+		return 0, fmt.Errorf("undefined symbol %q", name)
+	}
+	if sym.resolved {
+		return sym.value, nil
+	}
+	if sym.resolving {
+		return 0, fmt.Errorf("symbol %q is defined in terms of itself", name)
+	}
+	sym.resolving = true
+	here := s.here
+	s.here = sym.here
+	value, err := sym.expr(s)
+	s.here = here
+	sym.resolving = false
+	if err != nil {
+		return 0, fmt.Errorf("%s, defined at %s: %v", name, sym.at, err)
+	}
+	sym.value, sym.resolved = value, true
+	return value, nil
+}
+
+// operators lists the binary operators by precedence, loosest first
+var operators = [][]string{
+	{"|"},
+	{"^"},
+	{"&"},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// symbols are the operator tokens, longest first so they match greedily
+var symbols = []string{"<<", ">>", "|", "^", "&", "+", "-", "*", "/", "%", "~", "(", ")", "$"}
+
+// parseExpression parses an integer expression: numbers in decimal, 0x
+// hex or 0b binary, sprite rows such as ..####.. , symbols, $ for the
+// current address, and the unary - ~ and binary * / % + - << >> & ^ |
+// operators with C's precedence
+func parseExpression(source string) (expression, error) {
+	p := &parser{source: source}
+	p.next()
+	expr, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.token != "" {
+		return nil, p.fail("unexpected %q", p.token)
+	}
+	return expr, nil
+}
+
+type parser struct {
+	source string
+	pos    int
+	// token is the current token, "" at the end
+	token string
+}
+
+func (p *parser) fail(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid expression %q: %s", p.source, fmt.Sprintf(format, args...))
+}
+
+// next moves on to the following token
+func (p *parser) next() {
+	for p.pos < len(p.source) && (p.source[p.pos] == ' ' || p.source[p.pos] == '\t') {
+		p.pos++
+	}
+	if p.pos >= len(p.source) {
+		p.token = ""
+		return
+	}
+	for _, symbol := range symbols {
+		if strings.HasPrefix(p.source[p.pos:], symbol) {
+			p.pos += len(symbol)
+			p.token = symbol
+			return
+		}
+	}
+	end := p.pos
+	if isPixel(p.source[end]) {
+		for end < len(p.source) && isPixel(p.source[end]) {
+			end++
+		}
+	} else {
+		for end < len(p.source) && isWord(p.source[end]) {
+			end++
+		}
+	}
+	if end == p.pos {
+		end++
+	}
+	p.token = p.source[p.pos:end]
+	p.pos = end
+}
+
+func isWord(b byte) bool {
+	return b == '_' || b == '.' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+func isPixel(b byte) bool {
+	return b == '.' || b == '#'
+}
+
+// binary parses operators of the given precedence level and tighter
+func (p *parser) binary(level int) (expression, error) {
+	if level == len(operators) {
+		return p.unary()
+	}
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for contains(operators[level], p.token) {
+		operator := p.token
+		p.next()
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = apply(operator, left, right)
+	}
+	return left, nil
+}
+
+func contains(list []string, token string) bool {
+	for _, item := range list {
+		if item == token {
+			return true
+		}
+	}
+	return false
+}
+
+func apply(operator string, left, right expression) expression {
+	return func(s *scope) (int, error) {
+		a, err := left(s)
+		if err != nil {
+			return 0, err
+		}
+		b, err := right(s)
+		if err != nil {
+			return 0, err
+		}
+		switch operator {
+		case "|":
+			return a | b, nil
+		case "^":
+			return a ^ b, nil
+		case "&":
+			return a & b, nil
+		case "<<":
+			return a << uint(b&63), nil
+		case ">>":
+			return a >> uint(b&63), nil
+		case "+":
+			return a + b, nil
+		case "-":
+			return a - b, nil
+		case "*":
+			return a * b, nil
+		case "/", "%":
+			if b == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			if operator == "/" {
+				return a / b, nil
+			}
+			return a % b, nil
+		}
+		panic("unknown operator " + operator)
+	}
+}
+
+func (p *parser) unary() (expression, error) {
+	switch operator := p.token; operator {
+	case "-", "~":
+		p.next()
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(s *scope) (int, error) {
+			a, err := operand(s)
+			if operator == "-" {
+				return -a, err
+			}
+			return ^a, err
+		}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (expression, error) {
+	token := p.token
+	switch {
+	case token == "":
+		return nil, p.fail("unexpected end")
+	case token == "(":
+		p.next()
+		inner, err := p.binary(0)
+		if err != nil {
+			return nil, err
+		}
+		if p.token != ")" {
+			return nil, p.fail("want )")
+		}
+		p.next()
+		return inner, nil
+	case token == "$":
+		p.next()
+		return func(s *scope) (int, error) {
+			return s.here, nil
+		}, nil
+	case isPixel(token[0]):
+		value, err := sprite(token)
+		if err != nil {
+			return nil, p.fail("%v", err)
+		}
+		p.next()
+		return constant(value), nil
+	case '0' <= token[0] && token[0] <= '9':
+		value, err := strconv.ParseInt(token, 0, 64)
+		if err != nil {
+			return nil, p.fail("bad number %q", token)
+		}
+		p.next()
+		return constant(int(value)), nil
+	case isWord(token[0]):
+		if reserved(token) {
+			return nil, p.fail("%s is a register, not a value", token)
+		}
+		p.next()
+		return func(s *scope) (int, error) {
+			return s.lookup(token)
+		}, nil
+	}
+	return nil, p.fail("unexpected %q", token)
+}
+
+func constant(value int) expression {
+	return func(s *scope) (int, error) {
+		return value, nil
+	}
+}
+
+// sprite reads a row of pixels, # being set and . clear, as a byte, or
+// as a word for the 16 pixel rows of SUPER-CHIP sprites
+func sprite(row string) (int, error) {
+	width := 8
+	if len(row) > 8 {
+		width = 16
+	}
+	if len(row) > width {
+		return 0, fmt.Errorf("sprite row %q is wider than 16 pixels", row)
+	}
+	value := 0
+	for n := 0; n < len(row); n++ {
+		if row[n] == '#' {
+			value |= 1 << (width - 1 - n)
+		}
+	}
+	return value, nil
+}
+
+// reserved reports whether name is a register or other operand that
+// cannot be used as a symbol
+func reserved(name string) bool {
+	switch strings.ToUpper(name) {
+	case "I", "DT", "ST", "K", "F", "B", "HF", "R", "LONG":
+		return true
+	}
+	return register(name) >= 0
+}
+
+// register returns the number of the register Vx named, or -1
+func register(name string) int {
+	if len(name) != 2 || name[0] != 'V' && name[0] != 'v' {
+		return -1
+	}
+	x, err := strconv.ParseUint(name[1:], 16, 8)
+	if err != nil {
+		return -1
+	}
+	return int(x)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsmCommand(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "spin.s")
+	assert.NoError(t, os.WriteFile(source, []byte("start:\n    ld v0, 5\nloop:\n    jp loop\n"), 0644))
+
+	var stdout, stderr strings.Builder
+	assert.Equal(t, 0, asmCommand([]string{source}, &stdout, &stderr), stderr.String())
+	rom, err := os.ReadFile(filepath.Join(dir, "spin.ch8"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x60, 0x05, 0x12, 0x02}, rom)
+
+	octo := filepath.Join(dir, "spin.8o")
+	out := filepath.Join(dir, "octo.ch8")
+	assert.NoError(t, os.WriteFile(octo, []byte(": main\n  v0 := 5\n  loop again\n"), 0644))
+	assert.Equal(t, 0, asmCommand([]string{octo, "-o", out}, &stdout, &stderr), stderr.String())
+	rom, err = os.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x60, 0x05, 0x12, 0x02}, rom)
+
+	broken := filepath.Join(dir, "broken.s")
+	assert.NoError(t, os.WriteFile(broken, []byte("    frobnicate v0\n"), 0644))
+	assert.Equal(t, 1, asmCommand([]string{broken}, &stdout, &stderr))
+	assert.Equal(t, broken+":1: unknown instruction \"FROBNICATE\"\n", stderr.String())
+}
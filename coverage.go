@@ -0,0 +1,163 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Nuxij/goch8p/coverage"
+	"github.com/Nuxij/goch8p/cpu"
+	"github.com/Nuxij/goch8p/disasm"
+)
+
+// coverageCommand records which parts of a ROM run, or reports them as
+// an annotated disassembly, merging the coverage of several runs
+func coverageCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "run":
+			return coverageRun(args[1:], stdout, stderr)
+		case "report":
+			return coverageReport(args[1:], stdout, stderr)
+		}
+	}
+	fmt.Fprintln(stderr, "usage: goch8p coverage run [flags] rom | goch8p coverage report [flags] rom coverage...")
+	return 2
+}
+
+func coverageRun(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("coverage run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	machineFlags := newMachineFlags(flags)
+	frames := flags.Int("frames", 600, "frames to run")
+	out := flags.String("o", "", "coverage to write, the ROM with a .cov extension if empty")
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: goch8p coverage run [flags] rom")
+		flags.PrintDefaults()
+		return 2
+	}
+	m, err := machineFlags.parse()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	rom := positional[0]
+	data, err := os.ReadFile(rom)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	c, err := m.newCPU(data)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	cov := coverage.New(m.Mode.MemorySize())
+	stop := cov.Record(c)
+	for n := 0; n < *frames; n++ {
+		if err := c.RunFrame(); err != nil {
+			if !cpu.IsHalted(err) {
+				fmt.Fprintln(stderr, err)
+			}
+			break
+		}
+	}
+	stop()
+	if *out == "" {
+		*out = strings.TrimSuffix(rom, filepath.Ext(rom)) + ".cov"
+	}
+	if err := saveCoverage(*out, cov); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	return 0
+}
+
+func saveCoverage(path string, cov *coverage.Coverage) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := cov.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func coverageReport(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("coverage report", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	machineFlags := newMachineFlags(flags)
+	html := flags.Bool("html", false, "write HTML rather than text")
+	out := flags.String("o", "", "report to write, stdout if empty")
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) < 2 {
+		fmt.Fprintln(stderr, "usage: goch8p coverage report [flags] rom coverage...")
+		flags.PrintDefaults()
+		return 2
+	}
+	m, err := machineFlags.parse()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	rom, err := os.ReadFile(positional[0])
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	cov := coverage.New(m.Mode.MemorySize())
+	for _, path := range positional[1:] {
+		more, err := loadCoverage(path)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		cov.Merge(more)
+	}
+	r := coverage.NewReport(rom, cov, disasm.Options{Mode: m.Mode, Origin: m.Origin})
+	w := stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		defer f.Close()
+		w = f
+	}
+	if *html {
+		err = r.WriteHTML(w, filepath.Base(positional[0]))
+	} else {
+		_, err = r.WriteTo(w)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	return 0
+}
+
+func loadCoverage(path string) (*coverage.Coverage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cov, err := coverage.Load(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cov, nil
+}
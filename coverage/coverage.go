@@ -0,0 +1,186 @@
+// Package coverage records which bytes of memory a program executes,
+// reads and writes, saves and merges what it records across runs, and
+// reports it as an annotated disassembly
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Nuxij/goch8p/cpu"
+)
+
+// Use is how a byte has been accessed, any combination of Executed, Read
+// and Written
+type Use byte
+
+const (
+	// Executed is a byte fetched as part of an instruction
+	Executed Use = 1 << iota
+	// Read is a byte read as data, by a sprite draw or FX65 say
+	Read
+	// Written is a byte written, by FX33 or FX55 say
+	Written
+)
+
+// String shows the use as `xrw`, with a dash for each use missing
+func (u Use) String() string {
+	b := []byte("---")
+	for i, c := range []byte("xrw") {
+		if u&(1<<i) != 0 {
+			b[i] = c
+		}
+	}
+	return string(b)
+}
+
+// parseUse reads what String writes
+func parseUse(s string) (Use, bool) {
+	if len(s) != 3 {
+		return 0, false
+	}
+	var u Use
+	for i, c := range []byte("xrw") {
+		switch s[i] {
+		case c:
+			u |= 1 << i
+		case '-':
+		default:
+			return 0, false
+		}
+	}
+	return u, true
+}
+
+// FileInvalid is a coverage file that cannot be loaded
+type FileInvalid struct {
+	line   int
+	reason string
+}
+
+func (f FileInvalid) Error() string {
+	return fmt.Sprintf("coverage line %d: %s", f.line, f.reason)
+}
+
+// header starts every coverage file, followed by the memory size
+const header = "goch8p coverage"
+
+// Coverage is how each byte of memory has been used
+type Coverage struct {
+	uses []Use
+}
+
+// New covers size bytes of memory, none of them used yet
+func New(size int) *Coverage {
+	return &Coverage{uses: make([]Use, size)}
+}
+
+// Record adds the accesses c makes through its memory, fetches being
+// executed, from now on until the returned function is called
+func (cov *Coverage) Record(c *cpu.CPU) func() {
+	return c.Memory().Observe(func(a cpu.Access) {
+		if int(a.Addr) >= len(cov.uses) {
+			return
+		}
+		switch {
+		case a.Fetch:
+			cov.uses[a.Addr] |= Executed
+		case a.Write:
+			cov.uses[a.Addr] |= Written
+		default:
+			cov.uses[a.Addr] |= Read
+		}
+	})
+}
+
+// At returns how the byte at addr has been used
+func (cov *Coverage) At(addr uint16) Use {
+	if int(addr) >= len(cov.uses) {
+		return 0
+	}
+	return cov.uses[addr]
+}
+
+// Size returns how many bytes of memory are covered
+func (cov *Coverage) Size() int {
+	return len(cov.uses)
+}
+
+// Merge adds the uses recorded by other, such as another run of the same
+// ROM with different input
+func (cov *Coverage) Merge(other *Coverage) {
+	if len(other.uses) > len(cov.uses) {
+		cov.uses = append(cov.uses, make([]Use, len(other.uses)-len(cov.uses))...)
+	}
+	for addr, u := range other.uses {
+		cov.uses[addr] |= u
+	}
+}
+
+// Save writes the coverage as text: a header giving the memory size,
+// then each run of bytes used the same way, as `0200-0215 x--`
+func (cov *Coverage) Save(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "%s %d\n", header, len(cov.uses))
+	for start := 0; start < len(cov.uses); {
+		end := start
+		for end+1 < len(cov.uses) && cov.uses[end+1] == cov.uses[start] {
+			end++
+		}
+		switch {
+		case cov.uses[start] == 0:
+		case end == start:
+			fmt.Fprintf(b, "%04X %s\n", start, cov.uses[start])
+		default:
+			fmt.Fprintf(b, "%04X-%04X %s\n", start, end, cov.uses[start])
+		}
+		start = end + 1
+	}
+	return b.Flush()
+}
+
+// Load reads coverage written by Save
+func Load(r io.Reader) (*Coverage, error) {
+	s := bufio.NewScanner(r)
+	if !s.Scan() {
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+		return nil, FileInvalid{1, "empty file"}
+	}
+	size, err := strconv.Atoi(strings.TrimPrefix(s.Text(), header+" "))
+	if !strings.HasPrefix(s.Text(), header+" ") || err != nil || size < 0 || size > 0x10000 {
+		return nil, FileInvalid{1, "not a coverage file"}
+	}
+	cov := New(size)
+	for line := 2; s.Scan(); line++ {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, FileInvalid{line, "want an address range and its use"}
+		}
+		u, ok := parseUse(fields[1])
+		if !ok {
+			return nil, FileInvalid{line, fmt.Sprintf("bad use %q", fields[1])}
+		}
+		bounds := strings.SplitN(fields[0], "-", 2)
+		from, to := bounds[0], bounds[len(bounds)-1]
+		start, err := strconv.ParseUint(from, 16, 16)
+		if err != nil {
+			return nil, FileInvalid{line, fmt.Sprintf("bad address %q", from)}
+		}
+		end, err := strconv.ParseUint(to, 16, 16)
+		if err != nil || end < start || int(end) >= size {
+			return nil, FileInvalid{line, fmt.Sprintf("bad address %q", to)}
+		}
+		for addr := start; addr <= end; addr++ {
+			cov.uses[addr] |= u
+		}
+	}
+	return cov, s.Err()
+}
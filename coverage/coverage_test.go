@@ -0,0 +1,126 @@
+package coverage
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Nuxij/goch8p/asm"
+	"github.com/Nuxij/goch8p/cpu"
+)
+
+// program draws a sprite, stores V0 and spins, never reaching the CLS
+var program = asm.MustAssemble(`
+    LD I, sprite        ; 200
+    DRW V0, V0, 2       ; 202
+    SE V0, 0            ; 204
+    CLS                 ; 206
+    LD I, 0x300         ; 208
+    LD [I], V0          ; 20A
+loop:
+    JP loop             ; 20C
+sprite:
+    db 0xFF, 0x81       ; 20E
+    db 0x00             ; 210
+`, asm.Options{})
+
+func record(t *testing.T, steps int) *Coverage {
+	c := cpu.NewCPU(cpu.NewRAM(0x1000))
+	assert.NoError(t, c.Memory().Poke(0x200, program.ROM))
+	cov := New(0x1000)
+	stop := cov.Record(c)
+	for n := 0; n < steps; n++ {
+		assert.NoError(t, c.Step())
+	}
+	stop()
+	return cov
+}
+
+func TestCoverage_Record(t *testing.T) {
+	cov := record(t, 6)
+	assert.Equal(t, Executed, cov.At(0x200))
+	assert.Equal(t, Executed, cov.At(0x201))
+	assert.Equal(t, Use(0), cov.At(0x206), "skipped instructions are not executed")
+	assert.Equal(t, Read, cov.At(0x20E))
+	assert.Equal(t, Read, cov.At(0x20F))
+	assert.Equal(t, Use(0), cov.At(0x210))
+	assert.Equal(t, Written, cov.At(0x300))
+	assert.Equal(t, Executed, cov.At(0x20C))
+	assert.Equal(t, "x--", Executed.String())
+	assert.Equal(t, "-rw", (Read | Written).String())
+}
+
+func TestCoverage_Record_xoChipSkip(t *testing.T) {
+	// XO-CHIP looks past a skip for F000 NNNN, which is not the program
+	// reading the code it looks at
+	c := cpu.NewCPU(cpu.NewRAM(0x10000), cpu.WithMode(cpu.ModeXOChip))
+	assert.NoError(t, c.Memory().Poke(0x200, asm.MustAssemble(`
+    SE V0, 0            ; 200
+    CLS                 ; 202
+    SNE V0, 0           ; 204
+    CLS                 ; 206
+loop:
+    JP loop             ; 208
+`, asm.Options{Mode: cpu.ModeXOChip}).ROM))
+	cov := New(0x10000)
+	stop := cov.Record(c)
+	for n := 0; n < 4; n++ {
+		assert.NoError(t, c.Step())
+	}
+	stop()
+	assert.Equal(t, Use(0), cov.At(0x202), "the skipped instruction was only looked at")
+	assert.Equal(t, Use(0), cov.At(0x203))
+	assert.Equal(t, Executed, cov.At(0x206))
+	assert.Equal(t, Executed, cov.At(0x208))
+}
+
+func TestCoverage_Save(t *testing.T) {
+	cov := record(t, 6)
+	var b bytes.Buffer
+	assert.NoError(t, cov.Save(&b))
+	assert.Equal(t, `goch8p coverage 4096
+0200-0205 x--
+0208-020D x--
+020E-020F -r-
+0300 --w
+`, b.String())
+
+	loaded, err := Load(&b)
+	assert.NoError(t, err)
+	assert.Equal(t, cov, loaded)
+}
+
+func TestCoverage_Merge(t *testing.T) {
+	a, b := New(0x10), New(0x20)
+	a.uses[0x1] = Executed
+	b.uses[0x1] = Read
+	b.uses[0x18] = Written
+	a.Merge(b)
+	assert.Equal(t, 0x20, a.Size())
+	assert.Equal(t, Executed|Read, a.At(0x1))
+	assert.Equal(t, Written, a.At(0x18))
+}
+
+func TestLoad_errors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		err  error
+	}{
+		{"empty", "", FileInvalid{1, "empty file"}},
+		{"header", "coverage 4096\n", FileInvalid{1, "not a coverage file"}},
+		{"fields", "goch8p coverage 4096\n0200\n", FileInvalid{2, "want an address range and its use"}},
+		{"use", "goch8p coverage 4096\n0200 x\n", FileInvalid{2, `bad use "x"`}},
+		{"address", "goch8p coverage 4096\n\n02G0 x--\n", FileInvalid{3, `bad address "02G0"`}},
+		{"backwards", "goch8p coverage 4096\n0202-0200 x--\n", FileInvalid{2, `bad address "0200"`}},
+		{"beyond", "goch8p coverage 4096\n1000 x--\n", FileInvalid{2, `bad address "1000"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(strings.NewReader(tt.file))
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
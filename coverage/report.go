@@ -0,0 +1,180 @@
+package coverage
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/Nuxij/goch8p/cpu"
+	"github.com/Nuxij/goch8p/disasm"
+)
+
+// Report is a disassembly of a ROM annotated with its coverage
+type Report struct {
+	Listing  *disasm.Listing
+	Coverage *Coverage
+}
+
+// NewReport disassembles rom, starting from each run of executed bytes as
+// well as the origin so code only reached through BNNN is found too
+func NewReport(rom []byte, cov *Coverage, options disasm.Options) *Report {
+	origin := options.Origin
+	if origin == 0 {
+		origin = cpu.DefaultOrigin
+	}
+	for at := range rom {
+		addr := origin + uint16(at)
+		if cov.At(addr)&Executed != 0 && (at == 0 || cov.At(addr-1)&Executed == 0) {
+			options.Entries = append(options.Entries, addr)
+		}
+	}
+	return &Report{disasm.Disassemble(rom, options), cov}
+}
+
+// Use returns how any of the line's bytes were used
+func (r *Report) Use(line disasm.Line) Use {
+	var u Use
+	for i := range line.Bytes {
+		u |= r.Coverage.At(line.Addr + uint16(i))
+	}
+	return u
+}
+
+// Summary counts how much of a ROM was covered
+type Summary struct {
+	Instructions int
+	Executed     int
+	// Data is the bytes not disassembled as code, and Read those of them
+	// read
+	Data int
+	Read int
+}
+
+func (s Summary) String() string {
+	return fmt.Sprintf("%d of %d instructions executed (%s), %d of %d data bytes read (%s)",
+		s.Executed, s.Instructions, percent(s.Executed, s.Instructions),
+		s.Read, s.Data, percent(s.Read, s.Data))
+}
+
+func percent(n, of int) string {
+	if of == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(n)/float64(of))
+}
+
+// Summary counts the instructions executed and data read
+func (r *Report) Summary() Summary {
+	var s Summary
+	for _, line := range r.Listing.Lines {
+		u := r.Use(line)
+		if line.Instruction != nil {
+			s.Instructions++
+			if u&Executed != 0 {
+				s.Executed++
+			}
+			continue
+		}
+		s.Data++
+		if u&Read != 0 {
+			s.Read++
+		}
+	}
+	return s
+}
+
+// missed reports whether line is an instruction that was never executed
+func (r *Report) missed(line disasm.Line) bool {
+	return line.Instruction != nil && r.Use(line)&Executed == 0
+}
+
+// WriteTo writes the report as text, each line of the disassembly
+// showing its use as `xrw` and instructions never executed marked `!`
+func (r *Report) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "; %s\n", r.Summary())
+	for _, line := range r.Listing.Lines {
+		if label, ok := r.Listing.Labels[line.Addr]; ok {
+			fmt.Fprintf(&b, "\n%s:\n", label)
+		}
+		mark := " "
+		if r.missed(line) {
+			mark = "!"
+		}
+		fmt.Fprintf(&b, "%s %s   %-28s; %03X: %X\n", r.Use(line), mark, line.Text, line.Addr, line.Bytes)
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (r *Report) String() string {
+	var b strings.Builder
+	r.WriteTo(&b)
+	return b.String()
+}
+
+// reportLine is a line of the HTML report
+type reportLine struct {
+	Label string
+	Class string
+	Use   Use
+	Text  string
+	Addr  string
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { background: #111; color: #888; font-family: monospace; }
+.executed { color: #2cd495; }
+.missed { color: #e05050; font-weight: bold; }
+.read { color: #5fa8e0; }
+.label { color: #ddd; }
+</style>
+</head>
+<body>
+<p>{{.Summary}}</p>
+<pre>
+{{- range .Lines}}
+{{- if .Label}}
+
+<span class="label">{{.Label}}:</span>
+{{- end}}
+<span class="{{.Class}}">{{.Use}}   {{printf "%-28s" .Text}}; {{.Addr}}</span>
+{{- end}}
+</pre>
+</body>
+</html>
+`))
+
+// WriteHTML writes the report as a page, instructions executed in green,
+// those never executed in red and data read in blue
+func (r *Report) WriteHTML(w io.Writer, title string) error {
+	var lines []reportLine
+	for _, line := range r.Listing.Lines {
+		l := reportLine{
+			Label: r.Listing.Labels[line.Addr],
+			Use:   r.Use(line),
+			Text:  line.Text,
+			Addr:  fmt.Sprintf("%03X: %X", line.Addr, line.Bytes),
+		}
+		switch {
+		case r.missed(line):
+			l.Class = "missed"
+		case line.Instruction != nil:
+			l.Class = "executed"
+		case l.Use&Read != 0:
+			l.Class = "read"
+		}
+		lines = append(lines, l)
+	}
+	return reportTemplate.Execute(w, struct {
+		Title   string
+		Summary Summary
+		Lines   []reportLine
+	}{title, r.Summary(), lines})
+}
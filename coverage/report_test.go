@@ -0,0 +1,55 @@
+package coverage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Nuxij/goch8p/disasm"
+)
+
+func TestReport(t *testing.T) {
+	r := NewReport(program.ROM, record(t, 6), disasm.Options{})
+	assert.Equal(t, Summary{Instructions: 7, Executed: 6, Data: 3, Read: 2}, r.Summary())
+	assert.Equal(t, `; 6 of 7 instructions executed (85.7%), 2 of 3 data bytes read (66.7%)
+
+start:
+x--     LD I, data_20E              ; 200: A20E
+x--     DRW V0, V0, 2               ; 202: D002
+x--     SE V0, 0x00                 ; 204: 3000
+--- !   CLS                         ; 206: 00E0
+x--     LD I, 0x300                 ; 208: A300
+x--     LD [I], V0                  ; 20A: F055
+
+label_20C:
+x--     JP label_20C                ; 20C: 120C
+
+data_20E:
+-r-     db 0b11111111               ; 20E: FF
+-r-     db 0b10000001               ; 20F: 81
+---     db 0b00000000               ; 210: 00
+`, r.String())
+}
+
+func TestReport_entries(t *testing.T) {
+	// BB00 jumps to 0x202 + V0, which tracing the ROM alone cannot follow
+	rom := []byte{0x60, 0x02, 0xB2, 0x04, 0x00, 0x00, 0x61, 0x01, 0x12, 0x08}
+	cov := New(0x1000)
+	for _, addr := range []uint16{0x200, 0x201, 0x202, 0x203, 0x206, 0x207, 0x208, 0x209} {
+		cov.uses[addr] = Executed
+	}
+	r := NewReport(rom, cov, disasm.Options{})
+	assert.Equal(t, Summary{Instructions: 4, Executed: 4, Data: 2}, r.Summary())
+}
+
+func TestReport_WriteHTML(t *testing.T) {
+	r := NewReport(program.ROM, record(t, 6), disasm.Options{})
+	var b strings.Builder
+	assert.NoError(t, r.WriteHTML(&b, "<game>"))
+	html := b.String()
+	assert.Contains(t, html, "<title>&lt;game&gt;</title>")
+	assert.Contains(t, html, `<span class="missed">---   CLS                         ; 206: 00E0</span>`)
+	assert.Contains(t, html, `<span class="executed">x--   LD I, 0x300                 ; 208: A300</span>`)
+	assert.Contains(t, html, `<span class="read">-r-   db 0b11111111               ; 20E: FF</span>`)
+}
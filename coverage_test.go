@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoverageCommand(t *testing.T) {
+	dir := t.TempDir()
+	rom := filepath.Join(dir, "skip.ch8")
+	// SE V0, 0 over a CLS that never runs, then spin
+	assert.NoError(t, os.WriteFile(rom, []byte{0x30, 0x00, 0x00, 0xE0, 0x12, 0x04}, 0644))
+
+	var stdout, stderr strings.Builder
+	assert.Equal(t, 0, coverageCommand([]string{"run", rom, "-frames", "1"}, &stdout, &stderr), stderr.String())
+	cov := filepath.Join(dir, "skip.cov")
+	saved, err := os.ReadFile(cov)
+	assert.NoError(t, err)
+	assert.Equal(t, "goch8p coverage 4096\n0200-0201 x--\n0204-0205 x--\n", string(saved))
+
+	assert.Equal(t, 0, coverageCommand([]string{"report", rom, cov}, &stdout, &stderr), stderr.String())
+	assert.Contains(t, stdout.String(), "CLS")
+
+	stderr.Reset()
+	assert.Equal(t, 2, coverageCommand([]string{"report", "-mode", "chip9", rom, cov}, &stdout, &stderr))
+	assert.Equal(t, "unknown mode \"chip9\"\n", stderr.String())
+}
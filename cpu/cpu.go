@@ -2,21 +2,59 @@ package cpu
 
 import (
 	"encoding/binary"
-	"errors"
 	"fmt"
+	"math/rand"
 
 	"github.com/google/uuid"
 )
 
 type CPU struct {
-	ram     Device
-	pc      uint16
-	sp      uint16
-	index   uint16
-	stack   *Stack
-	v 		[16]uint16
-	screen  Screen
+	ram    *Rammer
+	pc     uint16
+	sp     uint16
+	index  uint16
+	stack  *Stack
+	v      [16]byte
+	keypad *Keypad
+	timers *Timers
+	screen Screen
+	quirks Quirks
+	mode   Mode
+	rpl    [16]byte
+	// pattern and pitch are the XO-CHIP audio registers
+	pattern [16]byte
+	pitch   byte
+	// keypadAddress is where the keypad is mapped into memory, if mapKeypad is set
+	mapKeypad     bool
+	keypadAddress uint16
+	// fault is an option NewCPU could not apply, which every Step returns
+	fault error
+	// random feeds CXNN, seeded with DefaultSeed unless an option says
+	// otherwise so that runs repeat exactly. seeded is its source when
+	// that can be saved, which an injected one cannot.
+	random *rand.Rand
+	seeded *seededSource
+	// instructionsPerFrame is how many instructions make a frame, cycle
+	// counts those executed so far in this one, frame counts the frames
+	// run and subscribers are sent each one
+	instructionsPerFrame int
+	cycle                int
+	frame                uint64
+	subscribers          []subscriber
+	nextSubscriber       int
+	// tracers are sent every instruction executed
+	tracers    []tracer
+	nextTracer int
+	// history holds the snapshots Rewind goes back to, if rewinding is on
+	history *history
+	// jit caches decoded blocks of instructions, if WithBlockCache is set
+	jit *blockCache
+	// natives holds instructions translated ahead of time, if WithNatives is set
+	natives *natives
+	// opcodes holds a handler for each of the decoder's instructions,
+	// registered from the instruction at the same index
 	opcodes []InstructionHandler
+	decoder Decoder
 }
 
 func RandomStringUUID() string {
@@ -24,50 +62,61 @@ func RandomStringUUID() string {
 	return fmt.Sprintf("%v", id)
 }
 
-type Screen struct {
-	width   uint16
-	height  uint16
-	size    uint16
-	address uint16
-}
-
-func NewCPU(ram Device) *CPU {
+// NewCPU builds a CPU running from ram, which must cover the mode's
+// MemorySize: 4KiB, or 64KiB for XO-CHIP. An option it cannot apply,
+// such as WithKeypadAt given a misaligned address, is returned by Step.
+func NewCPU(ram Device, options ...Option) *CPU {
 	addressableSize := uint16(0x1000)
-	screenSize := uint16(64*32) / 8
-	screenAddress := addressableSize - (screenSize + 1)
 	rammer := NewRammer(256, []Device{ram})
 	rammer.SetRegion(0x0, addressableSize, ram, 0x0)
-	rammer.SetRegion(screenAddress, screenSize, ram, screenAddress)
 	cpu := &CPU{
-		opcodes: []InstructionHandler{},
-		ram:     rammer,
-		pc:      0x200,
-		sp:      0x0,
-		index:   0x0,
-		stack:   NewStack(0x10),
-		screen: Screen{
-			width:   64,
-			height:  32,
-			size:    screenSize,
-			address: screenAddress,
-		},
-	}
-	for _, i := range AllOpcodes {
-		cpu.opcodes = append(cpu.opcodes, i.Register(cpu))
+		ram:    rammer,
+		pc:     DefaultOrigin,
+		sp:     0x0,
+		index:  0x0,
+		stack:  NewStack(0x10),
+		keypad: NewKeypad(),
+		timers: NewTimers(),
+		screen: NewScreen(),
+		quirks: QuirksCOSMACVIP,
+		pitch:  DefaultPitch,
+
+		instructionsPerFrame: DefaultInstructionsPerFrame,
+	}
+	WithSeed(DefaultSeed)(cpu)
+	for _, option := range options {
+		option(cpu)
+	}
+	// a region cannot span the whole 64KiB, so map memory in halves
+	for start := 0; start < cpu.mode.MemorySize(); start += 0x8000 {
+		size := cpu.mode.MemorySize() - start
+		if size > 0x8000 {
+			size = 0x8000
+		}
+		rammer.SetRegion(uint16(start), uint16(size), ram, uint16(start))
+	}
+	if cpu.mapKeypad {
+		cpu.fault = rammer.SetRegion(cpu.keypadAddress, Keys, cpu.keypad, 0x0)
+	}
+	if err := cpu.RegisterInstructions(cpu.mode.Instructions()); err != nil {
+		panic(err)
 	}
 	cpu.LoadFonts()
 	return cpu
 }
 
-// LoadFonts will put each of the fonts in Fonts into memory
+// LoadFonts will put each of the fonts in Fonts and BigFonts into memory
 func (c *CPU) LoadFonts() {
 	for i, font := range Fonts {
-		c.ram.Writes(uint16(i*5), font[:])
+		c.ram.Writes(FontAddress+uint16(i*len(font)), font[:])
+	}
+	for i, font := range BigFonts {
+		c.ram.Writes(BigFontAddress+uint16(i*len(font)), font[:])
 	}
 }
 
 func (c *CPU) FetchInstruction() (uint16, error) {
-	opbytes, err := c.ram.Reads(c.pc, 2)
+	opbytes, err := c.ram.Fetch(c.pc, 2)
 	if err != nil {
 		return 0x0, err
 	}
@@ -76,33 +125,88 @@ func (c *CPU) FetchInstruction() (uint16, error) {
 	return opcode, nil
 }
 
+// RegisterInstructions binds each instruction to the CPU and adds the
+// opcodes it claims to the decoder. It fails, registering nothing, if an
+// opcode would be claimed twice.
+func (c *CPU) RegisterInstructions(instructions []Instruction) error {
+	if err := c.decoder.Add(instructions); err != nil {
+		return err
+	}
+	for _, i := range instructions {
+		c.opcodes = append(c.opcodes, i.Register(c))
+	}
+	return nil
+}
+
 func (c *CPU) CallInstruction(handler InstructionHandler, opcode uint16) error {
 	return handler.HandleInstruction(opcode)
 }
 
 func (c *CPU) ExecuteInstruction(instruction uint16) error {
-	for _, op := range c.opcodes {
-		err := c.CallInstruction(op, instruction)
-		if ! errors.Is(err, InstructionNOP{instruction}) {
-			return err
-		}
+	if slot := c.decoder.table[instruction]; slot != 0 {
+		return c.CallInstruction(c.opcodes[slot-1], instruction)
 	}
-	var err error
-	fmt.Printf("Executing instruction: %x\n", instruction)
-	switch instruction >> 12 {
-	case 0x0:
-		switch instruction & 0x00FF {
-		case 0x00EC:
-			err = c.yieldCoroutine()
-		case 0x00EE:
-			err = c.returnFromSubroutine()
-		default:
-			err = InstructionUnknown{instruction}
-		}
-	default:
-		err = InstructionUnknown{instruction}
-	}
-	return err
+	return InstructionUnknown{instruction}
+}
+
+// PC returns the program counter
+func (c *CPU) PC() uint16 {
+	return c.pc
+}
+
+// Index returns the index register I
+func (c *CPU) Index() uint16 {
+	return c.index
+}
+
+// SetIndex sets the index register I
+func (c *CPU) SetIndex(addr uint16) {
+	c.index = addr
+}
+
+// V returns register VX
+func (c *CPU) V(x byte) byte {
+	return c.v[x&0xF]
+}
+
+// SetV sets register VX
+func (c *CPU) SetV(x byte, value byte) {
+	c.v[x&0xF] = value
+}
+
+// StackDepth returns how many calls deep the program is
+func (c *CPU) StackDepth() int {
+	return c.stack.Size()
+}
+
+// CallStack returns the return addresses on the stack, outermost first
+func (c *CPU) CallStack() []uint16 {
+	return c.stack.Entries()
+}
+
+// Memory returns the bus program memory is read and written through
+func (c *CPU) Memory() *Rammer {
+	return c.ram
+}
+
+// Mode returns the instruction set the CPU runs
+func (c *CPU) Mode() Mode {
+	return c.mode
+}
+
+// Keypad returns the keypad for frontends to press and release keys on
+func (c *CPU) Keypad() *Keypad {
+	return c.keypad
+}
+
+// Timers returns the delay and sound timers
+func (c *CPU) Timers() *Timers {
+	return c.timers
+}
+
+// TickTimers counts the timers down, once per 1/TimerRate seconds of emulated time
+func (c *CPU) TickTimers() {
+	c.timers.Tick()
 }
 
 func (c *CPU) IncrementPC(count uint16) {
@@ -113,14 +217,17 @@ func (c *CPU) SetPC(addr uint16) {
 	c.pc = addr
 }
 
-func (c *CPU) yieldCoroutine() error {
-	return nil
+// skipInstruction moves the PC past the next instruction, which on
+// XO-CHIP may be the four byte F000 NNNN
+func (c *CPU) skipInstruction() {
+	if c.mode >= ModeXOChip {
+		if next, err := c.ram.Peek(c.pc, 2); err == nil && binary.BigEndian.Uint16(next) == 0xF000 {
+			c.IncrementPC(2)
+		}
+	}
+	c.IncrementPC(2)
 }
 
-// func (c *CPU) jump(instruction uint16) error {
-// 	c.SetPC(instruction & 0x0FFF)
-// 	return nil
-// }
 func (c *CPU) callSubroutine(instruction uint16) error {
 	ok := c.stack.Push(c.pc)
 	if !ok {
@@ -137,3 +244,77 @@ func (c *CPU) returnFromSubroutine() error {
 	c.SetPC(d)
 	return nil
 }
+
+// drawSprite XORs a sprite of the given width onto each selected plane
+// at x,y, reporting whether any lit pixel was turned off. The sprite holds
+// the data for each selected plane one after the other.
+func (c *CPU) drawSprite(x, y byte, spriteWidth uint16, sprite []byte) (bool, error) {
+	planes := c.screen.selected()
+	if len(planes) == 0 {
+		return false, nil
+	}
+	planeSize := len(sprite) / len(planes)
+	collided := false
+	for n, plane := range planes {
+		lit, err := c.drawPlane(plane, x, y, spriteWidth, sprite[n*planeSize:(n+1)*planeSize])
+		if err != nil {
+			return collided, err
+		}
+		collided = collided || lit
+	}
+	return collided, nil
+}
+
+func (c *CPU) drawPlane(plane int, x, y byte, spriteWidth uint16, sprite []byte) (bool, error) {
+	width, height := c.screen.width, c.screen.height
+	xCoord := uint16(x) % width
+	yCoord := uint16(y) % height
+	rowSize := spriteWidth / 8
+	collided := false
+	for yPos := uint16(0); yPos < uint16(len(sprite))/rowSize; yPos++ {
+		py := yCoord + yPos
+		if py >= height {
+			if c.quirks.ClipSprites {
+				break
+			}
+			py %= height
+		}
+		row := sprite[yPos*rowSize : (yPos+1)*rowSize]
+		for xPos := uint16(0); xPos < spriteWidth; xPos++ {
+			px := xCoord + xPos
+			if px >= width {
+				if c.quirks.ClipSprites {
+					break
+				}
+				px %= width
+			}
+			if row[xPos/8]&(0x80>>(xPos%8)) == 0 {
+				continue
+			}
+			lit, err := c.screen.flip(plane, px, py)
+			if err != nil {
+				return collided, err
+			}
+			collided = collided || lit
+		}
+	}
+	return collided, nil
+}
+
+// shiftSource returns the register 8XY6 and 8XYE shift from
+func (c *CPU) shiftSource(op uint16) uint16 {
+	if c.quirks.ShiftVX {
+		return opX(op)
+	}
+	return opY(op)
+}
+
+// subtract stores a-b in VX, setting VF when there was no borrow
+func (c *CPU) subtract(x uint16, a, b byte) {
+	c.v[x] = a - b
+	if a >= b {
+		c.v[0xF] = 1
+	} else {
+		c.v[0xF] = 0
+	}
+}
@@ -1,7 +1,7 @@
 package cpu
 
 import (
-	"fmt"
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -23,7 +23,7 @@ func TestCPU_FetchInstruction(t *testing.T) {
 }
 func TestCPU_ExecuteInstruction(t *testing.T) {
 	cpu := NewCPU(NewRAM(0x1000))
-	assert.Equal(t, len(cpu.opcodes), 4)
+	assert.Equal(t, len(cpu.opcodes), len(AllOpcodes))
 
 	cpu.ram.Writes(0x0, []byte{0x00, 0xE0}) // clearscreen in memory for testing read + exec
 	cpu.stack.Push(0x200)                   // Dummy stack value so 0x00EE doesn't fail
@@ -38,12 +38,14 @@ func TestCPU_ExecuteInstruction(t *testing.T) {
 		{0x00EE, nil},
 		{0xA000, nil},
 		{0x00FF, InstructionUnknown{}},
-		{0x1000, InstructionUnknown{}},
+		{0x1000, nil},
 		{0x2000, nil},
-		{0xD000, InstructionUnknown{}},
+		{0xD000, nil},
 		{0xF000, InstructionUnknown{}},
-		{0x7000, InstructionUnknown{}},
-		{0x6000, InstructionUnknown{}},
+		{0x7000, nil},
+		{0x6000, nil},
+		{0x8008, InstructionUnknown{}},
+		{0xE000, InstructionUnknown{}},
 	}
 
 	for _, v := range ops {
@@ -55,37 +57,77 @@ func TestCPU_ExecuteInstruction(t *testing.T) {
 
 func TestCPU_ClearScreen(t *testing.T) {
 	cpu := NewCPU(NewRAM(0x1000))
-	cpu.ram.Writes(cpu.screen.address, DataForTest)
+	cpu.screen.vram.Writes(cpu.screen.address, DataForTest)
 	cpu.ExecuteInstruction(0x00E0)
 	for i := range DataForTest {
-		data, err := cpu.ram.Read(cpu.screen.address + uint16(i))
+		data, err := cpu.screen.vram.Read(cpu.screen.address + uint16(i))
 		assert.NoError(t, err)
 		assert.EqualValues(t, 0, data)
 	}
 }
 
-// Fails because fonts aren't loaded in memory
 func TestCPU_DrawSprite(t *testing.T) {
 	cpu := NewCPU(NewRAM(0x1000))
 	cpu.index = 0x0
 	cpu.ExecuteInstruction(0xD005)
+	rowSize := cpu.screen.width / 8
 	for i, val := range Fonts[0] {
-		d,_ := cpu.ram.Read(0x0 + uint16(i))
-		fmt.Printf("%X", d)
-		data, err := cpu.ram.Read(cpu.screen.address + uint16(i))
+		data, err := cpu.screen.vram.Read(cpu.screen.address + uint16(i)*rowSize)
 		assert.NoError(t, err)
 		assert.EqualValues(t, val, data)
 	}
+	assert.EqualValues(t, 0, cpu.v[0xF])
+
+	cpu.ExecuteInstruction(0xD005)
+	assert.EqualValues(t, 1, cpu.v[0xF], "drawing over lit pixels should set VF")
+	for i := range Fonts[0] {
+		data, _ := cpu.screen.vram.Read(cpu.screen.address + uint16(i)*rowSize)
+		assert.EqualValues(t, 0, data)
+	}
+}
+
+func TestCPU_DrawSprite_position(t *testing.T) {
+	tests := []struct {
+		name   string
+		x, y   byte
+		pixels [][2]uint16
+	}{
+		{"origin", 0, 0, [][2]uint16{{0, 0}, {7, 0}}},
+		{"offset", 10, 5, [][2]uint16{{10, 5}, {17, 5}}},
+		{"wraps start", 64 + 3, 32 + 2, [][2]uint16{{3, 2}, {10, 2}}},
+		{"clips right edge", 60, 0, [][2]uint16{{60, 0}, {63, 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu := NewCPU(NewRAM(0x1000))
+			cpu.ram.Write(0x300, 0xFF)
+			cpu.index = 0x300
+			cpu.v[0x1], cpu.v[0x2] = tt.x, tt.y
+			assert.NoError(t, cpu.ExecuteInstruction(0xD121))
+			count := 0
+			for i := uint16(0); i < cpu.screen.size; i++ {
+				data, _ := cpu.screen.vram.Read(cpu.screen.address + i)
+				for ; data != 0; data &= data - 1 {
+					count++
+				}
+			}
+			for _, p := range tt.pixels {
+				lit, err := cpu.screen.pixel(p[0], p[1])
+				assert.NoError(t, err)
+				assert.True(t, lit, "pixel %v should be lit", p)
+			}
+			assert.Equal(t, int(tt.pixels[1][0]-tt.pixels[0][0]+1), count)
+		})
+	}
 }
 
 func TestCPU_loadIndex(t *testing.T) {
 	cpu := NewCPU(NewRAM(0x1000))
 	opcode := uint16(0xA000)
 	target := uint16(0x0300)
-	cpu.ram.Writes(target, []byte{0xFF, 0xFF})
 	err := cpu.ExecuteInstruction(opcode | target)
 	assert.NoError(t, err)
-	assert.EqualValues(t, 0xFFFF, cpu.index)
+	assert.EqualValues(t, target, cpu.index)
 }
 
 func TestCPU_callSubroutine(t *testing.T) {
@@ -99,3 +141,295 @@ func TestCPU_callSubroutine(t *testing.T) {
 	assert.EqualValues(t, startPC, poppedPC)
 	assert.EqualValues(t, target, cpu.pc)
 }
+
+func TestCPU_jump(t *testing.T) {
+	cpu := NewCPU(NewRAM(0x1000))
+	assert.NoError(t, cpu.ExecuteInstruction(0x1ABC))
+	assert.EqualValues(t, 0xABC, cpu.pc)
+
+	cpu.v[0x0] = 0x10
+	assert.NoError(t, cpu.ExecuteInstruction(0xB300))
+	assert.EqualValues(t, 0x310, cpu.pc)
+}
+
+func TestCPU_returnFromSubroutine(t *testing.T) {
+	cpu := NewCPU(NewRAM(0x1000))
+	assert.Error(t, cpu.ExecuteInstruction(0x00EE), "returning with an empty stack")
+	cpu.ExecuteInstruction(0x2A00)
+	assert.NoError(t, cpu.ExecuteInstruction(0x00EE))
+	assert.EqualValues(t, 0x200, cpu.pc)
+	assert.Equal(t, 0, cpu.stack.Size())
+}
+
+func TestCPU_skips(t *testing.T) {
+	tests := []struct {
+		name   string
+		opcode uint16
+		vx, vy byte
+		key    bool
+		skip   bool
+	}{
+		{"3XNN equal", 0x3142, 0x42, 0, false, true},
+		{"3XNN not equal", 0x3142, 0x41, 0, false, false},
+		{"4XNN equal", 0x4142, 0x42, 0, false, false},
+		{"4XNN not equal", 0x4142, 0x41, 0, false, true},
+		{"5XY0 equal", 0x5120, 0x42, 0x42, false, true},
+		{"5XY0 not equal", 0x5120, 0x42, 0x41, false, false},
+		{"9XY0 equal", 0x9120, 0x42, 0x42, false, false},
+		{"9XY0 not equal", 0x9120, 0x42, 0x41, false, true},
+		{"EX9E pressed", 0xE19E, 0x5, 0, true, true},
+		{"EX9E released", 0xE19E, 0x5, 0, false, false},
+		{"EXA1 pressed", 0xE1A1, 0x5, 0, true, false},
+		{"EXA1 released", 0xE1A1, 0x5, 0, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu := NewCPU(NewRAM(0x1000))
+			cpu.v[0x1], cpu.v[0x2] = tt.vx, tt.vy
+			if tt.key {
+				cpu.keypad.Press(0x5)
+			}
+			assert.NoError(t, cpu.ExecuteInstruction(tt.opcode))
+			want := uint16(0x200)
+			if tt.skip {
+				want += 2
+			}
+			assert.EqualValues(t, want, cpu.pc)
+		})
+	}
+}
+
+func TestCPU_registers(t *testing.T) {
+	tests := []struct {
+		name   string
+		opcode uint16
+		vx, vy byte
+		want   byte
+		wantVF byte
+	}{
+		{"6XNN", 0x6142, 0x00, 0x00, 0x42, 0xAA},
+		{"7XNN", 0x7102, 0x10, 0x00, 0x12, 0xAA},
+		{"7XNN overflow leaves VF", 0x7102, 0xFF, 0x00, 0x01, 0xAA},
+		{"8XY0", 0x8120, 0x10, 0x20, 0x20, 0xAA},
+		{"8XY1", 0x8121, 0x0F, 0xF0, 0xFF, 0x00},
+		{"8XY2", 0x8122, 0x3C, 0x0F, 0x0C, 0x00},
+		{"8XY3", 0x8123, 0x3C, 0x0F, 0x33, 0x00},
+		{"8XY4", 0x8124, 0x10, 0x20, 0x30, 0x00},
+		{"8XY4 carry", 0x8124, 0xF0, 0x20, 0x10, 0x01},
+		{"8XY5", 0x8125, 0x30, 0x10, 0x20, 0x01},
+		{"8XY5 equal", 0x8125, 0x30, 0x30, 0x00, 0x01},
+		{"8XY5 borrow", 0x8125, 0x10, 0x30, 0xE0, 0x00},
+		{"8XY6", 0x8126, 0x00, 0x05, 0x02, 0x01},
+		{"8XY6 even", 0x8126, 0x00, 0x04, 0x02, 0x00},
+		{"8XY7", 0x8127, 0x10, 0x30, 0x20, 0x01},
+		{"8XY7 borrow", 0x8127, 0x30, 0x10, 0xE0, 0x00},
+		{"8XYE", 0x812E, 0x00, 0x81, 0x02, 0x01},
+		{"8XYE no carry", 0x812E, 0x00, 0x41, 0x82, 0x00},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu := NewCPU(NewRAM(0x1000))
+			cpu.v[0x1], cpu.v[0x2], cpu.v[0xF] = tt.vx, tt.vy, 0xAA
+			assert.NoError(t, cpu.ExecuteInstruction(tt.opcode))
+			assert.EqualValues(t, tt.want, cpu.v[0x1])
+			assert.EqualValues(t, tt.wantVF, cpu.v[0xF])
+		})
+	}
+}
+
+func TestCPU_flagRegisterAsOperand(t *testing.T) {
+	tests := []struct {
+		name   string
+		opcode uint16
+		vf, vy byte
+		wantVF byte
+	}{
+		{"8FY4 carry wins", 0x8F14, 0xFF, 0x02, 0x01},
+		{"8FY5 flag wins", 0x8F15, 0x10, 0x01, 0x01},
+		{"8FY6 flag wins", 0x8F16, 0x00, 0x02, 0x00},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu := NewCPU(NewRAM(0x1000))
+			cpu.v[0xF], cpu.v[0x1] = tt.vf, tt.vy
+			assert.NoError(t, cpu.ExecuteInstruction(tt.opcode))
+			assert.EqualValues(t, tt.wantVF, cpu.v[0xF])
+		})
+	}
+}
+
+func TestCPU_random(t *testing.T) {
+	cpu := NewCPU(NewRAM(0x1000))
+	for i := 0; i < 32; i++ {
+		assert.NoError(t, cpu.ExecuteInstruction(0xC10F))
+		assert.Zero(t, cpu.v[0x1]&0xF0, "CXNN should mask with NN")
+	}
+	assert.NoError(t, cpu.ExecuteInstruction(0xC100))
+	assert.Zero(t, cpu.v[0x1])
+}
+
+func TestCPU_randomSource(t *testing.T) {
+	draws := func(options ...Option) []byte {
+		cpu := NewCPU(NewRAM(0x1000), options...)
+		values := make([]byte, 16)
+		for i := range values {
+			assert.NoError(t, cpu.ExecuteInstruction(0xC1FF))
+			values[i] = cpu.v[0x1]
+		}
+		return values
+	}
+	assert.Equal(t, draws(), draws(), "the default seed should repeat")
+	assert.Equal(t, draws(WithSeed(42)), draws(WithSeed(42)))
+	assert.NotEqual(t, draws(WithSeed(42)), draws(WithSeed(43)))
+	assert.Equal(t, draws(WithSeed(7)), draws(WithRandom(rand.NewSource(7))))
+}
+
+func TestCPU_timers(t *testing.T) {
+	cpu := NewCPU(NewRAM(0x1000))
+	cpu.v[0x1] = 0x30
+	assert.NoError(t, cpu.ExecuteInstruction(0xF115))
+	assert.NoError(t, cpu.ExecuteInstruction(0xF118))
+	assert.EqualValues(t, 0x30, cpu.timers.Delay())
+	assert.EqualValues(t, 0x30, cpu.timers.Sound())
+	cpu.timers.SetDelay(0x12)
+	assert.NoError(t, cpu.ExecuteInstruction(0xF207))
+	assert.EqualValues(t, 0x12, cpu.v[0x2])
+}
+
+func TestCPU_waitKey(t *testing.T) {
+	cpu := NewCPU(NewRAM(0x1000))
+	cpu.SetPC(0x202)
+	assert.NoError(t, cpu.ExecuteInstruction(0xF30A))
+	assert.EqualValues(t, 0x200, cpu.pc, "FX0A should repeat until a key is pressed")
+	cpu.keypad.Press(0xB)
+	cpu.SetPC(0x202)
+	assert.NoError(t, cpu.ExecuteInstruction(0xF30A))
+	assert.EqualValues(t, 0x200, cpu.pc, "FX0A should repeat until the key is released")
+	cpu.keypad.Release(0xB)
+	cpu.SetPC(0x202)
+	assert.NoError(t, cpu.ExecuteInstruction(0xF30A))
+	assert.EqualValues(t, 0x202, cpu.pc)
+	assert.EqualValues(t, 0xB, cpu.v[0x3])
+}
+
+func TestCPU_keypadMapping(t *testing.T) {
+	cpu := NewCPU(NewRAM(0x1000), WithKeypadAt(0xF000))
+	cpu.Keypad().Press(0x4)
+	data, err := cpu.ram.Reads(0xF000, Keys)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, data[0x4])
+	assert.NoError(t, cpu.ram.Write(0xF009, 1))
+	assert.True(t, cpu.Keypad().Pressed(0x9))
+	_, err = cpu.ram.Reads(0xF00F, 2)
+	assert.Error(t, err, "reads should not run off the end of the keypad")
+}
+
+func TestCPU_keypadMisaligned(t *testing.T) {
+	cpu := NewCPU(NewRAM(0x1000), WithKeypadAt(0xF010))
+	assert.IsType(t, InvalidRegionAlignment{}, cpu.Step())
+	assert.EqualValues(t, DefaultOrigin, cpu.pc, "nothing should run")
+}
+
+func TestCPU_index(t *testing.T) {
+	tests := []struct {
+		name   string
+		opcode uint16
+		index  uint16
+		vx     byte
+		want   uint16
+	}{
+		{"FX1E", 0xF11E, 0x300, 0x20, 0x320},
+		{"FX29 zero", 0xF129, 0x300, 0x0, 0x0},
+		{"FX29 F", 0xF129, 0x300, 0xF, 0xF * 5},
+		{"FX29 ignores high nibble", 0xF129, 0x300, 0x1A, 0xA * 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu := NewCPU(NewRAM(0x1000))
+			cpu.index, cpu.v[0x1], cpu.v[0xF] = tt.index, tt.vx, 0xAA
+			assert.NoError(t, cpu.ExecuteInstruction(tt.opcode))
+			assert.EqualValues(t, tt.want, cpu.index)
+			assert.EqualValues(t, 0xAA, cpu.v[0xF])
+		})
+	}
+}
+
+func TestCPU_storeBCD(t *testing.T) {
+	tests := []struct {
+		vx   byte
+		want []byte
+	}{
+		{0, []byte{0, 0, 0}},
+		{7, []byte{0, 0, 7}},
+		{42, []byte{0, 4, 2}},
+		{255, []byte{2, 5, 5}},
+	}
+	for _, tt := range tests {
+		cpu := NewCPU(NewRAM(0x1000))
+		cpu.index, cpu.v[0x4] = 0x300, tt.vx
+		assert.NoError(t, cpu.ExecuteInstruction(0xF433))
+		got, err := cpu.ram.Reads(0x300, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, got)
+		assert.EqualValues(t, 0x300, cpu.index)
+	}
+}
+
+func TestCPU_storeAndLoadRegisters(t *testing.T) {
+	cpu := NewCPU(NewRAM(0x1000))
+	copy(cpu.v[:], DataForTest)
+	cpu.index = 0x300
+	assert.NoError(t, cpu.ExecuteInstruction(0xF355))
+	assert.EqualValues(t, 0x304, cpu.index)
+	got, _ := cpu.ram.Reads(0x300, 5)
+	assert.Equal(t, []byte{0x01, 0x02, 0x03, 0x04, 0x00}, got)
+
+	cpu.v = [16]byte{}
+	cpu.index = 0x300
+	assert.NoError(t, cpu.ExecuteInstruction(0xF265))
+	assert.EqualValues(t, 0x303, cpu.index)
+	assert.Equal(t, []byte{0x01, 0x02, 0x03, 0x00}, cpu.v[:4])
+}
+
+func TestCPU_RegisterInstructions(t *testing.T) {
+	cpu := NewCPU(NewRAM(0x1000))
+	registered := len(cpu.opcodes)
+
+	err := cpu.RegisterInstructions([]Instruction{OxJump{Opcode{0x1000, "Another Jump"}}})
+	assert.Equal(t, InstructionConflict{0x1FFF, "Jump", "Another Jump"}, err)
+
+	err = cpu.RegisterInstructions([]Instruction{
+		OxYield{Opcode{0x0100, "Spare"}},
+		OxLoad{Opcode{0x0000, "Overlaps Spare"}},
+	})
+	assert.IsType(t, InstructionConflict{}, err)
+	assert.Equal(t, registered, len(cpu.opcodes), "a failed registration should leave the CPU untouched")
+	assert.IsType(t, InstructionUnknown{}, cpu.ExecuteInstruction(0x0100))
+
+	err = cpu.RegisterInstructions([]Instruction{OxYield{Opcode{0x0100, "Spare"}}})
+	assert.NoError(t, err)
+	assert.NoError(t, cpu.ExecuteInstruction(0x0100))
+}
+
+func TestCPU_decoderCoversEveryOpcode(t *testing.T) {
+	cpu := NewCPU(NewRAM(0x1000))
+	for op := 0; op <= 0xFFFF; op++ {
+		slot := cpu.decoder.table[op]
+		for n, i := range cpu.decoder.instructions {
+			claims := uint16(op)&i.Mask() == i.Code()
+			if claims != (int(slot) == n+1) {
+				t.Fatalf("opcode %04X decodes to slot %d, but %q claims=%v", op, slot, i.Name(), claims)
+			}
+		}
+	}
+}
+
+func BenchmarkCPU_ExecuteInstruction(b *testing.B) {
+	cpu := NewCPU(NewRAM(0x1000))
+	program := []uint16{0x6105, 0x7101, 0x8214, 0x3000, 0xF11E, 0x9120}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		cpu.ExecuteInstruction(program[n%len(program)])
+	}
+}
@@ -0,0 +1,68 @@
+package cpu
+
+import "fmt"
+
+// Decoder maps every opcode to the instruction claiming it in constant
+// time. CPUs dispatch through one, and tools such as the disassembler
+// use one to name opcodes exactly as a CPU would run them.
+type Decoder struct {
+	instructions []Instruction
+	// table maps every opcode to its index in instructions plus one,
+	// zero marking opcodes no instruction claims
+	table [0x10000]uint8
+}
+
+// NewDecoder builds a decoder for instructions, such as a Mode's
+func NewDecoder(instructions []Instruction) (*Decoder, error) {
+	d := &Decoder{}
+	if err := d.Add(instructions); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Add adds the opcodes each instruction claims to the decoder. It fails,
+// adding nothing, if an opcode would be claimed twice.
+func (d *Decoder) Add(instructions []Instruction) error {
+	if len(d.instructions)+len(instructions) > 0xFF {
+		return fmt.Errorf("cannot register %d instructions: decoder is full", len(instructions))
+	}
+	table := d.table
+	for n, i := range instructions {
+		slot := uint8(len(d.instructions) + n + 1)
+		operands := ^i.Mask()
+		// walk every combination of the operand bits, finishing on zero
+		for bits := operands; ; bits = (bits - 1) & operands {
+			opcode := i.Code() | bits
+			if existing := table[opcode]; existing != 0 {
+				var first Instruction
+				if int(existing) <= len(d.instructions) {
+					first = d.instructions[existing-1]
+				} else {
+					first = instructions[int(existing)-len(d.instructions)-1]
+				}
+				return InstructionConflict{opcode, first.Name(), i.Name()}
+			}
+			table[opcode] = slot
+			if bits == 0 {
+				break
+			}
+		}
+	}
+	d.table = table
+	d.instructions = append(d.instructions[:len(d.instructions):len(d.instructions)], instructions...)
+	return nil
+}
+
+// Decode returns the instruction claiming opcode, if there is one
+func (d *Decoder) Decode(opcode uint16) (Instruction, bool) {
+	if slot := d.table[opcode]; slot != 0 {
+		return d.instructions[slot-1], true
+	}
+	return nil, false
+}
+
+// Instructions returns the instructions in the order they were added
+func (d *Decoder) Instructions() []Instruction {
+	return d.instructions
+}
@@ -0,0 +1,231 @@
+package cpu
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+)
+
+// maxBlockLength caps how many instructions a block decodes, so a long
+// run of straight-line code is cached in pieces
+const maxBlockLength = 64
+
+// compiled is an instruction decoded ahead of time into a closure that
+// executes it, fetch and all
+type compiled struct {
+	pc  uint16
+	run func() error
+}
+
+// block is a straight-line run of instructions from start, ending at the
+// first that can change the flow of control. stale marks blocks written
+// over since they were decoded.
+type block struct {
+	start        uint16
+	end          uint16
+	instructions []compiled
+	stale        bool
+}
+
+// blockCache holds the blocks decoded so far by start address. Memory is
+// watched through the Rammer, blocks being dropped as anything in them is
+// written, and all of them when memory changes unobserved.
+type blockCache struct {
+	blocks map[uint16]*block
+	// covered counts the blocks holding each address, so writes to data
+	// are passed over quickly
+	covered    []uint8
+	unobserved uint64
+	// current is the block executing and next the index in it of the
+	// instruction due next
+	current *block
+	next    int
+}
+
+// WithBlockCache executes cached blocks of instructions decoded ahead of
+// time, rather than fetching and decoding each as it comes. Programs run
+// the same either way, only faster, as Lockstep checks.
+func WithBlockCache() Option {
+	return func(c *CPU) {
+		c.jit = &blockCache{blocks: make(map[uint16]*block)}
+	}
+}
+
+// watch sizes the cache to memory and drops blocks as they are written
+func (j *blockCache) watch(c *CPU) {
+	j.covered = make([]uint8, c.mode.MemorySize())
+	j.unobserved = c.ram.unobserved
+	c.ram.Observe(func(a Access) {
+		if a.Write && int(a.Addr) < len(j.covered) && j.covered[a.Addr] > 0 {
+			j.invalidate(a.Addr)
+		}
+	})
+}
+
+// invalidate drops every block holding addr
+func (j *blockCache) invalidate(addr uint16) {
+	for start, b := range j.blocks {
+		if addr >= b.start && addr < b.end {
+			b.stale = true
+			delete(j.blocks, start)
+			j.cover(b, -1)
+		}
+	}
+}
+
+// flush drops every block
+func (j *blockCache) flush() {
+	for _, b := range j.blocks {
+		b.stale = true
+	}
+	j.blocks = make(map[uint16]*block)
+	for addr := range j.covered {
+		j.covered[addr] = 0
+	}
+}
+
+func (j *blockCache) cover(b *block, by int) {
+	for addr := int(b.start); addr < int(b.end) && addr < len(j.covered); addr++ {
+		j.covered[addr] = uint8(int(j.covered[addr]) + by)
+	}
+}
+
+// endsBlock reports whether an instruction can move the PC anywhere but
+// on to the next instruction
+func endsBlock(code uint16) bool {
+	switch code {
+	case 0x00EE, 0x00FD, 0x1000, 0x2000, 0xB000, 0x3000, 0x4000, 0x5000, 0x9000, 0xE09E, 0xE0A1, 0xF00A:
+		return true
+	}
+	return false
+}
+
+// compile decodes the block starting at start, which is empty if the
+// first instruction cannot be fetched or decoded
+func (c *CPU) compile(start uint16) *block {
+	b := &block{start: start, end: start}
+	for len(b.instructions) < maxBlockLength {
+		pc := b.end
+		data, err := c.ram.Peek(pc, 2)
+		if err != nil {
+			break
+		}
+		opcode := binary.BigEndian.Uint16(data)
+		slot := c.decoder.table[opcode]
+		if slot == 0 {
+			break
+		}
+		b.instructions = append(b.instructions, compiled{pc, c.bind(pc, data, c.opcodes[slot-1], opcode)})
+		i := c.decoder.instructions[slot-1]
+		b.end += Length(i)
+		if endsBlock(i.Code()) {
+			break
+		}
+	}
+	return b
+}
+
+// bind returns a closure executing the instruction at pc as
+// FetchInstruction and ExecuteInstruction would
+func (c *CPU) bind(pc uint16, data []byte, handler InstructionHandler, opcode uint16) func() error {
+	return func() error {
+		if len(c.ram.hooks) > 0 {
+			c.ram.observe(pc, data, false, true)
+		}
+		c.pc += 2
+		return handler.HandleInstruction(opcode)
+	}
+}
+
+// executeCached executes the instruction at the PC from the block cache,
+// decoding the block there first if need be. Anything the cache cannot
+// hold, such as an unknown opcode, goes through execute for its error.
+func (c *CPU) executeCached() error {
+	j := c.jit
+	if j.covered == nil {
+		j.watch(c)
+	}
+	if j.unobserved != c.ram.unobserved {
+		j.flush()
+		j.unobserved = c.ram.unobserved
+	}
+	b := j.current
+	if b == nil || b.stale || j.next >= len(b.instructions) || b.instructions[j.next].pc != c.pc {
+		b = j.blocks[c.pc]
+		if b == nil {
+			if b = c.compile(c.pc); len(b.instructions) == 0 {
+				j.current = nil
+				return c.execute()
+			}
+			j.blocks[c.pc] = b
+			j.cover(b, 1)
+		}
+		j.current, j.next = b, 0
+	}
+	i := b.instructions[j.next]
+	j.next++
+	return i.run()
+}
+
+// LockstepDiverged is an error Lockstep returns when two CPUs disagree
+type LockstepDiverged struct {
+	step   int
+	pc     uint16
+	reason string
+}
+
+func (l LockstepDiverged) Error() string {
+	return fmt.Sprintf("step %d, at %X: %s", l.step, l.pc, l.reason)
+}
+
+// Lockstep steps a and b together up to steps times, checking after each
+// step that their registers, memory and screens still match. It is for
+// proving that a CPU with WithBlockCache or WithNatives runs as one
+// without does. It returns the first divergence, nil if they match
+// throughout or the first error both steps return alike.
+func Lockstep(a, b *CPU, steps int) error {
+	for step := 1; step <= steps; step++ {
+		pc := a.pc
+		errA, errB := a.Step(), b.Step()
+		if fmt.Sprint(errA) != fmt.Sprint(errB) {
+			return LockstepDiverged{step, pc, fmt.Sprintf("stepping failed with %v and %v", errA, errB)}
+		}
+		if ra, rb := a.Registers(), b.Registers(); ra != rb {
+			return LockstepDiverged{step, pc, fmt.Sprintf("registers %+v and %+v", ra, rb)}
+		}
+		if reason := compareMemory(a, b); reason != "" {
+			return LockstepDiverged{step, pc, reason}
+		}
+		if !reflect.DeepEqual(a.Frame(), b.Frame()) {
+			return LockstepDiverged{step, pc, "screens differ"}
+		}
+		if errA != nil {
+			return nil
+		}
+	}
+	return nil
+}
+
+func compareMemory(a, b *CPU) string {
+	size := a.mode.MemorySize()
+	if b.mode.MemorySize() != size {
+		return "memory sizes differ"
+	}
+	for start := 0; start < size; start += 0x1000 {
+		ma, errA := a.ram.Peek(uint16(start), 0x1000)
+		mb, errB := b.ram.Peek(uint16(start), 0x1000)
+		if errA != nil || errB != nil {
+			return fmt.Sprintf("reading memory failed with %v and %v", errA, errB)
+		}
+		if bytes.Equal(ma, mb) {
+			continue
+		}
+		for at := range ma {
+			if ma[at] != mb[at] {
+				return fmt.Sprintf("memory at %X holds %02X and %02X", start+at, ma[at], mb[at])
+			}
+		}
+	}
+	return ""
+}
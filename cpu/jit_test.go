@@ -0,0 +1,119 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// busy calls a subroutine drawing digits, storing random numbers as BCD
+// and reading them back, looping forever
+var busy = []byte{
+	0x60, 0x05, // 200: LD V0, 5
+	0x61, 0x00, // 202: LD V1, 0
+	0x22, 0x0E, // 204: CALL 20E
+	0x71, 0x01, // 206: ADD V1, 1
+	0x31, 0x05, // 208: SE V1, 5
+	0x12, 0x04, // 20A: JP 204
+	0x12, 0x00, // 20C: JP 200
+	0xF0, 0x29, // 20E: LD F, V0
+	0xD0, 0x15, // 210: DRW V0, V1, 5
+	0xC2, 0xFF, // 212: RND V2, 0xFF
+	0xA3, 0x00, // 214: LD I, 0x300
+	0xF2, 0x33, // 216: LD B, V2
+	0xF2, 0x65, // 218: LD V2, [I]
+	0x80, 0x24, // 21A: ADD V0, V2
+	0x00, 0xEE, // 21C: RET
+}
+
+// patching counts up in V0 and writes it over the operand of the ADD at
+// 208, so V1 adds up 1, 2, 3...
+var patching = []byte{
+	0xA2, 0x09, // 200: LD I, 0x209
+	0x70, 0x01, // 202: ADD V0, 1
+	0xF0, 0x55, // 204: LD [I], V0
+	0x63, 0x00, // 206: LD V3, 0
+	0x71, 0x00, // 208: ADD V1, 0
+	0x12, 0x00, // 20A: JP 200
+}
+
+// counting is arithmetic in a tight loop, which decoding dominates
+var counting = []byte{
+	0x70, 0x01, // 200: ADD V0, 1
+	0x81, 0x04, // 202: ADD V1, V0
+	0x82, 0x13, // 204: XOR V2, V1
+	0x30, 0x00, // 206: SE V0, 0
+	0x12, 0x00, // 208: JP 200
+	0x73, 0x01, // 20A: ADD V3, 1
+	0x12, 0x00, // 20C: JP 200
+}
+
+func lockstep(t *testing.T, program []byte, options ...Option) (*CPU, *CPU) {
+	return newProgram(t, program, options...), newProgram(t, program, append(options, WithBlockCache())...)
+}
+
+func TestLockstep(t *testing.T) {
+	a, b := lockstep(t, busy)
+	assert.NoError(t, Lockstep(a, b, 5000))
+	assert.NotEmpty(t, b.jit.blocks)
+
+	a, b = lockstep(t, counting)
+	assert.NoError(t, Lockstep(a, b, 5000))
+
+	xo := []Option{WithMode(ModeXOChip), WithQuirks(QuirksXOChip)}
+	a = NewCPU(NewRAM(0x10000), xo...)
+	b = NewCPU(NewRAM(0x10000), append(xo, WithBlockCache())...)
+	for _, c := range []*CPU{a, b} {
+		assert.NoError(t, c.Memory().Poke(0x200, busy))
+	}
+	assert.NoError(t, Lockstep(a, b, 5000))
+}
+
+func TestLockstep_selfModifying(t *testing.T) {
+	a, b := lockstep(t, patching)
+	// five times round the loop of six instructions
+	assert.NoError(t, Lockstep(a, b, 30))
+	assert.EqualValues(t, 1+2+3+4+5, b.V(0x1))
+}
+
+func TestLockstep_unobserved(t *testing.T) {
+	a, b := lockstep(t, busy)
+	assert.NoError(t, Lockstep(a, b, 100))
+	// ADD V1, 2 so the loop never ends, and a fault in place of its jump
+	for _, c := range []*CPU{a, b} {
+		assert.NoError(t, c.Memory().Poke(0x206, []byte{0x71, 0x02}))
+		assert.NoError(t, c.Memory().Poke(0x20A, []byte{0x00, 0x00}))
+	}
+	assert.NoError(t, Lockstep(a, b, 100))
+	assert.EqualValues(t, 0x20C, b.PC(), "the block cache should see pokes")
+}
+
+func TestLockstep_diverged(t *testing.T) {
+	a := newProgram(t, busy)
+	b := newProgram(t, patching, WithBlockCache())
+	assert.Equal(t, LockstepDiverged{1, 0x200, "registers {PC:514 I:0 SP:0 V:[5 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0] DT:0 ST:0} and {PC:514 I:521 SP:0 V:[0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0] DT:0 ST:0}"}, Lockstep(a, b, 10))
+
+	a, b = newProgram(t, []byte{0x00, 0x00}), newProgram(t, []byte{0x00, 0x00}, WithBlockCache())
+	assert.NoError(t, Lockstep(a, b, 10), "matching errors should end the run")
+}
+
+func benchmarkFrames(b *testing.B, options ...Option) {
+	c := NewCPU(NewRAM(0x1000), options...)
+	if err := c.ram.Writes(0x200, counting); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err := c.RunFrame(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRunFrame(b *testing.B) {
+	benchmarkFrames(b)
+}
+
+func BenchmarkRunFrame_blockCache(b *testing.B) {
+	benchmarkFrames(b, WithBlockCache())
+}
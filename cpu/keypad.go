@@ -0,0 +1,116 @@
+package cpu
+
+import "fmt"
+
+// Keys is the number of keys on the hex keypad
+const Keys = 16
+
+// Keypad is the 16 key hex keypad. Frontends press and release keys on
+// it, and as a Device it can be mapped into memory, a byte per key being
+// 1 while that key is held.
+type Keypad struct {
+	uuid string
+	keys [Keys]bool
+	// waiting is set while FX0A waits, and released holds the key let go
+	// of since, or -1 until there is one
+	waiting  bool
+	released int
+}
+
+func NewKeypad() *Keypad {
+	return &Keypad{
+		uuid:     fmt.Sprintf("Keypad::%s", RandomStringUUID()),
+		released: -1,
+	}
+}
+
+func (k *Keypad) UUID() string {
+	return k.uuid
+}
+
+// Press holds key down
+func (k *Keypad) Press(key byte) {
+	k.keys[key&0xF] = true
+}
+
+// Release lets go of key
+func (k *Keypad) Release(key byte) {
+	key &= 0xF
+	if k.keys[key] && k.waiting && k.released < 0 {
+		k.released = int(key)
+	}
+	k.keys[key] = false
+}
+
+// Pressed reports whether key is held down
+func (k *Keypad) Pressed(key byte) bool {
+	return k.keys[key&0xF]
+}
+
+// wait returns the key that was pressed and released since waiting
+// started, the first call starting the wait
+func (k *Keypad) wait() (byte, bool) {
+	if !k.waiting {
+		k.waiting = true
+		k.released = -1
+		return 0, false
+	}
+	if k.released < 0 {
+		return 0, false
+	}
+	key := byte(k.released)
+	k.waiting = false
+	k.released = -1
+	return key, true
+}
+
+func (k *Keypad) Read(addr uint16) (byte, error) {
+	if err := k.checkBounds(addr, 1); err != nil {
+		return 0, err
+	}
+	if k.keys[addr] {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (k *Keypad) Reads(addr uint16, size uint16) ([]byte, error) {
+	if err := k.checkBounds(addr, int(size)); err != nil {
+		return nil, err
+	}
+	data := make([]byte, size)
+	for i := range data {
+		data[i], _ = k.Read(addr + uint16(i))
+	}
+	return data, nil
+}
+
+// Write presses the key at addr for a non-zero value and releases it otherwise
+func (k *Keypad) Write(addr uint16, value byte) error {
+	if err := k.checkBounds(addr, 1); err != nil {
+		return err
+	}
+	if value != 0 {
+		k.Press(byte(addr))
+	} else {
+		k.Release(byte(addr))
+	}
+	return nil
+}
+
+func (k *Keypad) Writes(addr uint16, values []byte) error {
+	if err := k.checkBounds(addr, len(values)); err != nil {
+		return err
+	}
+	for i, value := range values {
+		k.Write(addr+uint16(i), value)
+	}
+	return nil
+}
+
+func (k *Keypad) checkBounds(addr uint16, length int) error {
+	if int(addr)+length > Keys {
+		return AddressOutOfRange{Keys, addr + uint16(length)}
+	}
+	return nil
+}
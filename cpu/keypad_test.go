@@ -0,0 +1,55 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeypad_PressRelease(t *testing.T) {
+	keypad := NewKeypad()
+	keypad.Press(0xA)
+	assert.True(t, keypad.Pressed(0xA))
+	assert.False(t, keypad.Pressed(0xB))
+	keypad.Release(0xA)
+	assert.False(t, keypad.Pressed(0xA))
+}
+
+func TestKeypad_wait(t *testing.T) {
+	keypad := NewKeypad()
+	keypad.Press(0x3)
+	keypad.Release(0x3)
+	_, ok := keypad.wait()
+	assert.False(t, ok, "releases before waiting should not count")
+
+	keypad.Press(0x7)
+	_, ok = keypad.wait()
+	assert.False(t, ok, "a press alone should not end the wait")
+	keypad.Release(0x8)
+	_, ok = keypad.wait()
+	assert.False(t, ok, "releasing a key that was not held should not end the wait")
+	keypad.Release(0x7)
+	key, ok := keypad.wait()
+	assert.True(t, ok)
+	assert.EqualValues(t, 0x7, key)
+
+	_, ok = keypad.wait()
+	assert.False(t, ok, "the next wait should start afresh")
+}
+
+func TestKeypad_Device(t *testing.T) {
+	keypad := NewKeypad()
+	keypad.Press(0x2)
+	data, err := keypad.Reads(0x0, Keys)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, data)
+
+	assert.NoError(t, keypad.Writes(0xE, []byte{1, 1}))
+	assert.True(t, keypad.Pressed(0xF))
+	assert.NoError(t, keypad.Write(0x2, 0))
+	assert.False(t, keypad.Pressed(0x2))
+
+	_, err = keypad.Read(Keys)
+	assert.Error(t, err)
+	assert.Error(t, keypad.Writes(0xF, []byte{1, 1}))
+}
@@ -0,0 +1,62 @@
+package cpu
+
+// Mode is the instruction set the CPU understands
+type Mode int
+
+const (
+	// ModeChip8 is the original CHIP-8 instruction set
+	ModeChip8 Mode = iota
+	// ModeSuperChip adds the SUPER-CHIP 1.1 instructions
+	ModeSuperChip
+	// ModeXOChip adds the XO-CHIP instructions on top of SUPER-CHIP,
+	// along with a 64KiB address space
+	ModeXOChip
+)
+
+// Modes names each of the modes, for picking one from configuration
+var Modes = map[string]Mode{
+	"chip8":  ModeChip8,
+	"schip":  ModeSuperChip,
+	"xochip": ModeXOChip,
+}
+
+func (m Mode) String() string {
+	switch m {
+	case ModeChip8:
+		return "CHIP-8"
+	case ModeSuperChip:
+		return "SUPER-CHIP"
+	case ModeXOChip:
+		return "XO-CHIP"
+	}
+	return "unknown"
+}
+
+// Instructions returns every instruction available in the mode
+func (m Mode) Instructions() []Instruction {
+	instructions := append([]Instruction{}, AllOpcodes...)
+	if m >= ModeSuperChip {
+		instructions = append(instructions, SuperChipOpcodes...)
+	}
+	if m >= ModeXOChip {
+		instructions = append(instructions, XOChipOpcodes...)
+	}
+	return instructions
+}
+
+// Decoder builds a decoder for the mode's instructions
+func (m Mode) Decoder() *Decoder {
+	d, err := NewDecoder(m.Instructions())
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// MemorySize returns how many bytes of program memory the mode can address
+func (m Mode) MemorySize() int {
+	if m >= ModeXOChip {
+		return 0x10000
+	}
+	return 0x1000
+}
@@ -0,0 +1,110 @@
+package cpu
+
+import "encoding/binary"
+
+// Native is an instruction translated ahead of time into Go, as the aot
+// package does. Run executes it as its handler would, the PC having been
+// moved past Opcode, so for anything it does not do itself it can hand
+// Opcode to ExecuteInstruction.
+type Native struct {
+	Opcode uint16
+	Run    func(c *CPU) error
+}
+
+// natives holds the translated instructions by address. valid marks
+// those whose opcode memory still holds, memory being watched through the
+// Rammer as the block cache watches it, so any written over since
+// translation fall back to the interpreter.
+type natives struct {
+	byAddr     map[uint16]Native
+	code       []Native
+	data       [][]byte
+	valid      []bool
+	unobserved uint64
+	// ran and interpreted count the instructions executed natively and
+	// those that fell back
+	ran         uint64
+	interpreted uint64
+}
+
+// WithNatives runs the instructions in code, by address, in place of
+// fetching and decoding them, so long as memory still holds the opcodes
+// they were translated from. Anywhere else, as where code was computed
+// or rewritten at run time, the interpreter takes over.
+func WithNatives(code map[uint16]Native) Option {
+	return func(c *CPU) {
+		c.natives = &natives{byAddr: code}
+	}
+}
+
+// NativeCounts returns how many instructions ran natively and how many
+// the interpreter executed instead, both zero without WithNatives
+func (c *CPU) NativeCounts() (ran, interpreted uint64) {
+	if c.natives == nil {
+		return 0, 0
+	}
+	return c.natives.ran, c.natives.interpreted
+}
+
+// watch lays the natives out across memory and checks each against it,
+// then rechecks those a write could have touched as they are written
+func (n *natives) watch(c *CPU) {
+	size := c.mode.MemorySize()
+	n.code, n.data, n.valid = make([]Native, size), make([][]byte, size), make([]bool, size)
+	for addr, native := range n.byAddr {
+		if int(addr) < size {
+			n.code[addr] = native
+			n.data[addr] = []byte{byte(native.Opcode >> 8), byte(native.Opcode)}
+		}
+	}
+	n.recheck(c, 0, len(n.code))
+	n.unobserved = c.ram.unobserved
+	c.ram.Observe(func(a Access) {
+		if a.Write {
+			n.recheck(c, int(a.Addr)-1, int(a.Addr)+1)
+		}
+	})
+}
+
+// recheck marks which natives from start up to end match memory
+func (n *natives) recheck(c *CPU, start, end int) {
+	if start < 0 {
+		start = 0
+	}
+	for addr := start; addr < end && addr < len(n.code); addr++ {
+		n.valid[addr] = false
+		if n.code[addr].Run == nil {
+			continue
+		}
+		if data, err := c.ram.Peek(uint16(addr), 2); err == nil {
+			n.valid[addr] = binary.BigEndian.Uint16(data) == n.code[addr].Opcode
+		}
+	}
+}
+
+// executeNative executes the instruction at the PC natively if it can,
+// or else through the block cache or interpreter
+func (c *CPU) executeNative() error {
+	n := c.natives
+	if n.code == nil {
+		n.watch(c)
+	}
+	if n.unobserved != c.ram.unobserved {
+		n.recheck(c, 0, len(n.code))
+		n.unobserved = c.ram.unobserved
+	}
+	pc := c.pc
+	if int(pc) >= len(n.code) || !n.valid[pc] {
+		n.interpreted++
+		if c.jit != nil {
+			return c.executeCached()
+		}
+		return c.execute()
+	}
+	n.ran++
+	if len(c.ram.hooks) > 0 {
+		c.ram.observe(pc, n.data[pc], false, true)
+	}
+	c.pc += 2
+	return n.code[pc].Run(c)
+}
@@ -0,0 +1,53 @@
+package cpu
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// handled translates every instruction of program into a native that
+// hands it to its handler
+func handled(program []byte) map[uint16]Native {
+	natives := make(map[uint16]Native)
+	for at := 0; at+1 < len(program); at += 2 {
+		opcode := binary.BigEndian.Uint16(program[at:])
+		natives[uint16(0x200+at)] = Native{opcode, func(c *CPU) error {
+			return c.ExecuteInstruction(opcode)
+		}}
+	}
+	return natives
+}
+
+func TestNatives(t *testing.T) {
+	// LD V0, 5; JP 0x200, with the load translated as loading 6 to tell
+	// it apart
+	c := newProgram(t, []byte{0x60, 0x05, 0x12, 0x00}, WithNatives(map[uint16]Native{
+		0x200: {0x6005, func(c *CPU) error {
+			c.SetV(0x0, 6)
+			return nil
+		}},
+	}))
+	assert.NoError(t, c.Step())
+	assert.EqualValues(t, 6, c.V(0x0))
+	assert.EqualValues(t, 0x202, c.PC())
+	assert.NoError(t, c.Step())
+
+	assert.NoError(t, c.Memory().Poke(0x200, []byte{0x60, 0x07}))
+	assert.NoError(t, c.Step())
+	assert.EqualValues(t, 7, c.V(0x0), "a native written over should fall back")
+	ran, interpreted := c.NativeCounts()
+	assert.EqualValues(t, 1, ran)
+	assert.EqualValues(t, 2, interpreted)
+}
+
+func TestLockstep_natives(t *testing.T) {
+	a := newProgram(t, patching)
+	b := newProgram(t, patching, WithNatives(handled(patching)))
+	assert.NoError(t, Lockstep(a, b, 30))
+	assert.EqualValues(t, 1+2+3+4+5, b.V(0x1))
+	ran, interpreted := b.NativeCounts()
+	assert.EqualValues(t, 25, ran)
+	assert.EqualValues(t, 5, interpreted, "the patched ADD should be interpreted")
+}
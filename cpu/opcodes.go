@@ -1,23 +1,68 @@
 package cpu
 
 import (
-	"encoding/binary"
 	"fmt"
 )
 
 var AllOpcodes = []Instruction{
 	OxClearScreen{Opcode{0x00E0, "Clear Screen"}},
-	OxDrawSprite{Opcode{0xD000, "Draw Sprite"}},
+	OxYield{Opcode{0x00EC, "Yield"}},
 	OxReturn{Opcode{0x00EE, "Return from subroutine"}},
+	OxJump{Opcode{0x1000, "Jump"}},
 	OxCall{Opcode{0x2000, "Call Subroutine"}},
+	OxSkipEqual{Opcode{0x3000, "Skip If Equal"}},
+	OxSkipNotEqual{Opcode{0x4000, "Skip If Not Equal"}},
+	OxSkipRegistersEqual{Opcode{0x5000, "Skip If Registers Equal"}},
+	OxLoad{Opcode{0x6000, "Load"}},
+	OxAdd{Opcode{0x7000, "Add"}},
+	OxMove{Opcode{0x8000, "Move"}},
+	OxOr{Opcode{0x8001, "Or"}},
+	OxAnd{Opcode{0x8002, "And"}},
+	OxXor{Opcode{0x8003, "Xor"}},
+	OxAddRegisters{Opcode{0x8004, "Add Registers"}},
+	OxSubtract{Opcode{0x8005, "Subtract"}},
+	OxShiftRight{Opcode{0x8006, "Shift Right"}},
+	OxSubtractReverse{Opcode{0x8007, "Subtract Reverse"}},
+	OxShiftLeft{Opcode{0x800E, "Shift Left"}},
+	OxSkipRegistersNotEqual{Opcode{0x9000, "Skip If Registers Not Equal"}},
 	OxLoadIndex{Opcode{0xA000, "Load Index"}},
+	OxJumpOffset{Opcode{0xB000, "Jump With Offset"}},
+	OxRandom{Opcode{0xC000, "Random"}},
+	OxDrawSprite{Opcode{0xD000, "Draw Sprite"}},
+	OxSkipKeyPressed{Opcode{0xE09E, "Skip If Key Pressed"}},
+	OxSkipKeyNotPressed{Opcode{0xE0A1, "Skip If Key Not Pressed"}},
+	OxGetDelay{Opcode{0xF007, "Get Delay Timer"}},
+	OxWaitKey{Opcode{0xF00A, "Wait For Key"}},
+	OxSetDelay{Opcode{0xF015, "Set Delay Timer"}},
+	OxSetSound{Opcode{0xF018, "Set Sound Timer"}},
+	OxAddIndex{Opcode{0xF01E, "Add To Index"}},
+	OxLoadFont{Opcode{0xF029, "Load Font"}},
+	OxStoreBCD{Opcode{0xF033, "Store BCD"}},
+	OxStoreRegisters{Opcode{0xF055, "Store Registers"}},
+	OxLoadRegisters{Opcode{0xF065, "Load Registers"}},
 }
 
+// Masks select the bits of an opcode that identify its instruction,
+// everything else being operands.
+const (
+	MaskOpcode uint16 = 0xFFFF // no operands, e.g. 00E0
+	MaskClass  uint16 = 0xF000 // NNN, XNN or XYN operands, e.g. 1NNN
+	MaskXY     uint16 = 0xF00F // X and Y operands, e.g. 8XY4
+	MaskX      uint16 = 0xF0FF // X operand only, e.g. FX1E
+	MaskN      uint16 = 0xFFF0 // N operand only, e.g. 00CN
+)
+
 type InstructionHandler interface {
 	HandleInstruction(uint16) error
 }
+
+// Instruction describes one instruction of the set. It claims every opcode
+// whose bits under Mask equal Code, and Register binds it to a CPU.
+// Handlers are only ever called with opcodes they claimed.
 type Instruction interface {
 	Name() string
+	Code() uint16
+	Mask() uint16
 	Register(cpu *CPU) InstructionHandler
 }
 type InstructionHandlerFunc func(uint16) error
@@ -34,12 +79,15 @@ func (iu InstructionUnknown) Error() string {
 	return fmt.Sprintf("unknown instruction: %X", iu.opcode)
 }
 
-type InstructionNOP struct {
+// InstructionConflict is raised when two instructions claim the same opcode
+type InstructionConflict struct {
 	opcode uint16
+	first  string
+	second string
 }
 
-func (io InstructionNOP) Error() string {
-	return fmt.Sprintf("NOP: %X", io.opcode)
+func (ic InstructionConflict) Error() string {
+	return fmt.Sprintf("instructions %q and %q both claim opcode %04X", ic.first, ic.second, ic.opcode)
 }
 
 type Opcode struct {
@@ -51,64 +99,113 @@ func (i Opcode) Name() string {
 	return i.name
 }
 
+func (i Opcode) Code() uint16 {
+	return i.opcode
+}
+
+// opX returns the X register index of an opcode
+func opX(op uint16) uint16 {
+	return op & 0x0F00 >> 8
+}
+
+// opY returns the Y register index of an opcode
+func opY(op uint16) uint16 {
+	return op & 0x00F0 >> 4
+}
+
+// opN returns the lowest nibble of an opcode
+func opN(op uint16) uint16 {
+	return op & 0x000F
+}
+
+// opNN returns the lowest byte of an opcode
+func opNN(op uint16) byte {
+	return byte(op & 0x00FF)
+}
+
+// opNNN returns the address held in the lowest 12 bits of an opcode
+func opNNN(op uint16) uint16 {
+	return op & 0x0FFF
+}
+
 type OxClearScreen struct {
 	Opcode
 }
 
+func (o OxClearScreen) Mask() uint16 {
+	return MaskOpcode
+}
+
 func (o OxClearScreen) Register(cpu *CPU) InstructionHandler {
 	return InstructionHandlerFunc(func(op uint16) error {
-		if op == o.opcode {
-			for i := uint16(0); i < cpu.screen.size; i++ {
-				cpu.ram.Write(cpu.screen.address+i, 0x0)
-			}
-			return nil
-		}
-		return InstructionNOP{op}
+		return cpu.screen.clear()
+	})
+}
+
+// OxYield is a no-op, execution simply continues at the next instruction
+type OxYield struct {
+	Opcode
+}
+
+func (o OxYield) Mask() uint16 {
+	return MaskOpcode
+}
+
+func (o OxYield) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		return nil
 	})
 }
 
 type OxDrawSprite struct {
 	Opcode
 }
+
+func (o OxDrawSprite) Mask() uint16 {
+	return MaskClass
+}
+
+// Register draws N rows of the 8 pixel wide sprite at I to VX,VY. From
+// SUPER-CHIP on, N of zero draws a 16x16 sprite of 32 bytes instead.
+// On XO-CHIP each selected plane takes its own sprite, one after another.
+// The starting position wraps around the screen, the sprite itself either
+// wraps or is clipped at the edges depending on the ClipSprites quirk.
+// VF is set when any lit pixel is turned off.
 func (o OxDrawSprite) Register(cpu *CPU) InstructionHandler {
 	return InstructionHandlerFunc(func(op uint16) error {
-		if op & 0xF000 == o.opcode {
-			x := op & 0x0F00 >> 8
-			y := op & 0x00F0 >> 4
-			height := op & 0x000F
-			spriteAddress := cpu.index
-			xCoord := uint16(cpu.v[x] % 64)
-			yCoord := uint16(cpu.v[y] % 32)
-			cpu.v[0xF] = 0
+		spriteWidth, spriteHeight := uint16(8), opN(op)
+		if spriteHeight == 0 && cpu.mode >= ModeSuperChip {
+			spriteWidth, spriteHeight = 16, 16
+		}
+		planes := uint16(len(cpu.screen.selected()))
+		sprite, err := cpu.ram.Reads(cpu.index, planes*spriteHeight*spriteWidth/8)
+		if err != nil {
+			return err
+		}
+		collided, err := cpu.drawSprite(cpu.v[opX(op)], cpu.v[opY(op)], spriteWidth, sprite)
+		if err != nil {
+			return err
+		}
+		cpu.v[0xF] = 0
+		if collided {
+			cpu.v[0xF] = 1
+		}
+		return nil
+	})
+}
 
+type OxJump struct {
+	Opcode
+}
 
-			sprite, err := cpu.ram.Reads(spriteAddress, height)
-			if err != nil {
-				return err
-			}
-			for yPos, b := range sprite {
-				for xPos := uint16(0); xPos < 8; xPos++ {
-					if xCoord + xPos >= 64 || yCoord + uint16(yPos) >= 32 {
-						continue
-					}
-					pos := (y + uint16(yPos)) * 64 + x + xPos
-					pos += cpu.screen.address
-					onScreen, err := cpu.ram.Read(pos)
-					if err != nil {
-						return err
-					}
-					toBe := b & (0x80 >> xPos)
-					if toBe != 0 && onScreen != 0 {
-						cpu.ram.Write(pos, 0)
-						cpu.v[0xF] = 1
-					} else if toBe != 0 && onScreen == 0 {
-						cpu.ram.Write(pos, 1)
-					}
-				}
-			}
-			return nil
-		}
-		return InstructionNOP{op}
+func (o OxJump) Mask() uint16 {
+	return MaskClass
+}
+
+func (o OxJump) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		cpu.SetPC(opNNN(op))
+		return nil
 	})
 }
 
@@ -116,53 +213,541 @@ type OxCall struct {
 	Opcode
 }
 
+func (o OxCall) Mask() uint16 {
+	return MaskClass
+}
+
 func (o OxCall) Register(cpu *CPU) InstructionHandler {
 	return InstructionHandlerFunc(func(op uint16) error {
-		if op & 0xF000 == o.opcode {
-			ok := cpu.stack.Push(cpu.pc)
-			if !ok {
-				return fmt.Errorf("Stack overflow")
-			}
-			cpu.SetPC(op & 0x0FFF)
-			return nil
-		}
-		return InstructionNOP{op}
+		return cpu.callSubroutine(op)
 	})
 }
 
 type OxReturn struct {
 	Opcode
-}	
+}
+
+func (o OxReturn) Mask() uint16 {
+	return MaskOpcode
+}
 
 func (o OxReturn) Register(cpu *CPU) InstructionHandler {
 	return InstructionHandlerFunc(func(op uint16) error {
-		if op & 0xF000 == o.opcode {
-			d, ok := cpu.stack.Pop()
-			if !ok {
-				return fmt.Errorf("Stack is empty")
-			}
-			cpu.SetPC(d)
-			return nil
+		return cpu.returnFromSubroutine()
+	})
+}
+
+// OxSkipEqual skips the next instruction if VX equals NN
+type OxSkipEqual struct {
+	Opcode
+}
+
+func (o OxSkipEqual) Mask() uint16 {
+	return MaskClass
+}
+
+func (o OxSkipEqual) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		if cpu.v[opX(op)] == opNN(op) {
+			cpu.skipInstruction()
+		}
+		return nil
+	})
+}
+
+// OxSkipNotEqual skips the next instruction if VX does not equal NN
+type OxSkipNotEqual struct {
+	Opcode
+}
+
+func (o OxSkipNotEqual) Mask() uint16 {
+	return MaskClass
+}
+
+func (o OxSkipNotEqual) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		if cpu.v[opX(op)] != opNN(op) {
+			cpu.skipInstruction()
+		}
+		return nil
+	})
+}
+
+// OxSkipRegistersEqual skips the next instruction if VX equals VY
+type OxSkipRegistersEqual struct {
+	Opcode
+}
+
+func (o OxSkipRegistersEqual) Mask() uint16 {
+	return MaskXY
+}
+
+func (o OxSkipRegistersEqual) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		if cpu.v[opX(op)] == cpu.v[opY(op)] {
+			cpu.skipInstruction()
 		}
-		return InstructionNOP{op}
+		return nil
+	})
+}
+
+// OxSkipRegistersNotEqual skips the next instruction if VX does not equal VY
+type OxSkipRegistersNotEqual struct {
+	Opcode
+}
+
+func (o OxSkipRegistersNotEqual) Mask() uint16 {
+	return MaskXY
+}
+
+func (o OxSkipRegistersNotEqual) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		if cpu.v[opX(op)] != cpu.v[opY(op)] {
+			cpu.skipInstruction()
+		}
+		return nil
+	})
+}
+
+// OxLoad sets VX to NN
+type OxLoad struct {
+	Opcode
+}
+
+func (o OxLoad) Mask() uint16 {
+	return MaskClass
+}
+
+func (o OxLoad) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		cpu.v[opX(op)] = opNN(op)
+		return nil
+	})
+}
+
+// OxAdd adds NN to VX, VF is left untouched
+type OxAdd struct {
+	Opcode
+}
+
+func (o OxAdd) Mask() uint16 {
+	return MaskClass
+}
+
+func (o OxAdd) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		cpu.v[opX(op)] += opNN(op)
+		return nil
+	})
+}
+
+// OxMove sets VX to VY
+type OxMove struct {
+	Opcode
+}
+
+func (o OxMove) Mask() uint16 {
+	return MaskXY
+}
+
+func (o OxMove) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		cpu.v[opX(op)] = cpu.v[opY(op)]
+		return nil
+	})
+}
+
+// OxOr sets VX to VX|VY, resetting VF with the LogicResetsVF quirk
+type OxOr struct {
+	Opcode
+}
+
+func (o OxOr) Mask() uint16 {
+	return MaskXY
+}
+
+func (o OxOr) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		cpu.v[opX(op)] |= cpu.v[opY(op)]
+		if cpu.quirks.LogicResetsVF {
+			cpu.v[0xF] = 0
+		}
+		return nil
+	})
+}
+
+// OxAnd sets VX to VX&VY, resetting VF with the LogicResetsVF quirk
+type OxAnd struct {
+	Opcode
+}
+
+func (o OxAnd) Mask() uint16 {
+	return MaskXY
+}
+
+func (o OxAnd) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		cpu.v[opX(op)] &= cpu.v[opY(op)]
+		if cpu.quirks.LogicResetsVF {
+			cpu.v[0xF] = 0
+		}
+		return nil
+	})
+}
+
+// OxXor sets VX to VX^VY, resetting VF with the LogicResetsVF quirk
+type OxXor struct {
+	Opcode
+}
+
+func (o OxXor) Mask() uint16 {
+	return MaskXY
+}
+
+func (o OxXor) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		cpu.v[opX(op)] ^= cpu.v[opY(op)]
+		if cpu.quirks.LogicResetsVF {
+			cpu.v[0xF] = 0
+		}
+		return nil
+	})
+}
+
+// OxAddRegisters adds VY to VX, VF is set to the carry.
+// The flag is written last so it wins when X is F.
+type OxAddRegisters struct {
+	Opcode
+}
+
+func (o OxAddRegisters) Mask() uint16 {
+	return MaskXY
+}
+
+func (o OxAddRegisters) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		sum := uint16(cpu.v[opX(op)]) + uint16(cpu.v[opY(op)])
+		cpu.v[opX(op)] = byte(sum)
+		cpu.v[0xF] = byte(sum >> 8)
+		return nil
 	})
 }
 
+// OxSubtract sets VX to VX-VY, VF is set when there is no borrow
+type OxSubtract struct {
+	Opcode
+}
+
+func (o OxSubtract) Mask() uint16 {
+	return MaskXY
+}
+
+func (o OxSubtract) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		cpu.subtract(opX(op), cpu.v[opX(op)], cpu.v[opY(op)])
+		return nil
+	})
+}
+
+// OxSubtractReverse sets VX to VY-VX, VF is set when there is no borrow
+type OxSubtractReverse struct {
+	Opcode
+}
+
+func (o OxSubtractReverse) Mask() uint16 {
+	return MaskXY
+}
+
+func (o OxSubtractReverse) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		cpu.subtract(opX(op), cpu.v[opY(op)], cpu.v[opX(op)])
+		return nil
+	})
+}
+
+// OxShiftRight sets VX to VY shifted right by one, VF is set to the bit
+// shifted out. With the ShiftVX quirk VX is shifted in place instead.
+type OxShiftRight struct {
+	Opcode
+}
+
+func (o OxShiftRight) Mask() uint16 {
+	return MaskXY
+}
+
+func (o OxShiftRight) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		value := cpu.v[cpu.shiftSource(op)]
+		cpu.v[opX(op)] = value >> 1
+		cpu.v[0xF] = value & 0x1
+		return nil
+	})
+}
+
+// OxShiftLeft sets VX to VY shifted left by one, VF is set to the bit
+// shifted out. With the ShiftVX quirk VX is shifted in place instead.
+type OxShiftLeft struct {
+	Opcode
+}
+
+func (o OxShiftLeft) Mask() uint16 {
+	return MaskXY
+}
+
+func (o OxShiftLeft) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		value := cpu.v[cpu.shiftSource(op)]
+		cpu.v[opX(op)] = value << 1
+		cpu.v[0xF] = value >> 7
+		return nil
+	})
+}
+
+// OxLoadIndex sets I to NNN
 type OxLoadIndex struct {
 	Opcode
 }
 
+func (o OxLoadIndex) Mask() uint16 {
+	return MaskClass
+}
+
 func (o OxLoadIndex) Register(cpu *CPU) InstructionHandler {
 	return InstructionHandlerFunc(func(op uint16) error {
-		if op & 0xF000 == o.opcode {
-			data, err := cpu.ram.Reads(op&0x0FFF, 2)
-			if err != nil {
-				return err
-			}
-			cpu.index = binary.BigEndian.Uint16(data)
-			cpu.IncrementPC(2)
+		cpu.index = opNNN(op)
+		return nil
+	})
+}
+
+// OxJumpOffset jumps to NNN plus V0, or with the JumpVX quirk to XNN plus VX
+type OxJumpOffset struct {
+	Opcode
+}
+
+func (o OxJumpOffset) Mask() uint16 {
+	return MaskClass
+}
+
+func (o OxJumpOffset) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		offset := cpu.v[0x0]
+		if cpu.quirks.JumpVX {
+			offset = cpu.v[opX(op)]
+		}
+		cpu.SetPC(opNNN(op) + uint16(offset))
+		return nil
+	})
+}
+
+// OxRandom sets VX to a byte from the CPU's random source masked with NN
+type OxRandom struct {
+	Opcode
+}
+
+func (o OxRandom) Mask() uint16 {
+	return MaskClass
+}
+
+func (o OxRandom) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		cpu.v[opX(op)] = byte(cpu.random.Intn(0x100)) & opNN(op)
+		return nil
+	})
+}
+
+// OxSkipKeyPressed skips the next instruction if the key in VX is held down
+type OxSkipKeyPressed struct {
+	Opcode
+}
+
+func (o OxSkipKeyPressed) Mask() uint16 {
+	return MaskX
+}
+
+func (o OxSkipKeyPressed) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		if cpu.keypad.Pressed(cpu.v[opX(op)]) {
+			cpu.skipInstruction()
+		}
+		return nil
+	})
+}
+
+// OxSkipKeyNotPressed skips the next instruction if the key in VX is not held down
+type OxSkipKeyNotPressed struct {
+	Opcode
+}
+
+func (o OxSkipKeyNotPressed) Mask() uint16 {
+	return MaskX
+}
+
+func (o OxSkipKeyNotPressed) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		if !cpu.keypad.Pressed(cpu.v[opX(op)]) {
+			cpu.skipInstruction()
+		}
+		return nil
+	})
+}
+
+// OxGetDelay sets VX to the value of the delay timer
+type OxGetDelay struct {
+	Opcode
+}
+
+func (o OxGetDelay) Mask() uint16 {
+	return MaskX
+}
+
+func (o OxGetDelay) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		cpu.v[opX(op)] = cpu.timers.Delay()
+		return nil
+	})
+}
+
+// OxWaitKey stores the next key pressed and released in VX, repeating
+// itself until there is one as the COSMAC VIP did
+type OxWaitKey struct {
+	Opcode
+}
+
+func (o OxWaitKey) Mask() uint16 {
+	return MaskX
+}
+
+func (o OxWaitKey) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		if key, ok := cpu.keypad.wait(); ok {
+			cpu.v[opX(op)] = key
 			return nil
 		}
-		return InstructionNOP{op}
+		cpu.SetPC(cpu.pc - 2)
+		return nil
 	})
-}
\ No newline at end of file
+}
+
+// OxSetDelay sets the delay timer to VX
+type OxSetDelay struct {
+	Opcode
+}
+
+func (o OxSetDelay) Mask() uint16 {
+	return MaskX
+}
+
+func (o OxSetDelay) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		cpu.timers.SetDelay(cpu.v[opX(op)])
+		return nil
+	})
+}
+
+// OxSetSound sets the sound timer to VX
+type OxSetSound struct {
+	Opcode
+}
+
+func (o OxSetSound) Mask() uint16 {
+	return MaskX
+}
+
+func (o OxSetSound) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		cpu.timers.SetSound(cpu.v[opX(op)])
+		return nil
+	})
+}
+
+// OxAddIndex adds VX to I, VF is left untouched
+type OxAddIndex struct {
+	Opcode
+}
+
+func (o OxAddIndex) Mask() uint16 {
+	return MaskX
+}
+
+func (o OxAddIndex) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		cpu.index += uint16(cpu.v[opX(op)])
+		return nil
+	})
+}
+
+// OxLoadFont points I at the font sprite for the hex digit in VX
+type OxLoadFont struct {
+	Opcode
+}
+
+func (o OxLoadFont) Mask() uint16 {
+	return MaskX
+}
+
+func (o OxLoadFont) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		cpu.index = FontAddress + uint16(cpu.v[opX(op)]&0xF)*uint16(len(Font{}))
+		return nil
+	})
+}
+
+// OxStoreBCD writes the hundreds, tens and units of VX to I, I+1 and I+2
+type OxStoreBCD struct {
+	Opcode
+}
+
+func (o OxStoreBCD) Mask() uint16 {
+	return MaskX
+}
+
+func (o OxStoreBCD) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		value := cpu.v[opX(op)]
+		return cpu.ram.Writes(cpu.index, []byte{value / 100, value / 10 % 10, value % 10})
+	})
+}
+
+// OxStoreRegisters writes V0 to VX into memory starting at I, then moves
+// I as the quirks say
+type OxStoreRegisters struct {
+	Opcode
+}
+
+func (o OxStoreRegisters) Mask() uint16 {
+	return MaskX
+}
+
+func (o OxStoreRegisters) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		x := opX(op)
+		if err := cpu.ram.Writes(cpu.index, cpu.v[:x+1]); err != nil {
+			return err
+		}
+		cpu.index = cpu.quirks.advanceIndex(cpu.index, x)
+		return nil
+	})
+}
+
+// OxLoadRegisters reads V0 to VX from memory starting at I, then moves
+// I as the quirks say
+type OxLoadRegisters struct {
+	Opcode
+}
+
+func (o OxLoadRegisters) Mask() uint16 {
+	return MaskX
+}
+
+func (o OxLoadRegisters) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		x := opX(op)
+		data, err := cpu.ram.Reads(cpu.index, x+1)
+		if err != nil {
+			return err
+		}
+		copy(cpu.v[:], data)
+		cpu.index = cpu.quirks.advanceIndex(cpu.index, x)
+		return nil
+	})
+}
@@ -0,0 +1,172 @@
+package cpu
+
+import "fmt"
+
+// SuperChipOpcodes are the instructions SUPER-CHIP 1.1 adds to CHIP-8.
+// DXY0 is handled by OxDrawSprite.
+var SuperChipOpcodes = []Instruction{
+	OxScrollDown{Opcode{0x00C0, "Scroll Down"}},
+	OxScrollRight{Opcode{0x00FB, "Scroll Right"}},
+	OxScrollLeft{Opcode{0x00FC, "Scroll Left"}},
+	OxExit{Opcode{0x00FD, "Exit"}},
+	OxLowRes{Opcode{0x00FE, "Low Resolution"}},
+	OxHighRes{Opcode{0x00FF, "High Resolution"}},
+	OxLoadBigFont{Opcode{0xF030, "Load Big Font"}},
+	OxStoreFlags{Opcode{0xF075, "Store Flags"}},
+	OxLoadFlags{Opcode{0xF085, "Load Flags"}},
+}
+
+// Halted is returned once the program has exited with 00FD
+type Halted struct {
+	pc uint16
+}
+
+func (h Halted) Error() string {
+	return fmt.Sprintf("program exited at %X", h.pc)
+}
+
+// OxScrollDown scrolls the screen down by N pixels
+type OxScrollDown struct {
+	Opcode
+}
+
+func (o OxScrollDown) Mask() uint16 {
+	return MaskN
+}
+
+func (o OxScrollDown) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		return cpu.screen.scroll(0, int(opN(op)))
+	})
+}
+
+// OxScrollRight scrolls the screen right by 4 pixels
+type OxScrollRight struct {
+	Opcode
+}
+
+func (o OxScrollRight) Mask() uint16 {
+	return MaskOpcode
+}
+
+func (o OxScrollRight) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		return cpu.screen.scroll(4, 0)
+	})
+}
+
+// OxScrollLeft scrolls the screen left by 4 pixels
+type OxScrollLeft struct {
+	Opcode
+}
+
+func (o OxScrollLeft) Mask() uint16 {
+	return MaskOpcode
+}
+
+func (o OxScrollLeft) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		return cpu.screen.scroll(-4, 0)
+	})
+}
+
+// OxExit stops the program. The PC is left on the instruction so the
+// CPU stays halted however many more times it is stepped.
+type OxExit struct {
+	Opcode
+}
+
+func (o OxExit) Mask() uint16 {
+	return MaskOpcode
+}
+
+func (o OxExit) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		cpu.SetPC(cpu.pc - 2)
+		return Halted{cpu.pc}
+	})
+}
+
+// OxLowRes switches to the 64x32 resolution, clearing the screen
+type OxLowRes struct {
+	Opcode
+}
+
+func (o OxLowRes) Mask() uint16 {
+	return MaskOpcode
+}
+
+func (o OxLowRes) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		if err := cpu.screen.resize(LowResWidth, LowResHeight); err != nil {
+			return err
+		}
+		return cpu.screen.clear()
+	})
+}
+
+// OxHighRes switches to the 128x64 resolution, clearing the screen
+type OxHighRes struct {
+	Opcode
+}
+
+func (o OxHighRes) Mask() uint16 {
+	return MaskOpcode
+}
+
+func (o OxHighRes) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		if err := cpu.screen.resize(HighResWidth, HighResHeight); err != nil {
+			return err
+		}
+		return cpu.screen.clear()
+	})
+}
+
+// OxLoadBigFont points I at the large font sprite for the hex digit in VX
+type OxLoadBigFont struct {
+	Opcode
+}
+
+func (o OxLoadBigFont) Mask() uint16 {
+	return MaskX
+}
+
+func (o OxLoadBigFont) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		cpu.index = BigFontAddress + uint16(cpu.v[opX(op)]&0xF)*uint16(len(BigFont{}))
+		return nil
+	})
+}
+
+// OxStoreFlags saves V0 to VX into the RPL user flags
+type OxStoreFlags struct {
+	Opcode
+}
+
+func (o OxStoreFlags) Mask() uint16 {
+	return MaskX
+}
+
+func (o OxStoreFlags) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		copy(cpu.rpl[:opX(op)+1], cpu.v[:])
+		return nil
+	})
+}
+
+// OxLoadFlags restores V0 to VX from the RPL user flags
+type OxLoadFlags struct {
+	Opcode
+}
+
+func (o OxLoadFlags) Mask() uint16 {
+	return MaskX
+}
+
+func (o OxLoadFlags) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		copy(cpu.v[:opX(op)+1], cpu.rpl[:])
+		return nil
+	})
+}
@@ -0,0 +1,105 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuperChip_onlyInSuperChipMode(t *testing.T) {
+	for _, i := range SuperChipOpcodes {
+		cpu := NewCPU(NewRAM(0x1000))
+		assert.IsType(t, InstructionUnknown{}, cpu.ExecuteInstruction(i.Code()), i.Name())
+	}
+	cpu := NewCPU(NewRAM(0x1000), WithMode(ModeSuperChip))
+	assert.Equal(t, len(AllOpcodes)+len(SuperChipOpcodes), len(cpu.opcodes))
+}
+
+func TestSuperChip_resolution(t *testing.T) {
+	cpu := NewCPU(NewRAM(0x1000), WithMode(ModeSuperChip))
+	cpu.screen.flip(0, 0, 0)
+	assert.NoError(t, cpu.ExecuteInstruction(0x00FF))
+	assert.True(t, cpu.screen.HighRes())
+	assert.Empty(t, litPixels(cpu.screen), "switching resolution should clear the screen")
+
+	cpu.v[0x1], cpu.v[0x2] = 120, 60
+	cpu.index = FontAddress
+	assert.NoError(t, cpu.ExecuteInstruction(0xD125))
+	lit, _ := cpu.screen.pixel(120, 60)
+	assert.True(t, lit, "sprites should reach the high resolution edges")
+
+	assert.NoError(t, cpu.ExecuteInstruction(0x00FE))
+	assert.False(t, cpu.screen.HighRes())
+	assert.Empty(t, litPixels(cpu.screen))
+}
+
+func TestSuperChip_drawBigSprite(t *testing.T) {
+	cpu := NewCPU(NewRAM(0x1000), WithMode(ModeSuperChip))
+	sprite := make([]byte, 32)
+	sprite[0], sprite[1] = 0x80, 0x01   // top corners
+	sprite[30], sprite[31] = 0x80, 0x01 // bottom corners
+	cpu.ram.Writes(0x300, sprite)
+	cpu.index = 0x300
+	cpu.v[0x1], cpu.v[0x2] = 4, 2
+	assert.NoError(t, cpu.ExecuteInstruction(0xD120))
+	assert.Equal(t, [][2]uint16{{4, 2}, {19, 2}, {4, 17}, {19, 17}}, litPixels(cpu.screen))
+	assert.EqualValues(t, 0, cpu.v[0xF])
+
+	assert.NoError(t, cpu.ExecuteInstruction(0xD120))
+	assert.EqualValues(t, 1, cpu.v[0xF])
+	assert.Empty(t, litPixels(cpu.screen))
+
+	chip8 := NewCPU(NewRAM(0x1000))
+	chip8.ram.Writes(0x300, sprite)
+	chip8.index = 0x300
+	assert.NoError(t, chip8.ExecuteInstruction(0xD120))
+	assert.Empty(t, litPixels(chip8.screen), "DXY0 should draw nothing on CHIP-8")
+}
+
+func TestSuperChip_scroll(t *testing.T) {
+	tests := []struct {
+		opcode uint16
+		want   [2]uint16
+	}{
+		{0x00C3, [2]uint16{8, 8}},
+		{0x00FB, [2]uint16{12, 5}},
+		{0x00FC, [2]uint16{4, 5}},
+	}
+	for _, tt := range tests {
+		cpu := NewCPU(NewRAM(0x1000), WithMode(ModeSuperChip))
+		cpu.screen.flip(0, 8, 5)
+		assert.NoError(t, cpu.ExecuteInstruction(tt.opcode))
+		assert.Equal(t, [][2]uint16{tt.want}, litPixels(cpu.screen), "after %04X", tt.opcode)
+	}
+}
+
+func TestSuperChip_exit(t *testing.T) {
+	cpu := NewCPU(NewRAM(0x1000), WithMode(ModeSuperChip))
+	cpu.ram.Writes(0x200, []byte{0x00, 0xFD})
+	for i := 0; i < 2; i++ {
+		op, err := cpu.FetchInstruction()
+		assert.NoError(t, err)
+		assert.Equal(t, Halted{0x200}, cpu.ExecuteInstruction(op))
+		assert.EqualValues(t, 0x200, cpu.pc)
+	}
+}
+
+func TestSuperChip_loadBigFont(t *testing.T) {
+	cpu := NewCPU(NewRAM(0x1000), WithMode(ModeSuperChip))
+	cpu.v[0x3] = 0x7
+	assert.NoError(t, cpu.ExecuteInstruction(0xF330))
+	assert.EqualValues(t, BigFontAddress+7*10, cpu.index)
+	font, _ := cpu.ram.Reads(cpu.index, 10)
+	assert.Equal(t, BigFonts[7][:], font)
+}
+
+func TestSuperChip_flags(t *testing.T) {
+	cpu := NewCPU(NewRAM(0x1000), WithMode(ModeSuperChip))
+	copy(cpu.v[:], DataForTest)
+	assert.NoError(t, cpu.ExecuteInstruction(0xF275))
+	assert.Equal(t, []byte{0x01, 0x02, 0x03, 0x00}, cpu.rpl[:4])
+
+	cpu.v = [16]byte{}
+	assert.NoError(t, cpu.ExecuteInstruction(0xF185))
+	assert.Equal(t, []byte{0x01, 0x02, 0x00}, cpu.v[:3])
+}
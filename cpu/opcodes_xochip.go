@@ -0,0 +1,167 @@
+package cpu
+
+import "encoding/binary"
+
+// XOChipOpcodes are the instructions XO-CHIP adds to SUPER-CHIP
+var XOChipOpcodes = []Instruction{
+	OxScrollUp{Opcode{0x00D0, "Scroll Up"}},
+	OxSaveRange{Opcode{0x5002, "Save Register Range"}},
+	OxLoadRange{Opcode{0x5003, "Load Register Range"}},
+	OxLoadLongIndex{Opcode{0xF000, "Load Long Index"}},
+	OxSelectPlanes{Opcode{0xF001, "Select Planes"}},
+	OxLoadAudio{Opcode{0xF002, "Load Audio Pattern"}},
+	OxSetPitch{Opcode{0xF03A, "Set Pitch"}},
+}
+
+// DefaultPitch plays the audio pattern at 4000Hz
+const DefaultPitch = 64
+
+// OxScrollUp scrolls the selected planes up by N pixels
+type OxScrollUp struct {
+	Opcode
+}
+
+func (o OxScrollUp) Mask() uint16 {
+	return MaskN
+}
+
+func (o OxScrollUp) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		return cpu.screen.scroll(0, -int(opN(op)))
+	})
+}
+
+// registerRange returns the registers from VX to VY inclusive, in
+// descending order when X is greater than Y
+func registerRange(op uint16) []uint16 {
+	x, y := opX(op), opY(op)
+	registers := []uint16{}
+	for r := x; ; {
+		registers = append(registers, r)
+		if r == y {
+			return registers
+		}
+		if x < y {
+			r++
+		} else {
+			r--
+		}
+	}
+}
+
+// OxSaveRange writes VX to VY into memory starting at I, leaving I alone
+type OxSaveRange struct {
+	Opcode
+}
+
+func (o OxSaveRange) Mask() uint16 {
+	return MaskXY
+}
+
+func (o OxSaveRange) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		registers := registerRange(op)
+		values := make([]byte, len(registers))
+		for i, r := range registers {
+			values[i] = cpu.v[r]
+		}
+		return cpu.ram.Writes(cpu.index, values)
+	})
+}
+
+// OxLoadRange reads VX to VY from memory starting at I, leaving I alone
+type OxLoadRange struct {
+	Opcode
+}
+
+func (o OxLoadRange) Mask() uint16 {
+	return MaskXY
+}
+
+func (o OxLoadRange) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		registers := registerRange(op)
+		values, err := cpu.ram.Reads(cpu.index, uint16(len(registers)))
+		if err != nil {
+			return err
+		}
+		for i, r := range registers {
+			cpu.v[r] = values[i]
+		}
+		return nil
+	})
+}
+
+// OxLoadLongIndex sets I to the 16 bit address in the word following it
+type OxLoadLongIndex struct {
+	Opcode
+}
+
+func (o OxLoadLongIndex) Mask() uint16 {
+	return MaskOpcode
+}
+
+func (o OxLoadLongIndex) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		data, err := cpu.ram.Fetch(cpu.pc, 2)
+		if err != nil {
+			return err
+		}
+		cpu.index = binary.BigEndian.Uint16(data)
+		cpu.IncrementPC(2)
+		return nil
+	})
+}
+
+// OxSelectPlanes selects the bitplanes drawing instructions affect,
+// X being a bitmask of them
+type OxSelectPlanes struct {
+	Opcode
+}
+
+func (o OxSelectPlanes) Mask() uint16 {
+	return MaskX
+}
+
+func (o OxSelectPlanes) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		cpu.screen.planes = byte(opX(op)) & (1<<Planes - 1)
+		return nil
+	})
+}
+
+// OxLoadAudio loads the 16 byte audio pattern from I
+type OxLoadAudio struct {
+	Opcode
+}
+
+func (o OxLoadAudio) Mask() uint16 {
+	return MaskOpcode
+}
+
+func (o OxLoadAudio) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		data, err := cpu.ram.Reads(cpu.index, uint16(len(cpu.pattern)))
+		if err != nil {
+			return err
+		}
+		copy(cpu.pattern[:], data)
+		return nil
+	})
+}
+
+// OxSetPitch sets the audio pattern playback rate to VX
+type OxSetPitch struct {
+	Opcode
+}
+
+func (o OxSetPitch) Mask() uint16 {
+	return MaskX
+}
+
+func (o OxSetPitch) Register(cpu *CPU) InstructionHandler {
+	return InstructionHandlerFunc(func(op uint16) error {
+		cpu.pitch = cpu.v[opX(op)]
+		return nil
+	})
+}
@@ -0,0 +1,155 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newXOChip() *CPU {
+	return NewCPU(NewRAM(0x10000), WithMode(ModeXOChip), WithQuirks(QuirksXOChip))
+}
+
+func TestXOChip_onlyInXOChipMode(t *testing.T) {
+	for _, i := range XOChipOpcodes {
+		cpu := NewCPU(NewRAM(0x1000), WithMode(ModeSuperChip))
+		assert.IsType(t, InstructionUnknown{}, cpu.ExecuteInstruction(i.Code()), i.Name())
+	}
+}
+
+func TestXOChip_addressSpace(t *testing.T) {
+	cpu := newXOChip()
+	assert.NoError(t, cpu.ram.Writes(0xFFFE, []byte{0x00, 0xE0}))
+	cpu.SetPC(0xFFFE)
+	op, err := cpu.FetchInstruction()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0x00E0, op)
+
+	assert.NoError(t, cpu.ram.Writes(0x7FFF, []byte{0xAA, 0xBB}))
+	data, err := cpu.ram.Reads(0x7FFF, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xAA, 0xBB}, data)
+
+	chip8 := NewCPU(NewRAM(0x1000))
+	_, err = chip8.ram.Read(0x1000)
+	assert.Error(t, err)
+}
+
+func TestXOChip_loadLongIndex(t *testing.T) {
+	cpu := newXOChip()
+	cpu.ram.Writes(0x200, []byte{0xF0, 0x00, 0xAB, 0xCD})
+	op, _ := cpu.FetchInstruction()
+	assert.NoError(t, cpu.ExecuteInstruction(op))
+	assert.EqualValues(t, 0xABCD, cpu.index)
+	assert.EqualValues(t, 0x204, cpu.pc)
+}
+
+func TestXOChip_skipsLongInstructions(t *testing.T) {
+	tests := []struct {
+		name string
+		next []byte
+		want uint16
+	}{
+		{"over F000 NNNN", []byte{0xF0, 0x00, 0x12, 0x34}, 0x206},
+		{"over a normal instruction", []byte{0x60, 0x00, 0x12, 0x34}, 0x204},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu := newXOChip()
+			cpu.ram.Writes(0x200, append([]byte{0x30, 0x00}, tt.next...))
+			op, _ := cpu.FetchInstruction()
+			assert.NoError(t, cpu.ExecuteInstruction(op))
+			assert.EqualValues(t, tt.want, cpu.pc)
+		})
+	}
+
+	chip8 := NewCPU(NewRAM(0x1000))
+	chip8.ram.Writes(0x202, []byte{0xF0, 0x00})
+	chip8.SetPC(0x202)
+	chip8.ExecuteInstruction(0x3000)
+	assert.EqualValues(t, 0x204, chip8.pc, "CHIP-8 has no four byte instructions")
+}
+
+func TestXOChip_registerRanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		opcode uint16
+		want   []byte
+	}{
+		{"ascending", 0x5242, []byte{0x03, 0x04, 0x05}},
+		{"descending", 0x5422, []byte{0x05, 0x04, 0x03}},
+		{"single", 0x5332, []byte{0x04}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu := newXOChip()
+			copy(cpu.v[:], DataForTest)
+			cpu.index = 0x300
+			assert.NoError(t, cpu.ExecuteInstruction(tt.opcode))
+			got, _ := cpu.ram.Reads(0x300, uint16(len(tt.want)))
+			assert.Equal(t, tt.want, got)
+			assert.EqualValues(t, 0x300, cpu.index)
+
+			cpu.v = [16]byte{}
+			assert.NoError(t, cpu.ExecuteInstruction(tt.opcode+1))
+			for _, r := range registerRange(tt.opcode) {
+				assert.Equal(t, DataForTest[r], cpu.v[r], "loading V%X back", r)
+			}
+		})
+	}
+}
+
+func TestXOChip_planes(t *testing.T) {
+	cpu := newXOChip()
+	cpu.ram.Writes(0x300, []byte{0x80, 0xC0})
+	cpu.index = 0x300
+
+	assert.NoError(t, cpu.ExecuteInstruction(0xF301))
+	assert.NoError(t, cpu.ExecuteInstruction(0xD001))
+	color, _ := cpu.screen.color(0, 0)
+	assert.EqualValues(t, 0x3, color, "first sprite byte on plane 1, second on plane 2")
+	color, _ = cpu.screen.color(1, 0)
+	assert.EqualValues(t, 0x2, color)
+
+	assert.NoError(t, cpu.ExecuteInstruction(0xF201))
+	assert.NoError(t, cpu.ExecuteInstruction(0xD001))
+	assert.EqualValues(t, 1, cpu.v[0xF])
+	color, _ = cpu.screen.color(0, 0)
+	assert.EqualValues(t, 0x1, color)
+	color, _ = cpu.screen.color(1, 0)
+	assert.EqualValues(t, 0x2, color)
+
+	assert.NoError(t, cpu.ExecuteInstruction(0xF101))
+	assert.NoError(t, cpu.ExecuteInstruction(0x00E0))
+	assert.Equal(t, [][2]uint16{{1, 0}}, litPixels(cpu.screen), "clearing plane 1 should leave plane 2 alone")
+	assert.NoError(t, cpu.ExecuteInstruction(0xF301))
+	assert.NoError(t, cpu.ExecuteInstruction(0x00E0))
+	assert.Empty(t, litPixels(cpu.screen))
+
+	assert.NoError(t, cpu.ExecuteInstruction(0xF001))
+	assert.NoError(t, cpu.ExecuteInstruction(0xD001))
+	assert.EqualValues(t, 0, cpu.v[0xF])
+	assert.Empty(t, litPixels(cpu.screen), "no planes selected should draw nothing")
+}
+
+func TestXOChip_scrollUp(t *testing.T) {
+	cpu := newXOChip()
+	cpu.screen.flip(0, 3, 5)
+	cpu.screen.flip(1, 4, 5)
+	cpu.ExecuteInstruction(0xF201)
+	assert.NoError(t, cpu.ExecuteInstruction(0x00D2))
+	assert.Equal(t, [][2]uint16{{4, 3}, {3, 5}}, litPixels(cpu.screen))
+}
+
+func TestXOChip_audio(t *testing.T) {
+	cpu := newXOChip()
+	assert.EqualValues(t, DefaultPitch, cpu.pitch)
+	cpu.ram.Writes(0x300, DataForTest)
+	cpu.index = 0x300
+	assert.NoError(t, cpu.ExecuteInstruction(0xF002))
+	assert.Equal(t, DataForTest, cpu.pattern[:])
+
+	cpu.v[0x5] = 0x80
+	assert.NoError(t, cpu.ExecuteInstruction(0xF53A))
+	assert.EqualValues(t, 0x80, cpu.pitch)
+}
@@ -0,0 +1,80 @@
+package cpu
+
+import "math/rand"
+
+// DefaultSeed seeds the random source of a CPU built without WithSeed
+// or WithRandom, so that tests and headless runs are reproducible
+const DefaultSeed = 0xC8
+
+// Option configures a CPU as NewCPU builds it
+type Option func(*CPU)
+
+// WithQuirks picks the behaviour of the ambiguous instructions,
+// QuirksCOSMACVIP being the default
+func WithQuirks(quirks Quirks) Option {
+	return func(c *CPU) {
+		c.quirks = quirks
+	}
+}
+
+// WithMode picks the instruction set, ModeChip8 being the default
+func WithMode(mode Mode) Option {
+	return func(c *CPU) {
+		c.mode = mode
+	}
+}
+
+// WithInstructionsPerFrame sets how many instructions make a frame, and
+// so how fast the program runs, DefaultInstructionsPerFrame being
+// the default
+func WithInstructionsPerFrame(n int) Option {
+	return func(c *CPU) {
+		c.instructionsPerFrame = n
+	}
+}
+
+// WithRandom makes CXNN draw from source, which the CPU then owns.
+// Save states cannot capture an injected source, so loading one leaves
+// it as it is.
+func WithRandom(source rand.Source) Option {
+	return func(c *CPU) {
+		c.random = rand.New(source)
+		c.seeded = nil
+	}
+}
+
+// WithSeed seeds the source CXNN draws from, so the same seed and input
+// give the same run
+func WithSeed(seed int64) Option {
+	return func(c *CPU) {
+		c.seeded = newSeededSource(seed)
+		c.random = rand.New(c.seeded)
+	}
+}
+
+// WithRewind records a snapshot every interval frames for Rewind, keeping
+// the newest depth of them. DefaultRewindInterval and DefaultRewindDepth
+// give five minutes of history.
+func WithRewind(interval, depth int) Option {
+	return func(c *CPU) {
+		c.history = newHistory(interval, depth)
+	}
+}
+
+// WithKeypadAt maps the keypad into memory at addr, which must be
+// aligned to a region. The keypad takes over that whole region, so it
+// is best put above the memory a program uses. A misaligned addr maps
+// nothing, and the CPU's Step returns InvalidRegionAlignment.
+func WithKeypadAt(addr uint16) Option {
+	return func(c *CPU) {
+		c.mapKeypad = true
+		c.keypadAddress = addr
+	}
+}
+
+// WithSoundHook sets the hook fired as the sound timer starts and stops
+func WithSoundHook(hook SoundHook) Option {
+	return func(c *CPU) {
+		c.timers.OnSound(hook)
+	}
+}
@@ -0,0 +1,77 @@
+package cpu
+
+// IndexIncrement is how far FX55 and FX65 move I after touching V0 to VX
+type IndexIncrement int
+
+const (
+	// IndexPastX leaves I just past the last register, I += X+1
+	IndexPastX IndexIncrement = iota
+	// IndexToX leaves I on the last register, I += X
+	IndexToX
+	// IndexUnchanged leaves I where it was
+	IndexUnchanged
+)
+
+// Quirks picks between the behaviours that CHIP-8 implementations
+// disagree on. The zero value enables no quirks: it differs from
+// QuirksCOSMACVIP by leaving LogicResetsVF and ClipSprites off.
+type Quirks struct {
+	// ShiftVX makes 8XY6 and 8XYE shift VX in place, ignoring VY
+	ShiftVX bool
+	// Index is how FX55 and FX65 move I
+	Index IndexIncrement
+	// JumpVX makes BNNN behave as BXNN, jumping to XNN plus VX
+	JumpVX bool
+	// LogicResetsVF makes 8XY1, 8XY2 and 8XY3 reset VF
+	LogicResetsVF bool
+	// ClipSprites stops DXYN at the screen edges instead of wrapping
+	// pixels around to the other side
+	ClipSprites bool
+}
+
+var (
+	// QuirksCOSMACVIP is the original interpreter on the RCA COSMAC VIP
+	QuirksCOSMACVIP = Quirks{
+		Index:         IndexPastX,
+		LogicResetsVF: true,
+		ClipSprites:   true,
+	}
+	// QuirksCHIP48 is CHIP-48 on the HP-48 calculators
+	QuirksCHIP48 = Quirks{
+		ShiftVX:     true,
+		Index:       IndexToX,
+		JumpVX:      true,
+		ClipSprites: true,
+	}
+	// QuirksSuperChip is SUPER-CHIP 1.1 on the HP-48 calculators
+	QuirksSuperChip = Quirks{
+		ShiftVX:     true,
+		Index:       IndexUnchanged,
+		JumpVX:      true,
+		ClipSprites: true,
+	}
+	// QuirksXOChip is XO-CHIP as implemented by Octo
+	QuirksXOChip = Quirks{
+		Index: IndexPastX,
+	}
+)
+
+// QuirkPresets names each of the presets, for picking one from configuration
+var QuirkPresets = map[string]Quirks{
+	"vip":    QuirksCOSMACVIP,
+	"chip48": QuirksCHIP48,
+	"schip":  QuirksSuperChip,
+	"xochip": QuirksXOChip,
+}
+
+// advanceIndex moves I past registers V0 to VX according to the quirks
+func (q Quirks) advanceIndex(index uint16, x uint16) uint16 {
+	switch q.Index {
+	case IndexToX:
+		return index + x
+	case IndexUnchanged:
+		return index
+	default:
+		return index + x + 1
+	}
+}
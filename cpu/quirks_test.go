@@ -0,0 +1,116 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuirks_presets(t *testing.T) {
+	for name, quirks := range QuirkPresets {
+		t.Run(name, func(t *testing.T) {
+			cpu := NewCPU(NewRAM(0x1000), WithQuirks(quirks))
+			assert.Equal(t, quirks, cpu.quirks)
+		})
+	}
+	assert.Equal(t, QuirksCOSMACVIP, NewCPU(NewRAM(0x1000)).quirks)
+}
+
+func TestQuirks_shift(t *testing.T) {
+	tests := []struct {
+		name   string
+		quirks Quirks
+		opcode uint16
+		want   byte
+		wantVF byte
+	}{
+		{"VIP right shifts VY", QuirksCOSMACVIP, 0x8126, 0x20, 0x0},
+		{"VIP left shifts VY", QuirksCOSMACVIP, 0x812E, 0x80, 0x0},
+		{"SCHIP right shifts VX", QuirksSuperChip, 0x8126, 0x01, 0x1},
+		{"SCHIP left shifts VX", QuirksSuperChip, 0x812E, 0x06, 0x0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu := NewCPU(NewRAM(0x1000), WithQuirks(tt.quirks))
+			cpu.v[0x1], cpu.v[0x2] = 0x03, 0x40
+			assert.NoError(t, cpu.ExecuteInstruction(tt.opcode))
+			assert.EqualValues(t, tt.want, cpu.v[0x1])
+			assert.EqualValues(t, tt.wantVF, cpu.v[0xF])
+		})
+	}
+}
+
+func TestQuirks_logicResetsVF(t *testing.T) {
+	for _, opcode := range []uint16{0x8121, 0x8122, 0x8123} {
+		cpu := NewCPU(NewRAM(0x1000), WithQuirks(QuirksCOSMACVIP))
+		cpu.v[0xF] = 0xAA
+		assert.NoError(t, cpu.ExecuteInstruction(opcode))
+		assert.EqualValues(t, 0x00, cpu.v[0xF], "VIP should reset VF on %04X", opcode)
+
+		cpu = NewCPU(NewRAM(0x1000), WithQuirks(QuirksCHIP48))
+		cpu.v[0xF] = 0xAA
+		assert.NoError(t, cpu.ExecuteInstruction(opcode))
+		assert.EqualValues(t, 0xAA, cpu.v[0xF], "CHIP-48 should leave VF on %04X", opcode)
+	}
+}
+
+func TestQuirks_jump(t *testing.T) {
+	cpu := NewCPU(NewRAM(0x1000), WithQuirks(QuirksCOSMACVIP))
+	cpu.v[0x0], cpu.v[0x3] = 0x10, 0x20
+	assert.NoError(t, cpu.ExecuteInstruction(0xB300))
+	assert.EqualValues(t, 0x310, cpu.pc)
+
+	cpu = NewCPU(NewRAM(0x1000), WithQuirks(QuirksCHIP48))
+	cpu.v[0x0], cpu.v[0x3] = 0x10, 0x20
+	assert.NoError(t, cpu.ExecuteInstruction(0xB300))
+	assert.EqualValues(t, 0x320, cpu.pc)
+}
+
+func TestQuirks_index(t *testing.T) {
+	tests := []struct {
+		name   string
+		quirks Quirks
+		want   uint16
+	}{
+		{"VIP", QuirksCOSMACVIP, 0x304},
+		{"CHIP-48", QuirksCHIP48, 0x303},
+		{"SCHIP", QuirksSuperChip, 0x300},
+		{"XO-CHIP", QuirksXOChip, 0x304},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, opcode := range []uint16{0xF355, 0xF365} {
+				cpu := NewCPU(NewRAM(0x1000), WithQuirks(tt.quirks))
+				cpu.index = 0x300
+				assert.NoError(t, cpu.ExecuteInstruction(opcode))
+				assert.EqualValues(t, tt.want, cpu.index, "after %04X", opcode)
+			}
+		})
+	}
+}
+
+func TestQuirks_clipSprites(t *testing.T) {
+	tests := []struct {
+		name   string
+		quirks Quirks
+		wraps  bool
+	}{
+		{"VIP clips", QuirksCOSMACVIP, false},
+		{"XO-CHIP wraps", QuirksXOChip, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu := NewCPU(NewRAM(0x1000), WithQuirks(tt.quirks))
+			cpu.ram.Writes(0x300, []byte{0xFF, 0xFF})
+			cpu.index = 0x300
+			cpu.v[0x1], cpu.v[0x2] = 60, 31
+			assert.NoError(t, cpu.ExecuteInstruction(0xD122))
+			for _, p := range [][2]uint16{{0, 0}, {3, 0}, {0, 31}, {60, 0}} {
+				lit, _ := cpu.screen.pixel(p[0], p[1])
+				assert.Equal(t, tt.wraps, lit, "pixel %v", p)
+			}
+			lit, _ := cpu.screen.pixel(63, 31)
+			assert.True(t, lit)
+		})
+	}
+}
@@ -3,7 +3,7 @@ package cpu
 import "fmt"
 
 type AddressOutOfRange struct {
-	size uint16
+	size int
 	addr uint16
 }
 
@@ -15,10 +15,11 @@ type RAM struct {
 	uuid string
 	backbuffer [][]byte
 	data       []byte
-	size       uint16
+	size       int
 }
 
-func NewRAM(size uint16) *RAM {
+// NewRAM builds a RAM of size bytes, up to the 64KiB a uint16 can address
+func NewRAM(size int) *RAM {
 	r := &RAM{
 		uuid: fmt.Sprintf("RAM::%s", RandomStringUUID()),
 		size: size,
@@ -42,10 +43,10 @@ func (r *RAM) Reads(addr uint16, size uint16) ([]byte, error) {
 	if err := r.checkBounds(addr); err != nil {
 		return nil, err
 	}
-	if err := r.checkBounds(addr + size); err != nil {
+	if err := r.checkEnd(addr, int(size)); err != nil {
 		return nil, err
 	}
-	return r.data[addr : addr+size], nil
+	return r.data[addr : int(addr)+int(size)], nil
 }
 
 func (r *RAM) Write(addr uint16, value byte) error {
@@ -60,7 +61,7 @@ func (r *RAM) Writes(addr uint16, data []byte) error {
 	if err := r.checkBounds(addr); err != nil {
 		return err
 	}
-	if err := r.checkBounds(addr + uint16(len(data))); err != nil {
+	if err := r.checkEnd(addr, len(data)); err != nil {
 		return err
 	}
 	for i, b := range data {
@@ -70,12 +71,20 @@ func (r *RAM) Writes(addr uint16, data []byte) error {
 }
 
 func (r *RAM) checkBounds(addr uint16) error {
-	if addr >= r.size {
+	if int(addr) >= r.size {
 		return AddressOutOfRange{r.size, addr}
 	}
 	return nil
 }
 
+// checkEnd makes sure a run of length bytes starting at addr fits in the RAM
+func (r *RAM) checkEnd(addr uint16, length int) error {
+	if int(addr)+length > r.size {
+		return AddressOutOfRange{r.size, addr + uint16(length)}
+	}
+	return nil
+}
+
 func (r *RAM) flushData() {
 	r.backbuffer = append(r.backbuffer, r.data)
 	r.clearData(false)
@@ -7,11 +7,11 @@ import (
 )
 
 var DataForTest = []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F, 0x10}
-var Size = uint16(0x1000)
+const Size = 0x1000
 var Last = uint16(0xFFF)
 var Half = uint16(0x800)
 func TestRAM_size_is_separate_from_data_length(t *testing.T) {
-	size := uint16(len(DataForTest) + 512)
+	size := len(DataForTest) + 512
 	r := NewRAM(size)
 	r.data = DataForTest
 	assert.EqualValues(t, len(DataForTest), len(r.data)) // obvious, may change as we implement r.Write()
@@ -19,7 +19,7 @@ func TestRAM_size_is_separate_from_data_length(t *testing.T) {
 	assert.NotEqual(t, len(r.data), r.size)
 }
 func TestRAM_stores_history_in_a_buffer_when_cleared(t *testing.T) {
-	size := uint16(len(DataForTest) + 512)
+	size := len(DataForTest) + 512
 	r := NewRAM(size)
 	r.data = DataForTest
 	assert.EqualValues(t, 0, len(r.backbuffer))
@@ -28,7 +28,7 @@ func TestRAM_stores_history_in_a_buffer_when_cleared(t *testing.T) {
 	assert.EqualValues(t, DataForTest[0], r.backbuffer[len(r.backbuffer)-1][0x0])
 }
 func TestRAM_Clear(t *testing.T) {
-	size := uint16(len(DataForTest) + 512)
+	size := len(DataForTest) + 512
 	r := NewRAM(size)
 	r.data = DataForTest
 	r.Clear()
@@ -36,7 +36,61 @@ type Rammer struct {
 	uuid      string
 	alignment uint16
 	devices   map[string]Device
-	regions   map[uint16]Region
+	// order lists the device IDs as they were added, which save states
+	// rely on to match devices up
+	order   []string
+	regions map[uint16]Region
+	// hooks are told of every byte read or written
+	hooks    []accessHook
+	nextHook int
+	// unobserved counts the changes to memory the hooks are not told of,
+	// from pokes, loaded states and regions being mapped, so caches of
+	// memory can tell when to start over
+	unobserved uint64
+}
+
+// Access is a byte read or written through a Rammer, instruction fetches
+// being reads
+type Access struct {
+	Addr  uint16
+	Value byte
+	Write bool
+	// Fetch marks reads of instructions to execute, rather than of data
+	Fetch bool
+}
+
+// AccessHook is called with every byte read or written through a Rammer,
+// once the access has succeeded
+type AccessHook func(Access)
+
+type accessHook struct {
+	id   int
+	hook AccessHook
+}
+
+// Observe calls hook with every access from now on, until the returned
+// function is called
+func (r *Rammer) Observe(hook AccessHook) func() {
+	r.nextHook++
+	id := r.nextHook
+	r.hooks = append(r.hooks, accessHook{id, hook})
+	return func() {
+		for i, h := range r.hooks {
+			if h.id == id {
+				r.hooks = append(r.hooks[:i:i], r.hooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// observe tells the hooks of values accessed from addr on
+func (r *Rammer) observe(addr uint16, values []byte, write, fetch bool) {
+	for _, h := range r.hooks {
+		for i, value := range values {
+			h.hook(Access{addr + uint16(i), value, write, fetch})
+		}
+	}
 }
 
 func NewRammer(alignment uint16, devices []Device) *Rammer {
@@ -47,11 +101,27 @@ func NewRammer(alignment uint16, devices []Device) *Rammer {
 		regions:   make(map[uint16]Region),
 	}
 	for _, device := range devices {
-		r.devices[device.UUID()] = device
+		r.addDevice(device)
 	}
 	return r
 }
 
+func (r *Rammer) addDevice(device Device) {
+	if _, ok := r.devices[device.UUID()]; !ok {
+		r.order = append(r.order, device.UUID())
+	}
+	r.devices[device.UUID()] = device
+}
+
+// Devices returns the devices behind the Rammer in the order they were added
+func (r *Rammer) Devices() []Device {
+	devices := make([]Device, len(r.order))
+	for i, id := range r.order {
+		devices[i] = r.devices[id]
+	}
+	return devices
+}
+
 func (r *Rammer) UUID() string {
 	return r.uuid
 }
@@ -72,8 +142,10 @@ func (r *Rammer) SetRegion(start uint16, size uint16, device Device, deviceOffse
 	if start != 0 && start%r.alignment != 0 {
 		return InvalidRegionAlignment{r.alignment, start}
 	}
-	for i := uint16(start); i < start+size; i += r.alignment {
-		r.regions[r.getRegionID(i)] = Region{
+	r.unobserved++
+	r.addDevice(device)
+	for i := int(start); i < int(start)+int(size); i += int(r.alignment) {
+		r.regions[r.getRegionID(uint16(i))] = Region{
 			Start: start,
 			Devices: []RegionDevice{
 				{device.UUID(), size, deviceOffset},
@@ -83,6 +155,18 @@ func (r *Rammer) SetRegion(start uint16, size uint16, device Device, deviceOffse
 	return nil
 }
 
+// RemoveRegion unmaps the addresses from start to start+size
+func (r *Rammer) RemoveRegion(start uint16, size uint16) error {
+	if start != 0 && start%r.alignment != 0 {
+		return InvalidRegionAlignment{r.alignment, start}
+	}
+	r.unobserved++
+	for i := int(start); i < int(start)+int(size); i += int(r.alignment) {
+		delete(r.regions, r.getRegionID(uint16(i)))
+	}
+	return nil
+}
+
 type AddressInvalid struct {
 	addr uint16
 }
@@ -102,7 +186,11 @@ func (r *Rammer) Read(addr uint16) (byte, error) {
 	if _, err := r.checkBounds(addr); err != nil {
 		return 0, fmt.Errorf("cannot read: %w", err)
 	} else {
-		return r.readThrough(addr)
+		value, err := r.readThrough(addr)
+		if err == nil && len(r.hooks) > 0 {
+			r.observe(addr, []byte{value}, false, false)
+		}
+		return value, err
 	}
 }
 
@@ -115,15 +203,57 @@ func (r *Rammer) readThrough(addr uint16) (byte, error) {
 	return r.devices[lastDevice.ID].Read(lastDevice.Offset + (addr - region.Start))
 }
 
+// Reads reads size bytes from addr, carrying on through the following
+// regions when the first one ends too soon
 func (r *Rammer) Reads(addr uint16, size uint16) ([]byte, error) {
-	if region, err := r.checkBounds(addr); err != nil {
-		return nil, fmt.Errorf("fail: Reads(%w)", err)
-	} else {
-		deviceAddr := region.Devices[0].Offset + (addr - region.Start)
-		if deviceAddr+size > region.Devices[0].Size {
-			size = region.Devices[0].Size - deviceAddr
+	data, err := r.reads(addr, size)
+	if err == nil && len(r.hooks) > 0 {
+		r.observe(addr, data, false, false)
+	}
+	return data, err
+}
+
+// Fetch reads like Reads, telling the hooks the bytes are an instruction
+// being fetched to execute
+func (r *Rammer) Fetch(addr uint16, size uint16) ([]byte, error) {
+	data, err := r.reads(addr, size)
+	if err == nil && len(r.hooks) > 0 {
+		r.observe(addr, data, false, true)
+	}
+	return data, err
+}
+
+// Peek reads like Reads without telling the hooks, for tools looking at
+// memory rather than the program using it
+func (r *Rammer) Peek(addr uint16, size uint16) ([]byte, error) {
+	return r.reads(addr, size)
+}
+
+func (r *Rammer) reads(addr uint16, size uint16) ([]byte, error) {
+	var data []byte
+	for {
+		region, err := r.checkBounds(addr)
+		if err != nil {
+			return nil, fmt.Errorf("fail: Reads(%w)", err)
+		}
+		regionAddr := addr - region.Start
+		if regionAddr >= region.Devices[0].Size {
+			return nil, fmt.Errorf("fail: Reads(%w)", AddressInvalid{addr})
+		}
+		chunk := size
+		if int(regionAddr)+int(chunk) > int(region.Devices[0].Size) {
+			chunk = region.Devices[0].Size - regionAddr
+		}
+		deviceAddr := region.Devices[0].Offset + regionAddr
+		values, err := r.devices[region.Devices[0].ID].Reads(deviceAddr, chunk)
+		if err != nil || (data == nil && chunk == size) {
+			return values, err
+		}
+		data = append(data, values...)
+		addr, size = addr+chunk, size-chunk
+		if size == 0 {
+			return data, nil
 		}
-		return r.devices[region.Devices[0].ID].Reads(deviceAddr, size)
 	}
 }
 
@@ -131,7 +261,11 @@ func (r *Rammer) Write(addr uint16, value byte) error {
 	if region, err := r.checkBounds(addr); err != nil {
 		return fmt.Errorf("cannot write: %w", err)
 	} else {
-		return r.devices[region.Devices[0].ID].Write(region.Devices[0].Offset+(addr-region.Start), value)
+		err := r.devices[region.Devices[0].ID].Write(region.Devices[0].Offset+(addr-region.Start), value)
+		if err == nil && len(r.hooks) > 0 {
+			r.observe(addr, []byte{value}, true, false)
+		}
+		return err
 	}
 }
 
@@ -145,14 +279,42 @@ func (r *Rammer) Write(addr uint16, value byte) error {
 // 	return r.devices[lastDevice.ID].Write(lastDevice.Offset+(addr-region.Start), value)
 // }
 
+// Writes writes values from addr, carrying on through the following
+// regions when the first one ends too soon
 func (r *Rammer) Writes(addr uint16, values []byte) error {
-	if region, err := r.checkBounds(addr); err != nil {
-		return fmt.Errorf("cannot write: %w", err)
-	} else {
-		deviceAddr := region.Devices[0].Offset + (addr - region.Start)
-		if deviceAddr+uint16(len(values)) > region.Devices[0].Size {
-			values = values[:region.Devices[0].Size-deviceAddr]
+	err := r.writes(addr, values)
+	if err == nil && len(r.hooks) > 0 {
+		r.observe(addr, values, true, false)
+	}
+	return err
+}
+
+// Poke writes like Writes without telling the hooks, for tools changing
+// memory from outside the program
+func (r *Rammer) Poke(addr uint16, values []byte) error {
+	r.unobserved++
+	return r.writes(addr, values)
+}
+
+func (r *Rammer) writes(addr uint16, values []byte) error {
+	for len(values) > 0 {
+		region, err := r.checkBounds(addr)
+		if err != nil {
+			return fmt.Errorf("cannot write: %w", err)
+		}
+		regionAddr := addr - region.Start
+		if regionAddr >= region.Devices[0].Size {
+			return fmt.Errorf("cannot write: %w", AddressInvalid{addr})
 		}
-		return r.devices[region.Devices[0].ID].Writes(deviceAddr, values)
+		chunk := values
+		if int(regionAddr)+len(chunk) > int(region.Devices[0].Size) {
+			chunk = values[:region.Devices[0].Size-regionAddr]
+		}
+		deviceAddr := region.Devices[0].Offset + regionAddr
+		if err := r.devices[region.Devices[0].ID].Writes(deviceAddr, chunk); err != nil {
+			return err
+		}
+		addr, values = addr+uint16(len(chunk)), values[len(chunk):]
 	}
+	return nil
 }
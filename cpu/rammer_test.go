@@ -118,3 +118,77 @@ func TestRammer_Write(t *testing.T) {
 		})
 	}
 }
+
+func TestRammer_RemoveRegion(t *testing.T) {
+	ram := NewRAM(Size)
+	r := NewRammer(0x100, []Device{ram})
+	assert.NoError(t, r.SetRegion(0x0, 0x400, ram, 0x0))
+	assert.NoError(t, r.RemoveRegion(0x200, 0x200))
+	_, err := r.Read(0x1FF)
+	assert.NoError(t, err)
+	_, err = r.Read(0x200)
+	assert.ErrorIs(t, err, AddressInvalid{0x200})
+	_, err = r.Read(0x3FF)
+	assert.ErrorIs(t, err, AddressInvalid{0x3FF})
+	assert.IsType(t, InvalidRegionAlignment{}, r.RemoveRegion(0x10, 0x100))
+}
+
+func TestRammer_ReadsWritesAcrossRegions(t *testing.T) {
+	low, high := NewRAM(0x100), NewRAM(0x100)
+	r := NewRammer(0x100, []Device{low, high})
+	r.SetRegion(0x0, 0x100, low, 0x0)
+	r.SetRegion(0x100, 0x100, high, 0x0)
+
+	assert.NoError(t, r.Writes(0xFE, []byte{0x1, 0x2, 0x3, 0x4}))
+	got, err := r.Reads(0xFE, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x1, 0x2, 0x3, 0x4}, got)
+	data, _ := high.Reads(0x0, 2)
+	assert.Equal(t, []byte{0x3, 0x4}, data)
+
+	_, err = r.Reads(0x1FF, 2)
+	assert.ErrorIs(t, err, AddressInvalid{0x200})
+	assert.ErrorIs(t, r.Writes(0x1FF, []byte{0x1, 0x2}), AddressInvalid{0x200})
+}
+
+func TestRammer_SetRegion_topOfAddressSpace(t *testing.T) {
+	ram := NewRAM(0x10000)
+	r := NewRammer(0x100, []Device{ram})
+	assert.NoError(t, r.SetRegion(0x0, 0x8000, ram, 0x0))
+	assert.NoError(t, r.SetRegion(0x8000, 0x8000, ram, 0x8000))
+	assert.NoError(t, r.Write(0xFFFF, 0xAB))
+	got, err := ram.Read(0xFFFF)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0xAB, got)
+}
+
+func TestRammer_Observe(t *testing.T) {
+	ram := NewRAM(0x200)
+	r := NewRammer(0x100, []Device{ram})
+	r.SetRegion(0x0, 0x200, ram, 0x0)
+	accesses := []Access{}
+	stop := r.Observe(func(a Access) {
+		accesses = append(accesses, a)
+	})
+
+	assert.NoError(t, r.Write(0x10, 0xAA))
+	assert.NoError(t, r.Writes(0xFF, []byte{0x1, 0x2}))
+	r.Read(0x10)
+	r.Reads(0xFF, 2)
+	r.Fetch(0x10, 1)
+	r.Read(0x300)
+	r.Poke(0x20, []byte{0x1})
+	r.Peek(0x20, 1)
+	stop()
+	r.Read(0x10)
+
+	assert.Equal(t, []Access{
+		{0x10, 0xAA, true, false},
+		{0xFF, 0x1, true, false},
+		{0x100, 0x2, true, false},
+		{0x10, 0xAA, false, false},
+		{0xFF, 0x1, false, false},
+		{0x100, 0x2, false, false},
+		{0x10, 0xAA, false, true},
+	}, accesses, "failed accesses, peeks and pokes should not be observed")
+}
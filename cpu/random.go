@@ -0,0 +1,36 @@
+package cpu
+
+import "math/rand"
+
+// seededSource is a rand.Source that remembers its seed and how many
+// numbers it has given, so a save state can put it back where it was
+type seededSource struct {
+	source rand.Source
+	seed   int64
+	draws  uint64
+}
+
+func newSeededSource(seed int64) *seededSource {
+	return &seededSource{
+		source: rand.NewSource(seed),
+		seed:   seed,
+	}
+}
+
+func (s *seededSource) Int63() int64 {
+	s.draws++
+	return s.source.Int63()
+}
+
+func (s *seededSource) Seed(seed int64) {
+	s.source.Seed(seed)
+	s.seed = seed
+	s.draws = 0
+}
+
+// skip draws n numbers, to catch up with a source that has given them
+func (s *seededSource) skip(n uint64) {
+	for ; n > 0; n-- {
+		s.Int63()
+	}
+}
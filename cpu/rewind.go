@@ -0,0 +1,176 @@
+package cpu
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	// DefaultRewindInterval is how many frames pass between snapshots
+	DefaultRewindInterval = 4
+	// DefaultRewindDepth is how many snapshots are kept, five minutes at
+	// 60 frames a second with the default interval
+	DefaultRewindDepth = 5 * 60 * TimerRate / DefaultRewindInterval
+)
+
+// NothingToRewind is returned rewinding with no history recorded
+type NothingToRewind struct{}
+
+func (n NothingToRewind) Error() string {
+	return "nothing to rewind"
+}
+
+// history is a bounded ring of snapshots taken every interval frames.
+// Only the newest is kept whole, each older one being stored as the
+// delta that turns the snapshot after it back into it, so the oldest
+// can be dropped without touching the rest.
+type history struct {
+	interval int
+	latest   []byte
+	frame    uint64
+	// deltas and frames are parallel rings of count entries, the oldest
+	// at start
+	deltas [][]byte
+	frames []uint64
+	start  int
+	count  int
+}
+
+func newHistory(interval, depth int) *history {
+	return &history{
+		interval: interval,
+		deltas:   make([][]byte, depth),
+		frames:   make([]uint64, depth),
+	}
+}
+
+// record makes snapshot, taken at frame, the newest
+func (h *history) record(snapshot []byte, frame uint64) {
+	if h.latest != nil {
+		if h.count == len(h.deltas) {
+			h.start = (h.start + 1) % len(h.deltas)
+			h.count--
+		}
+		end := (h.start + h.count) % len(h.deltas)
+		h.deltas[end] = diff(snapshot, h.latest)
+		h.frames[end] = h.frame
+		h.count++
+	}
+	h.latest = snapshot
+	h.frame = frame
+}
+
+// rewind drops snapshots newer than frame, or all but the oldest if
+// there are none that old, returning the newest that remains
+func (h *history) rewind(frame uint64) ([]byte, error) {
+	if h.latest == nil {
+		return nil, NothingToRewind{}
+	}
+	for h.frame > frame && h.count > 0 {
+		end := (h.start + h.count - 1) % len(h.deltas)
+		previous, err := patch(h.latest, h.deltas[end])
+		if err != nil {
+			return nil, err
+		}
+		h.latest, h.frame = previous, h.frames[end]
+		h.deltas[end] = nil
+		h.count--
+	}
+	return h.latest, nil
+}
+
+// diff returns a delta that patch turns from into to with. It is the
+// length of to, then runs of bytes that are unchanged followed by runs
+// of bytes that change, each run length a uvarint.
+func diff(from, to []byte) []byte {
+	var delta bytes.Buffer
+	writeUvarint(&delta, uint64(len(to)))
+	for i := 0; i < len(to); {
+		same := i
+		for same < len(to) && same < len(from) && from[same] == to[same] {
+			same++
+		}
+		changed := same
+		for changed < len(to) && (changed >= len(from) || from[changed] != to[changed]) {
+			changed++
+		}
+		writeUvarint(&delta, uint64(same-i))
+		writeUvarint(&delta, uint64(changed-same))
+		delta.Write(to[same:changed])
+		i = changed
+	}
+	return delta.Bytes()
+}
+
+// patch applies a delta diff made to from
+func patch(from, delta []byte) ([]byte, error) {
+	r := bytes.NewReader(delta)
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("bad delta: %w", err)
+	}
+	to := make([]byte, length)
+	copy(to, from)
+	for i := uint64(0); i < length; {
+		same, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("bad delta: %w", err)
+		}
+		changed, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("bad delta: %w", err)
+		}
+		i += same
+		if i+changed > length {
+			return nil, fmt.Errorf("bad delta: run past %X", length)
+		}
+		if _, err := io.ReadFull(r, to[i:i+changed]); err != nil {
+			return nil, fmt.Errorf("bad delta: %w", err)
+		}
+		i += changed
+	}
+	return to, nil
+}
+
+func writeUvarint(b *bytes.Buffer, value uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	b.Write(buf[:binary.PutUvarint(buf[:], value)])
+}
+
+// snapshot records the machine if rewinding is on and a snapshot is due
+func (c *CPU) snapshot() error {
+	if c.history == nil || c.frame%uint64(c.history.interval) != 0 {
+		return nil
+	}
+	var state bytes.Buffer
+	if err := c.saveState(&state); err != nil {
+		return err
+	}
+	c.history.record(state.Bytes(), c.frame)
+	return nil
+}
+
+// Rewind puts the machine back at least frames frames, to the newest
+// snapshot that old or the oldest kept if history does not go back that
+// far, and publishes it. Snapshots after the one restored are forgotten.
+// Holding a rewind key calls it once a frame in place of RunFrame.
+func (c *CPU) Rewind(frames int) error {
+	if c.history == nil {
+		return NothingToRewind{}
+	}
+	target := uint64(0)
+	if uint64(frames) < c.frame {
+		target = c.frame - uint64(frames)
+	}
+	state, err := c.history.rewind(target)
+	if err != nil {
+		return err
+	}
+	if err := c.loadState(bytes.NewReader(state)); err != nil {
+		return err
+	}
+	c.publish()
+	return nil
+}
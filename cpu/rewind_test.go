@@ -0,0 +1,109 @@
+package cpu
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to []byte
+	}{
+		{"same", []byte{1, 2, 3}, []byte{1, 2, 3}},
+		{"changed", []byte{1, 2, 3, 4, 5}, []byte{1, 9, 3, 9, 9}},
+		{"grown", []byte{1, 2}, []byte{1, 2, 3, 4}},
+		{"shrunk", []byte{1, 2, 3, 4}, []byte{1, 7}},
+		{"from nothing", nil, []byte{1, 2}},
+		{"to nothing", []byte{1, 2}, []byte{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := patch(tt.from, diff(tt.from, tt.to))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.to, got)
+		})
+	}
+
+	from := make([]byte, 0x1000)
+	to := append([]byte(nil), from...)
+	to[0x800] = 1
+	assert.Less(t, len(diff(from, to)), 16, "a delta should only hold what changed")
+
+	_, err := patch(from, []byte{0x10, 0x00, 0x20})
+	assert.Error(t, err, "runs past the end should fail")
+}
+
+// counter runs a program counting frames in V1, so a machine's V1 says
+// which frame it is at
+func counter(t *testing.T, options ...Option) *CPU {
+	return newProgram(t, []byte{0x71, 0x01, 0x12, 0x00}, append([]Option{WithInstructionsPerFrame(2)}, options...)...)
+}
+
+func TestCPU_Rewind(t *testing.T) {
+	cpu := counter(t, WithRewind(2, 8))
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, cpu.RunFrame())
+	}
+	assert.EqualValues(t, 10, cpu.v[0x1])
+
+	published := []uint64{}
+	cpu.Subscribe(func(f Frame) {
+		published = append(published, f.Number)
+	})
+
+	assert.NoError(t, cpu.Rewind(3))
+	assert.EqualValues(t, 6, cpu.frame, "the newest snapshot at least 3 frames back is frame 6")
+	assert.EqualValues(t, 6, cpu.v[0x1])
+	assert.Equal(t, []uint64{6}, published)
+
+	assert.NoError(t, cpu.Rewind(1))
+	assert.EqualValues(t, 4, cpu.v[0x1])
+
+	assert.NoError(t, cpu.RunFrame())
+	assert.NoError(t, cpu.RunFrame())
+	assert.EqualValues(t, 6, cpu.v[0x1], "running on should record afresh")
+	assert.NoError(t, cpu.Rewind(1))
+	assert.EqualValues(t, 4, cpu.v[0x1])
+
+	assert.NoError(t, cpu.Rewind(100))
+	assert.EqualValues(t, 2, cpu.v[0x1], "rewinding too far should stop at the oldest")
+}
+
+func TestCPU_Rewind_bounded(t *testing.T) {
+	cpu := counter(t, WithRewind(1, 4))
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, cpu.RunFrame())
+	}
+	assert.Equal(t, 4, cpu.history.count)
+	assert.NoError(t, cpu.Rewind(100))
+	assert.EqualValues(t, 16, cpu.v[0x1], "only the newest snapshots should be kept")
+}
+
+func TestCPU_Rewind_restores(t *testing.T) {
+	cpu := newStateProgram(t)
+	WithRewind(1, 16)(cpu)
+	var states [][]byte
+	for i := 0; i < 6; i++ {
+		assert.NoError(t, cpu.RunFrame())
+		var state bytes.Buffer
+		assert.NoError(t, cpu.SaveState(&state))
+		states = append(states, state.Bytes())
+	}
+	for i := 4; i >= 0; i-- {
+		assert.NoError(t, cpu.Rewind(1))
+		var state bytes.Buffer
+		assert.NoError(t, cpu.SaveState(&state))
+		assert.Equal(t, states[i], state.Bytes(), "frame %d", i+1)
+	}
+}
+
+func TestCPU_Rewind_off(t *testing.T) {
+	cpu := counter(t)
+	assert.NoError(t, cpu.RunFrame())
+	assert.Equal(t, NothingToRewind{}, cpu.Rewind(1))
+	cpu = counter(t, WithRewind(4, 4))
+	assert.Equal(t, NothingToRewind{}, cpu.Rewind(1), "nothing is recorded before the first interval")
+}
@@ -1,5 +1,14 @@
 package cpu
 
+const (
+	// FontAddress is where Fonts are loaded
+	FontAddress = 0x0
+	// BigFontAddress is where BigFonts are loaded, just after Fonts
+	BigFontAddress = FontAddress + 16*5
+	// DefaultOrigin is where ROMs are loaded and start running
+	DefaultOrigin = 0x200
+)
+
 // 5-high sprite for fonts
 type Font [5]byte
 
@@ -21,4 +30,27 @@ var Fonts = [16]Font{
 	{0xE0, 0x90, 0x90, 0x90, 0xE0}, // D
 	{0xF0, 0x80, 0xF0, 0x80, 0xF0}, // E
 	{0xF0, 0x80, 0xF0, 0x80, 0x80}, // F
-}
\ No newline at end of file
+}
+
+// 10-high sprite for the SUPER-CHIP large fonts
+type BigFont [10]byte
+
+// BigFonts is a list of the SUPER-CHIP large fonts 0-F
+var BigFonts = [16]BigFont{
+	{0x3C, 0x7E, 0xE7, 0xC3, 0xC3, 0xC3, 0xC3, 0xE7, 0x7E, 0x3C}, // 0
+	{0x18, 0x38, 0x58, 0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x3C}, // 1
+	{0x3E, 0x7F, 0xC3, 0x06, 0x0C, 0x18, 0x30, 0x60, 0xFF, 0xFF}, // 2
+	{0x3C, 0x7E, 0xC3, 0x03, 0x0E, 0x0E, 0x03, 0xC3, 0x7E, 0x3C}, // 3
+	{0x06, 0x0E, 0x1E, 0x36, 0x66, 0xC6, 0xFF, 0xFF, 0x06, 0x06}, // 4
+	{0xFF, 0xFF, 0xC0, 0xC0, 0xFC, 0xFE, 0x03, 0xC3, 0x7E, 0x3C}, // 5
+	{0x3E, 0x7C, 0xE0, 0xC0, 0xFC, 0xFE, 0xC3, 0xC3, 0x7E, 0x3C}, // 6
+	{0xFF, 0xFF, 0x03, 0x06, 0x0C, 0x18, 0x30, 0x60, 0x60, 0x60}, // 7
+	{0x3C, 0x7E, 0xC3, 0xC3, 0x7E, 0x7E, 0xC3, 0xC3, 0x7E, 0x3C}, // 8
+	{0x3C, 0x7E, 0xC3, 0xC3, 0x7F, 0x3F, 0x03, 0x03, 0x3E, 0x7C}, // 9
+	{0x18, 0x3C, 0x66, 0xC3, 0xC3, 0xFF, 0xFF, 0xC3, 0xC3, 0xC3}, // A
+	{0xFC, 0xFE, 0xC3, 0xC3, 0xFE, 0xFE, 0xC3, 0xC3, 0xFE, 0xFC}, // B
+	{0x3C, 0x7E, 0xC3, 0xC0, 0xC0, 0xC0, 0xC0, 0xC3, 0x7E, 0x3C}, // C
+	{0xFC, 0xFE, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xFE, 0xFC}, // D
+	{0xFF, 0xFF, 0xC0, 0xC0, 0xFC, 0xFC, 0xC0, 0xC0, 0xFF, 0xFF}, // E
+	{0xFF, 0xFF, 0xC0, 0xC0, 0xFC, 0xFC, 0xC0, 0xC0, 0xC0, 0xC0}, // F
+}
@@ -0,0 +1,193 @@
+package cpu
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// DefaultInstructionsPerFrame is how many instructions RunFrame executes
+// unless WithInstructionsPerFrame says otherwise, about 600 a second
+const DefaultInstructionsPerFrame = 10
+
+// Frame is the screen as it stood at the end of a frame
+type Frame struct {
+	// Number counts the frames run, starting at one
+	Number uint64
+	Width  uint16
+	Height uint16
+	// Planes holds a copy of each bitplane, packed 8 pixels to a byte
+	// with the most significant bit leftmost
+	Planes [Planes][]byte
+	// Sound reports whether the sound timer was counting
+	Sound bool
+}
+
+// Color returns the pixel at x,y as a bit per plane
+func (f Frame) Color(x, y uint16) byte {
+	pixel := int(y)*int(f.Width) + int(x)
+	color := byte(0)
+	for plane, data := range f.Planes {
+		if data[pixel/8]&(0x80>>(pixel%8)) != 0 {
+			color |= 1 << plane
+		}
+	}
+	return color
+}
+
+// Pixel reports whether the pixel at x,y is lit on any plane
+func (f Frame) Pixel(x, y uint16) bool {
+	return f.Color(x, y) != 0
+}
+
+// String draws the frame as text for a terminal, two rows of pixels to
+// a line in half blocks
+func (f Frame) String() string {
+	var b strings.Builder
+	for y := uint16(0); y < f.Height; y += 2 {
+		for x := uint16(0); x < f.Width; x++ {
+			top, bottom := f.Pixel(x, y), y+1 < f.Height && f.Pixel(x, y+1)
+			switch {
+			case top && bottom:
+				b.WriteRune('█')
+			case top:
+				b.WriteRune('▀')
+			case bottom:
+				b.WriteRune('▄')
+			default:
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// FrameHandler is called with each finished frame
+type FrameHandler func(Frame)
+
+// Subscribe calls handler with every frame from now on, until the
+// returned function is called
+func (c *CPU) Subscribe(handler FrameHandler) func() {
+	c.nextSubscriber++
+	id := c.nextSubscriber
+	c.subscribers = append(c.subscribers, subscriber{id, handler})
+	return func() {
+		for i, s := range c.subscribers {
+			if s.id == id {
+				c.subscribers = append(c.subscribers[:i:i], c.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+type subscriber struct {
+	id      int
+	handler FrameHandler
+}
+
+// Step fetches and executes one instruction. Once a frame's worth have
+// been executed it ends the frame, ticking the timers once, recording a
+// snapshot if one is due and publishing the frame, so emulated time
+// runs the same however the CPU is driven.
+func (c *CPU) Step() error {
+	if c.fault != nil {
+		return c.fault
+	}
+	var err error
+	switch {
+	case len(c.tracers) > 0:
+		err = c.traceInstruction()
+	case c.natives != nil:
+		err = c.executeNative()
+	case c.jit != nil:
+		err = c.executeCached()
+	default:
+		err = c.execute()
+	}
+	if err != nil {
+		return err
+	}
+	if c.cycle++; c.cycle < c.instructionsPerFrame {
+		return nil
+	}
+	c.cycle = 0
+	c.TickTimers()
+	c.frame++
+	if err := c.snapshot(); err != nil {
+		return err
+	}
+	c.publish()
+	return nil
+}
+
+// execute fetches and executes one instruction
+func (c *CPU) execute() error {
+	opcode, err := c.FetchInstruction()
+	if err != nil {
+		return err
+	}
+	return c.ExecuteInstruction(opcode)
+}
+
+// RunFrame steps until the current frame ends. It stops short at the
+// first error, which it returns without ending the frame.
+func (c *CPU) RunFrame() error {
+	for frame := c.frame; c.frame == frame; {
+		if err := c.Step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run runs a frame every 1/TimerRate seconds until ctx is cancelled,
+// returning nil, or a frame fails, returning its error. A program
+// exiting with 00FD stops it with a Halted error.
+func (c *CPU) Run(ctx context.Context) error {
+	ticker := time.NewTicker(time.Second / TimerRate)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := c.RunFrame(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Frame returns the screen as it stands, numbered as the last frame run
+func (c *CPU) Frame() Frame {
+	f := Frame{
+		Number: c.frame,
+		Width:  c.screen.width,
+		Height: c.screen.height,
+		Sound:  c.timers.Playing(),
+	}
+	for plane := range f.Planes {
+		data, _ := c.screen.vram.Reads(c.screen.planeAddress(plane), c.screen.size)
+		f.Planes[plane] = append([]byte(nil), data...)
+	}
+	return f
+}
+
+func (c *CPU) publish() {
+	if len(c.subscribers) == 0 {
+		return
+	}
+	frame := c.Frame()
+	for _, s := range c.subscribers {
+		s.handler(frame)
+	}
+}
+
+// IsHalted reports whether err says the program has exited
+func IsHalted(err error) bool {
+	var halted Halted
+	return errors.As(err, &halted)
+}
@@ -0,0 +1,105 @@
+package cpu
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newProgram builds a CPU with program loaded at 0x200
+func newProgram(t *testing.T, program []byte, options ...Option) *CPU {
+	cpu := NewCPU(NewRAM(0x1000), options...)
+	assert.NoError(t, cpu.ram.Writes(0x200, program))
+	return cpu
+}
+
+func TestCPU_Step(t *testing.T) {
+	cpu := newProgram(t, []byte{0x61, 0x23, 0x71, 0x01})
+	assert.NoError(t, cpu.Step())
+	assert.EqualValues(t, 0x202, cpu.pc)
+	assert.NoError(t, cpu.Step())
+	assert.EqualValues(t, 0x204, cpu.pc)
+	assert.EqualValues(t, 0x24, cpu.v[0x1])
+}
+
+func TestCPU_RunFrame(t *testing.T) {
+	// 7101 1200: count up in V1 forever
+	cpu := newProgram(t, []byte{0x71, 0x01, 0x12, 0x00}, WithInstructionsPerFrame(8))
+	cpu.timers.SetDelay(5)
+	assert.NoError(t, cpu.RunFrame())
+	assert.EqualValues(t, 4, cpu.v[0x1], "a frame should execute 8 instructions")
+	assert.EqualValues(t, 4, cpu.timers.Delay(), "a frame should tick the timers once")
+	assert.NoError(t, cpu.RunFrame())
+	assert.EqualValues(t, 8, cpu.v[0x1])
+	assert.EqualValues(t, 3, cpu.timers.Delay())
+}
+
+func TestCPU_RunFrame_fault(t *testing.T) {
+	cpu := newProgram(t, []byte{0x71, 0x01, 0x00, 0x00})
+	cpu.timers.SetDelay(5)
+	assert.ErrorIs(t, cpu.RunFrame(), InstructionUnknown{0x0000})
+	assert.EqualValues(t, 5, cpu.timers.Delay(), "a failed frame should not tick the timers")
+}
+
+func TestCPU_Subscribe(t *testing.T) {
+	// 6005 F029 D005 1206: draw the 0 glyph then spin
+	cpu := newProgram(t, []byte{0x60, 0x05, 0xF0, 0x29, 0xD0, 0x05, 0x12, 0x06})
+	frames := []Frame{}
+	unsubscribe := cpu.Subscribe(func(f Frame) {
+		frames = append(frames, f)
+	})
+	assert.NoError(t, cpu.RunFrame())
+	assert.NoError(t, cpu.RunFrame())
+	unsubscribe()
+	assert.NoError(t, cpu.RunFrame())
+
+	assert.Len(t, frames, 2)
+	assert.EqualValues(t, 1, frames[0].Number)
+	assert.EqualValues(t, 2, frames[1].Number)
+	assert.EqualValues(t, LowResWidth, frames[0].Width)
+	assert.EqualValues(t, LowResHeight, frames[0].Height)
+	assert.True(t, frames[0].Pixel(5, 5), "the glyph's corner should be lit")
+	assert.False(t, frames[0].Pixel(6, 6), "the glyph's middle should be unlit")
+	assert.EqualValues(t, 0x1, frames[0].Color(5, 5))
+
+	cpu.screen.clear()
+	assert.True(t, frames[0].Pixel(5, 5), "frames should be copies of the screen")
+}
+
+func TestFrame_String(t *testing.T) {
+	f := Frame{Width: 8, Height: 3, Planes: [Planes][]byte{{0xC0, 0xA0, 0x90}, {0, 0, 0}}}
+	assert.Equal(t, "█▀▄     \n▀  ▀    \n", f.String())
+}
+
+func TestCPU_Run(t *testing.T) {
+	t.Run("cancelled", func(t *testing.T) {
+		cpu := newProgram(t, []byte{0x12, 0x00})
+		ctx, cancel := context.WithCancel(context.Background())
+		frames := 0
+		cpu.Subscribe(func(f Frame) {
+			if frames++; frames == 3 {
+				cancel()
+			}
+		})
+		assert.NoError(t, cpu.Run(ctx))
+		assert.Equal(t, 3, frames)
+	})
+	t.Run("halted", func(t *testing.T) {
+		cpu := newProgram(t, []byte{0x00, 0xFD}, WithMode(ModeSuperChip))
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		err := cpu.Run(ctx)
+		assert.True(t, IsHalted(err))
+		assert.EqualValues(t, 0x200, cpu.pc)
+	})
+	t.Run("fault", func(t *testing.T) {
+		cpu := newProgram(t, []byte{0x00, 0xFD})
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		err := cpu.Run(ctx)
+		assert.ErrorIs(t, err, InstructionUnknown{0x00FD}, "00FD is not CHIP-8")
+		assert.False(t, IsHalted(err))
+	})
+}
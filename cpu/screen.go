@@ -0,0 +1,169 @@
+package cpu
+
+// Screen is a framebuffer of up to Planes bitplanes, each packed 8 pixels
+// to a byte with the most significant bit leftmost, rows following each
+// other. It lives on its own bus rather than in program memory, so that
+// its regions can grow and shrink as the resolution changes.
+type Screen struct {
+	vram    *Rammer
+	buffer  *RAM
+	width   uint16
+	height  uint16
+	size    uint16
+	address uint16
+	// planes selects the bitplanes that drawing, clearing and scrolling
+	// affect, bit 0 being the first plane
+	planes byte
+}
+
+const (
+	// LowResWidth and LowResHeight are the CHIP-8 resolution
+	LowResWidth  = 64
+	LowResHeight = 32
+	// HighResWidth and HighResHeight are the SUPER-CHIP resolution
+	HighResWidth  = 128
+	HighResHeight = 64
+	// Planes is the number of XO-CHIP bitplanes
+	Planes = 2
+	// planeStride is the distance between planes on the bus, enough
+	// for one at the high resolution
+	planeStride = HighResWidth * HighResHeight / 8
+)
+
+// NewScreen builds a screen with framebuffers big enough for the high
+// resolution mode, starting in low resolution with the first plane selected
+func NewScreen() Screen {
+	buffer := NewRAM(Planes * planeStride)
+	s := Screen{
+		vram:   NewRammer(256, []Device{buffer}),
+		buffer: buffer,
+		planes: 0x1,
+	}
+	s.resize(LowResWidth, LowResHeight)
+	return s
+}
+
+// resize maps a framebuffer region per plane for the given resolution,
+// the caller is responsible for clearing them
+func (s *Screen) resize(width, height uint16) error {
+	for plane := 0; plane < Planes; plane++ {
+		if err := s.vram.RemoveRegion(s.planeAddress(plane), s.size); err != nil {
+			return err
+		}
+	}
+	s.width = width
+	s.height = height
+	s.size = width * height / 8
+	for plane := 0; plane < Planes; plane++ {
+		offset := uint16(plane * planeStride)
+		if err := s.vram.SetRegion(s.planeAddress(plane), s.size, s.buffer, offset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HighRes reports whether the screen is in the SUPER-CHIP resolution
+func (s Screen) HighRes() bool {
+	return s.width == HighResWidth
+}
+
+// selected returns the indexes of the selected planes in order
+func (s Screen) selected() []int {
+	planes := make([]int, 0, Planes)
+	for plane := 0; plane < Planes; plane++ {
+		if s.planes&(1<<plane) != 0 {
+			planes = append(planes, plane)
+		}
+	}
+	return planes
+}
+
+// planeAddress returns where a plane starts on the bus
+func (s Screen) planeAddress(plane int) uint16 {
+	return s.address + uint16(plane*planeStride)
+}
+
+// pixelAddress returns the address of the byte holding pixel x,y of the
+// first plane and the bit within it
+func (s Screen) pixelAddress(x, y uint16) (uint16, byte) {
+	pixel := y*s.width + x
+	return s.address + pixel/8, 0x80 >> (pixel % 8)
+}
+
+// color returns the pixel at x,y as a bit per plane
+func (s Screen) color(x, y uint16) (byte, error) {
+	addr, bit := s.pixelAddress(x, y)
+	color := byte(0)
+	for plane := 0; plane < Planes; plane++ {
+		data, err := s.vram.Read(addr + uint16(plane*planeStride))
+		if err != nil {
+			return 0, err
+		}
+		if data&bit != 0 {
+			color |= 1 << plane
+		}
+	}
+	return color, nil
+}
+
+// pixel reports whether the pixel at x,y is lit on any plane
+func (s Screen) pixel(x, y uint16) (bool, error) {
+	color, err := s.color(x, y)
+	return color != 0, err
+}
+
+// flip toggles the pixel at x,y on a plane and reports whether it was
+// lit beforehand
+func (s Screen) flip(plane int, x, y uint16) (bool, error) {
+	addr, bit := s.pixelAddress(x, y)
+	addr += uint16(plane * planeStride)
+	data, err := s.vram.Read(addr)
+	if err != nil {
+		return false, err
+	}
+	return data&bit != 0, s.vram.Write(addr, data^bit)
+}
+
+// clear turns every pixel of the selected planes off
+func (s Screen) clear() error {
+	for _, plane := range s.selected() {
+		if err := s.vram.Writes(s.planeAddress(plane), make([]byte, s.size)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scroll moves the picture on the selected planes by dx,dy pixels,
+// filling the gap left behind with unlit pixels
+func (s Screen) scroll(dx, dy int) error {
+	for _, plane := range s.selected() {
+		if err := s.scrollPlane(plane, dx, dy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s Screen) scrollPlane(plane int, dx, dy int) error {
+	data, err := s.vram.Reads(s.planeAddress(plane), s.size)
+	if err != nil {
+		return err
+	}
+	width, height := int(s.width), int(s.height)
+	scrolled := make([]byte, len(data))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			from := (y-dy)*width + (x - dx)
+			if x-dx < 0 || x-dx >= width || y-dy < 0 || y-dy >= height {
+				continue
+			}
+			if data[from/8]&(0x80>>(from%8)) != 0 {
+				to := y*width + x
+				scrolled[to/8] |= 0x80 >> (to % 8)
+			}
+		}
+	}
+	return s.vram.Writes(s.planeAddress(plane), scrolled)
+}
@@ -0,0 +1,69 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func litPixels(s Screen) [][2]uint16 {
+	lit := [][2]uint16{}
+	for y := uint16(0); y < s.height; y++ {
+		for x := uint16(0); x < s.width; x++ {
+			if on, _ := s.pixel(x, y); on {
+				lit = append(lit, [2]uint16{x, y})
+			}
+		}
+	}
+	return lit
+}
+
+func TestScreen_resize(t *testing.T) {
+	s := NewScreen()
+	assert.False(t, s.HighRes())
+	assert.EqualValues(t, 256, s.size)
+	_, err := s.vram.Read(s.size)
+	assert.Error(t, err, "low resolution should only map 256 bytes")
+
+	assert.NoError(t, s.resize(HighResWidth, HighResHeight))
+	assert.True(t, s.HighRes())
+	assert.EqualValues(t, 1024, s.size)
+	_, err = s.vram.Read(s.size - 1)
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.resize(LowResWidth, LowResHeight))
+	_, err = s.vram.Read(s.size)
+	assert.Error(t, err, "shrinking should unmap the rest of the framebuffer")
+}
+
+func TestScreen_scroll(t *testing.T) {
+	tests := []struct {
+		name   string
+		dx, dy int
+		want   [][2]uint16
+	}{
+		{"down", 0, 3, [][2]uint16{{10, 8}, {63, 34}}},
+		{"right", 4, 0, [][2]uint16{{14, 5}, {67, 31}}},
+		{"off the right", 128, 0, [][2]uint16{}},
+		{"left", -4, 0, [][2]uint16{{6, 5}, {59, 31}}},
+		{"off the bottom", 0, 64, [][2]uint16{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScreen()
+			s.resize(HighResWidth, HighResHeight)
+			s.flip(0, 10, 5)
+			s.flip(0, 63, 31)
+			assert.NoError(t, s.scroll(tt.dx, tt.dy))
+			assert.Equal(t, tt.want, litPixels(s))
+		})
+	}
+}
+
+func TestScreen_clear(t *testing.T) {
+	s := NewScreen()
+	s.flip(0, 1, 1)
+	s.flip(0, 63, 31)
+	assert.NoError(t, s.clear())
+	assert.Empty(t, litPixels(s))
+}
@@ -21,6 +21,11 @@ func (s *Stack) MaxSize() int {
 	return int(s.size)
 }
 
+// Entries returns a copy of the values on the stack, oldest first
+func (s *Stack) Entries() []uint16 {
+	return append([]uint16(nil), s.entries...)
+}
+
 // Push pushes a value onto the stack if possible
 func (s *Stack) Push(value uint16) bool {
 	if len(s.entries) >= int(s.size) {
@@ -0,0 +1,391 @@
+package cpu
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sort"
+)
+
+// StateMagic opens every save state
+const StateMagic = "CH8S"
+
+// StateVersion is the save state format SaveState writes. LoadState reads
+// this version only, failing clearly on any other. It goes up whenever
+// the payload's layout changes; version 2 added the cycle counter.
+const StateVersion uint16 = 2
+
+// A save state is StateMagic, the version and the payload's length, the
+// payload, then the payload's CRC-32, all big endian.
+
+// maxStateLength bounds the payload length LoadState believes, well
+// above the state of a machine with 64KiB of memory, so that a damaged
+// header cannot ask for gigabytes
+const maxStateLength = 1 << 20
+
+// Stateful is a Device whose contents can be saved into and loaded from
+// a save state. Every device behind a CPU's Rammer must be one.
+type Stateful interface {
+	SaveState(w io.Writer) error
+	LoadState(r io.Reader) error
+}
+
+// StateInvalid is returned loading something that is not a save state,
+// is damaged, or was saved from a differently built machine
+type StateInvalid struct {
+	reason string
+}
+
+func (s StateInvalid) Error() string {
+	return fmt.Sprintf("invalid save state: %s", s.reason)
+}
+
+// StateVersionUnsupported is returned loading a save state from another
+// version of the format
+type StateVersionUnsupported struct {
+	version uint16
+}
+
+func (s StateVersionUnsupported) Error() string {
+	return fmt.Sprintf("save state version %d is unsupported, want %d", s.version, StateVersion)
+}
+
+// DeviceStateless is returned saving a machine with a device that is not Stateful
+type DeviceStateless struct {
+	id string
+}
+
+func (d DeviceStateless) Error() string {
+	return fmt.Sprintf("device %s cannot be saved", d.id)
+}
+
+// stateWriter writes big endian values, keeping the first error
+type stateWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (s *stateWriter) write(values ...interface{}) {
+	for _, value := range values {
+		if s.err == nil {
+			s.err = binary.Write(s.w, binary.BigEndian, value)
+		}
+	}
+}
+
+// stateReader reads big endian values, keeping the first error
+type stateReader struct {
+	r   io.Reader
+	err error
+}
+
+func (s *stateReader) read(values ...interface{}) {
+	for _, value := range values {
+		if s.err == nil {
+			s.err = binary.Read(s.r, binary.BigEndian, value)
+		}
+	}
+}
+
+// SaveState writes the whole machine to w: registers, stack, timers,
+// keypad, screen, the random source and every device behind the Rammer
+// along with its region map
+func (c *CPU) SaveState(w io.Writer) error {
+	var payload bytes.Buffer
+	if err := c.saveState(&payload); err != nil {
+		return err
+	}
+	s := &stateWriter{w: w}
+	s.write([]byte(StateMagic), StateVersion, uint32(payload.Len()), payload.Bytes())
+	s.write(crc32.ChecksumIEEE(payload.Bytes()))
+	return s.err
+}
+
+// LoadState replaces the machine with one SaveState wrote. The CPU must
+// have been built with the same mode and memory layout. Nothing is
+// changed if the state is damaged or from another version.
+func (c *CPU) LoadState(r io.Reader) error {
+	s := &stateReader{r: r}
+	magic := make([]byte, len(StateMagic))
+	var version uint16
+	var length uint32
+	s.read(magic)
+	if s.err != nil || string(magic) != StateMagic {
+		return StateInvalid{"bad magic"}
+	}
+	s.read(&version)
+	if s.err == nil && version != StateVersion {
+		return StateVersionUnsupported{version}
+	}
+	s.read(&length)
+	if s.err != nil {
+		return StateInvalid{"truncated header"}
+	}
+	if length > maxStateLength {
+		return StateInvalid{"payload too large"}
+	}
+	payload := make([]byte, length)
+	var checksum uint32
+	s.read(payload, &checksum)
+	if s.err != nil {
+		return StateInvalid{"truncated"}
+	}
+	if crc32.ChecksumIEEE(payload) != checksum {
+		return StateInvalid{"checksum mismatch"}
+	}
+	return c.loadState(bytes.NewReader(payload))
+}
+
+func (c *CPU) saveState(w io.Writer) error {
+	s := &stateWriter{w: w}
+	s.write(byte(c.mode), c.quirks.ShiftVX, byte(c.quirks.Index), c.quirks.JumpVX, c.quirks.LogicResetsVF, c.quirks.ClipSprites)
+	s.write(c.pc, c.sp, c.index, c.v, c.rpl, c.pattern, c.pitch)
+	s.write(uint16(c.stack.MaxSize()), uint16(c.stack.Size()), c.stack.entries)
+	s.write(c.timers.delay, c.timers.sound, c.frame, uint16(c.cycle))
+	s.write(c.seeded != nil)
+	if c.seeded != nil {
+		s.write(c.seeded.seed, c.seeded.draws)
+	}
+	s.write(c.screen.width, c.screen.height, c.screen.planes)
+	if s.err != nil {
+		return s.err
+	}
+	for _, device := range []Device{c.screen.buffer, c.keypad, c.ram} {
+		if err := saveDevice(w, device); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cpuState is the CPU's own part of a save state, decoded but not yet
+// loaded
+type cpuState struct {
+	quirks          Quirks
+	pc, sp, i       uint16
+	v, rpl, pattern [16]byte
+	pitch           byte
+	entries         []uint16
+	delay, sound    byte
+	frame           uint64
+	cycle           uint16
+	seeded          bool
+	seed            int64
+	draws           uint64
+	width, height   uint16
+	planes          byte
+}
+
+// decodeState reads the CPU's part of a save state, checking it fits c
+func (c *CPU) decodeState(r io.Reader) (cpuState, error) {
+	s := &stateReader{r: r}
+	var st cpuState
+	var mode, index byte
+	s.read(&mode)
+	if s.err == nil && Mode(mode) != c.mode {
+		return st, StateInvalid{fmt.Sprintf("saved in %s mode, not %s", Mode(mode), c.mode)}
+	}
+	s.read(&st.quirks.ShiftVX, &index, &st.quirks.JumpVX, &st.quirks.LogicResetsVF, &st.quirks.ClipSprites)
+	st.quirks.Index = IndexIncrement(index)
+	s.read(&st.pc, &st.sp, &st.i, &st.v, &st.rpl, &st.pattern, &st.pitch)
+
+	var stackSize, stackLength uint16
+	s.read(&stackSize, &stackLength)
+	if s.err == nil && (stackSize != uint16(c.stack.MaxSize()) || stackLength > stackSize) {
+		return st, StateInvalid{"stack size mismatch"}
+	}
+	st.entries = make([]uint16, stackLength)
+	s.read(st.entries)
+	s.read(&st.delay, &st.sound, &st.frame, &st.cycle)
+	s.read(&st.seeded)
+	if st.seeded {
+		s.read(&st.seed, &st.draws)
+	}
+	s.read(&st.width, &st.height, &st.planes)
+	if s.err != nil {
+		return st, StateInvalid{s.err.Error()}
+	}
+	if (st.width != LowResWidth || st.height != LowResHeight) && (st.width != HighResWidth || st.height != HighResHeight) {
+		return st, StateInvalid{fmt.Sprintf("bad resolution %dx%d", st.width, st.height)}
+	}
+	return st, nil
+}
+
+// loadState decodes the CPU's part of the state, then loads the devices,
+// and only once they have all loaded changes the CPU. Devices load
+// themselves, so they are saved first and put back if any fails.
+func (c *CPU) loadState(r io.Reader) error {
+	st, err := c.decodeState(r)
+	if err != nil {
+		return err
+	}
+	devices := []Device{c.screen.buffer, c.keypad, c.ram}
+	var backup bytes.Buffer
+	for _, device := range devices {
+		if err := saveDevice(&backup, device); err != nil {
+			return err
+		}
+	}
+	for _, device := range devices {
+		if err := loadDevice(r, device); err != nil {
+			restore := bytes.NewReader(backup.Bytes())
+			for _, device := range devices {
+				loadDevice(restore, device)
+			}
+			return err
+		}
+	}
+
+	c.quirks = st.quirks
+	c.pc, c.sp, c.index = st.pc, st.sp, st.i
+	c.v, c.rpl, c.pattern, c.pitch = st.v, st.rpl, st.pattern, st.pitch
+	c.stack.entries = st.entries
+	c.timers.SetDelay(st.delay)
+	c.timers.SetSound(st.sound)
+	c.frame, c.cycle = st.frame, int(st.cycle)
+	if st.seeded {
+		WithSeed(st.seed)(c)
+		c.seeded.skip(st.draws)
+	}
+	if err := c.screen.resize(st.width, st.height); err != nil {
+		return err
+	}
+	c.screen.planes = st.planes
+	return nil
+}
+
+func saveDevice(w io.Writer, device Device) error {
+	stateful, ok := device.(Stateful)
+	if !ok {
+		return DeviceStateless{device.UUID()}
+	}
+	return stateful.SaveState(w)
+}
+
+func loadDevice(r io.Reader, device Device) error {
+	stateful, ok := device.(Stateful)
+	if !ok {
+		return DeviceStateless{device.UUID()}
+	}
+	return stateful.LoadState(r)
+}
+
+// SaveState writes the RAM's size and contents
+func (r *RAM) SaveState(w io.Writer) error {
+	s := &stateWriter{w: w}
+	s.write(uint32(r.size), r.data)
+	return s.err
+}
+
+// LoadState reads contents SaveState wrote, which must be the same size
+func (r *RAM) LoadState(rd io.Reader) error {
+	s := &stateReader{r: rd}
+	var size uint32
+	s.read(&size)
+	if s.err == nil && int(size) != r.size {
+		return StateInvalid{fmt.Sprintf("RAM size %X, want %X", size, r.size)}
+	}
+	data := make([]byte, r.size)
+	s.read(data)
+	if s.err != nil {
+		return s.err
+	}
+	r.data = data
+	return nil
+}
+
+// SaveState writes which keys are held and any wait for a key
+func (k *Keypad) SaveState(w io.Writer) error {
+	s := &stateWriter{w: w}
+	s.write(k.keys, k.waiting, int8(k.released))
+	return s.err
+}
+
+// LoadState reads the keys and wait SaveState wrote
+func (k *Keypad) LoadState(r io.Reader) error {
+	s := &stateReader{r: r}
+	var keys [Keys]bool
+	var waiting bool
+	var released int8
+	s.read(&keys, &waiting, &released)
+	if s.err != nil {
+		return s.err
+	}
+	k.keys, k.waiting, k.released = keys, waiting, int(released)
+	return nil
+}
+
+// SaveState writes each device in the order they were added, then the
+// region map with devices referred to by that order
+func (r *Rammer) SaveState(w io.Writer) error {
+	s := &stateWriter{w: w}
+	s.write(r.alignment, uint16(len(r.order)))
+	if s.err != nil {
+		return s.err
+	}
+	index := make(map[string]uint16, len(r.order))
+	for i, id := range r.order {
+		index[id] = uint16(i)
+		if err := saveDevice(w, r.devices[id]); err != nil {
+			return err
+		}
+	}
+	ids := make([]int, 0, len(r.regions))
+	for id := range r.regions {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+	s.write(uint32(len(ids)))
+	for _, id := range ids {
+		region := r.regions[uint16(id)]
+		s.write(uint16(id), region.Start, uint16(len(region.Devices)))
+		for _, device := range region.Devices {
+			s.write(index[device.ID], device.Size, device.Offset)
+		}
+	}
+	return s.err
+}
+
+// LoadState reads the devices and region map SaveState wrote, into a
+// Rammer holding as many devices, added in the same order
+func (r *Rammer) LoadState(rd io.Reader) error {
+	r.unobserved++
+	s := &stateReader{r: rd}
+	var alignment, count uint16
+	s.read(&alignment, &count)
+	if s.err != nil {
+		return s.err
+	}
+	if alignment != r.alignment || int(count) != len(r.order) {
+		return StateInvalid{fmt.Sprintf("%d devices aligned to %X, want %d aligned to %X", count, alignment, len(r.order), r.alignment)}
+	}
+	for _, id := range r.order {
+		if err := loadDevice(rd, r.devices[id]); err != nil {
+			return err
+		}
+	}
+	var length uint32
+	s.read(&length)
+	regions := make(map[uint16]Region)
+	for ; length > 0 && s.err == nil; length-- {
+		var id, start, devices uint16
+		s.read(&id, &start, &devices)
+		region := Region{Start: start}
+		for ; devices > 0 && s.err == nil; devices-- {
+			var index, size, offset uint16
+			s.read(&index, &size, &offset)
+			if int(index) >= len(r.order) {
+				return StateInvalid{fmt.Sprintf("region %X refers to missing device %d", id, index)}
+			}
+			region.Devices = append(region.Devices, RegionDevice{r.order[index], size, offset})
+		}
+		regions[id] = region
+	}
+	if s.err != nil {
+		return s.err
+	}
+	r.regions = regions
+	return nil
+}
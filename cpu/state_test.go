@@ -0,0 +1,141 @@
+package cpu
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newStateProgram builds a SUPER-CHIP machine which keeps drawing random
+// glyphs from inside a subroutine
+func newStateProgram(t *testing.T) *CPU {
+	cpu := newProgram(t, []byte{
+		0x00, 0xFF, // hi-res
+		0x22, 0x06, // call 206
+		0x12, 0x04, // spin
+		0xC0, 0xFF, // V0 = random
+		0xF0, 0x29, // I = glyph V0
+		0xD0, 0x05, // draw it at V0,V0
+		0x12, 0x06, // again
+	}, WithMode(ModeSuperChip), WithQuirks(QuirksSuperChip), WithKeypadAt(0xF000))
+	return cpu
+}
+
+func TestCPU_SaveState(t *testing.T) {
+	cpu := newStateProgram(t)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, cpu.RunFrame())
+	}
+	cpu.timers.SetDelay(30)
+	cpu.Keypad().Press(0x6)
+
+	var saved bytes.Buffer
+	assert.NoError(t, cpu.SaveState(&saved))
+	state := saved.Bytes()
+
+	var frames []Frame
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, cpu.RunFrame())
+		frames = append(frames, cpu.Frame())
+	}
+
+	t.Run("restores the same machine", func(t *testing.T) {
+		loaded := newStateProgram(t)
+		assert.NoError(t, loaded.LoadState(bytes.NewReader(state)))
+		var resaved bytes.Buffer
+		assert.NoError(t, loaded.SaveState(&resaved))
+		assert.Equal(t, state, resaved.Bytes())
+
+		assert.EqualValues(t, 1, loaded.stack.Size())
+		assert.EqualValues(t, 30, loaded.timers.Delay())
+		assert.True(t, loaded.Keypad().Pressed(0x6))
+		assert.True(t, loaded.screen.HighRes())
+	})
+	t.Run("runs on identically", func(t *testing.T) {
+		assert.NoError(t, cpu.LoadState(bytes.NewReader(state)))
+		for i := 0; i < 5; i++ {
+			assert.NoError(t, cpu.RunFrame())
+			assert.Equal(t, frames[i], cpu.Frame())
+		}
+	})
+}
+
+func TestCPU_LoadState_invalid(t *testing.T) {
+	cpu := newStateProgram(t)
+	var saved bytes.Buffer
+	assert.NoError(t, cpu.SaveState(&saved))
+	state := saved.Bytes()
+
+	corrupt := func(at int, value byte) []byte {
+		damaged := append([]byte(nil), state...)
+		damaged[at] = value
+		return damaged
+	}
+	versioned := func(v uint16) []byte {
+		damaged := append([]byte(nil), state...)
+		binary.BigEndian.PutUint16(damaged[len(StateMagic):], v)
+		return damaged
+	}
+	version := versioned(StateVersion + 1)
+	oversized := append([]byte(nil), state...)
+	binary.BigEndian.PutUint32(oversized[len(StateMagic)+2:], 0xFFFFFFFF)
+
+	tests := []struct {
+		name  string
+		state []byte
+		err   error
+	}{
+		{"empty", nil, StateInvalid{"bad magic"}},
+		{"magic", corrupt(0, 'X'), StateInvalid{"bad magic"}},
+		{"version", version, StateVersionUnsupported{StateVersion + 1}},
+		{"version 1", versioned(1), StateVersionUnsupported{1}},
+		{"truncated", state[:len(state)-1], StateInvalid{"truncated"}},
+		{"length", oversized, StateInvalid{"payload too large"}},
+		{"checksum", corrupt(len(state)-10, 0xFF), StateInvalid{"checksum mismatch"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu := newStateProgram(t)
+			cpu.SetPC(0x300)
+			assert.Equal(t, tt.err, cpu.LoadState(bytes.NewReader(tt.state)))
+			assert.EqualValues(t, 0x300, cpu.pc, "a failed load should change nothing")
+		})
+	}
+
+	t.Run("truncated device", func(t *testing.T) {
+		// a damaged RAM payload, framed with a valid length and checksum
+		header := len(StateMagic) + 2 + 4
+		payload := state[header : len(state)-4-1]
+		var damaged bytes.Buffer
+		s := &stateWriter{w: &damaged}
+		s.write([]byte(StateMagic), StateVersion, uint32(len(payload)), payload, crc32.ChecksumIEEE(payload))
+		assert.NoError(t, s.err)
+
+		cpu := newStateProgram(t)
+		cpu.SetPC(0x300)
+		cpu.Keypad().Press(0x2)
+		before, _ := cpu.Memory().Peek(0x200, 0x10)
+		assert.Error(t, cpu.LoadState(bytes.NewReader(damaged.Bytes())))
+		assert.EqualValues(t, 0x300, cpu.pc, "a failed load should change nothing")
+		assert.True(t, cpu.Keypad().Pressed(0x2), "devices loaded before the failure should be put back")
+		after, _ := cpu.Memory().Peek(0x200, 0x10)
+		assert.Equal(t, before, after)
+	})
+	t.Run("mode", func(t *testing.T) {
+		cpu := NewCPU(NewRAM(0x1000))
+		assert.Equal(t, StateInvalid{"saved in SUPER-CHIP mode, not CHIP-8"}, cpu.LoadState(bytes.NewReader(state)))
+	})
+	t.Run("layout", func(t *testing.T) {
+		cpu := NewCPU(NewRAM(0x1000), WithMode(ModeSuperChip))
+		assert.Error(t, cpu.LoadState(bytes.NewReader(state)), "the keypad is not mapped")
+	})
+}
+
+func TestCPU_SaveState_stateless(t *testing.T) {
+	device := struct{ Device }{NewRAM(0x1000)}
+	cpu := NewCPU(device)
+	assert.Equal(t, DeviceStateless{device.UUID()}, cpu.SaveState(&bytes.Buffer{}))
+}
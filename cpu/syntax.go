@@ -0,0 +1,151 @@
+package cpu
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Syntax gives the assembly syntax of each instruction by its Code,
+// after Cowgod's reference. In operands, Vx and Vy are the X and Y
+// registers, byte is NN, nibble is N, addr is NNN, n is X as a plain
+// number and long is the word following the opcode. Everything else is
+// written as it is.
+var Syntax = map[uint16]string{
+	0x00E0: "CLS",
+	0x00EC: "YIELD",
+	0x00EE: "RET",
+	0x1000: "JP addr",
+	0x2000: "CALL addr",
+	0x3000: "SE Vx, byte",
+	0x4000: "SNE Vx, byte",
+	0x5000: "SE Vx, Vy",
+	0x6000: "LD Vx, byte",
+	0x7000: "ADD Vx, byte",
+	0x8000: "LD Vx, Vy",
+	0x8001: "OR Vx, Vy",
+	0x8002: "AND Vx, Vy",
+	0x8003: "XOR Vx, Vy",
+	0x8004: "ADD Vx, Vy",
+	0x8005: "SUB Vx, Vy",
+	0x8006: "SHR Vx, Vy",
+	0x8007: "SUBN Vx, Vy",
+	0x800E: "SHL Vx, Vy",
+	0x9000: "SNE Vx, Vy",
+	0xA000: "LD I, addr",
+	0xB000: "JP V0, addr",
+	0xC000: "RND Vx, byte",
+	0xD000: "DRW Vx, Vy, nibble",
+	0xE09E: "SKP Vx",
+	0xE0A1: "SKNP Vx",
+	0xF007: "LD Vx, DT",
+	0xF00A: "LD Vx, K",
+	0xF015: "LD DT, Vx",
+	0xF018: "LD ST, Vx",
+	0xF01E: "ADD I, Vx",
+	0xF029: "LD F, Vx",
+	0xF033: "LD B, Vx",
+	0xF055: "LD [I], Vx",
+	0xF065: "LD Vx, [I]",
+	// SUPER-CHIP
+	0x00C0: "SCD nibble",
+	0x00FB: "SCR",
+	0x00FC: "SCL",
+	0x00FD: "EXIT",
+	0x00FE: "LOW",
+	0x00FF: "HIGH",
+	0xF030: "LD HF, Vx",
+	0xF075: "LD R, Vx",
+	0xF085: "LD Vx, R",
+	// XO-CHIP
+	0x00D0: "SCU nibble",
+	0x5002: "SAVE Vx, Vy",
+	0x5003: "LOAD Vx, Vy",
+	0xF000: "LD I, LONG long",
+	0xF001: "PLANE n",
+	0xF002: "AUDIO",
+	0xF03A: "PITCH Vx",
+}
+
+// SplitSyntax splits an instruction's syntax into its mnemonic and operands
+func SplitSyntax(syntax string) (string, []string) {
+	parts := strings.SplitN(syntax, " ", 2)
+	if len(parts) == 1 {
+		return parts[0], nil
+	}
+	return parts[0], strings.Split(parts[1], ", ")
+}
+
+// Length returns how many bytes the instruction takes, F000 being
+// followed by its long operand
+func Length(i Instruction) uint16 {
+	if strings.HasSuffix(Syntax[i.Code()], "long") {
+		return 4
+	}
+	return 2
+}
+
+// ROMWord returns the opcode at addr in rom loaded at origin, if rom
+// holds the whole of one there
+func ROMWord(rom []byte, origin, addr uint16) (uint16, bool) {
+	at := int(addr) - int(origin)
+	if at < 0 || at+2 > len(rom) {
+		return 0, false
+	}
+	return binary.BigEndian.Uint16(rom[at:]), true
+}
+
+// DecodeROM returns the instruction at addr in rom loaded at origin and
+// how long it is, if d knows it and rom holds the whole of it
+func DecodeROM(d *Decoder, rom []byte, origin, addr uint16) (Instruction, uint16, bool) {
+	opcode, ok := ROMWord(rom, origin, addr)
+	if !ok {
+		return nil, 0, false
+	}
+	i, ok := d.Decode(opcode)
+	if !ok {
+		return nil, 0, false
+	}
+	length := Length(i)
+	if int(addr)+int(length) > int(origin)+len(rom) {
+		return nil, 0, false
+	}
+	return i, length, true
+}
+
+// Format writes an instruction as assembly from its bytes, naming
+// addresses with address, or in hex if address is nil
+func Format(i Instruction, bytes []byte, address func(uint16) string) string {
+	if address == nil {
+		address = func(addr uint16) string {
+			return fmt.Sprintf("0x%03X", addr)
+		}
+	}
+	opcode := binary.BigEndian.Uint16(bytes)
+	mnemonic, operands := SplitSyntax(Syntax[i.Code()])
+	for n, operand := range operands {
+		words := strings.Split(operand, " ")
+		last := &words[len(words)-1]
+		switch *last {
+		case "Vx":
+			*last = fmt.Sprintf("V%X", opcode>>8&0xF)
+		case "Vy":
+			*last = fmt.Sprintf("V%X", opcode>>4&0xF)
+		case "byte":
+			*last = fmt.Sprintf("0x%02X", opcode&0xFF)
+		case "nibble":
+			*last = fmt.Sprintf("%d", opcode&0xF)
+		case "n":
+			*last = fmt.Sprintf("%d", opcode>>8&0xF)
+		case "addr":
+			*last = address(opcode & 0x0FFF)
+		case "long":
+			*last = address(binary.BigEndian.Uint16(bytes[2:]))
+		}
+		operands[n] = strings.Join(words, " ")
+	}
+	if len(operands) == 0 {
+		return mnemonic
+	}
+	return mnemonic + " " + strings.Join(operands, ", ")
+}
@@ -0,0 +1,69 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyntax(t *testing.T) {
+	placeholders := map[string]uint16{"Vx": 0x0F00, "n": 0x0F00, "Vy": 0x00F0, "nibble": 0x000F, "byte": 0x00FF, "addr": 0x0FFF}
+	instructions := ModeXOChip.Instructions()
+	assert.Len(t, Syntax, len(instructions), "every instruction should have a syntax, and nothing else")
+	for _, i := range instructions {
+		syntax, ok := Syntax[i.Code()]
+		if !assert.True(t, ok, "%q has no syntax", i.Name()) {
+			continue
+		}
+		_, operands := SplitSyntax(syntax)
+		bits := uint16(0)
+		for _, operand := range operands {
+			bits |= placeholders[operand]
+		}
+		assert.Equal(t, ^i.Mask(), bits, "%q operands should cover exactly the operand bits", syntax)
+	}
+}
+
+func TestSplitSyntax(t *testing.T) {
+	mnemonic, operands := SplitSyntax("DRW Vx, Vy, nibble")
+	assert.Equal(t, "DRW", mnemonic)
+	assert.Equal(t, []string{"Vx", "Vy", "nibble"}, operands)
+	mnemonic, operands = SplitSyntax("CLS")
+	assert.Equal(t, "CLS", mnemonic)
+	assert.Empty(t, operands)
+}
+
+func TestLength(t *testing.T) {
+	assert.EqualValues(t, 4, Length(OxLoadLongIndex{Opcode{0xF000, "Load Long Index"}}))
+	assert.EqualValues(t, 2, Length(OxLoadIndex{Opcode{0xA000, "Load Index"}}))
+}
+
+func TestDecodeROM(t *testing.T) {
+	// LD V0, 1 then the start of an F000 NNNN cut short
+	rom := []byte{0x60, 0x01, 0xF0, 0x00, 0x12}
+	word, ok := ROMWord(rom, 0x200, 0x202)
+	assert.True(t, ok)
+	assert.EqualValues(t, 0xF000, word)
+	_, ok = ROMWord(rom, 0x200, 0x204)
+	assert.False(t, ok, "only half a word is left")
+	_, ok = ROMWord(rom, 0x200, 0x1FE)
+	assert.False(t, ok, "before the ROM")
+
+	d := ModeXOChip.Decoder()
+	i, length, ok := DecodeROM(d, rom, 0x200, 0x200)
+	assert.True(t, ok)
+	assert.EqualValues(t, 0x6000, i.Code())
+	assert.EqualValues(t, 2, length)
+	_, _, ok = DecodeROM(d, rom, 0x200, 0x202)
+	assert.False(t, ok, "the long operand runs past the end")
+	_, _, ok = DecodeROM(ModeChip8.Decoder(), rom, 0x200, 0x202)
+	assert.False(t, ok, "CHIP-8 has no F000")
+}
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, "DRW V1, V2, 5", Format(OxDrawSprite{Opcode{0xD000, "Draw Sprite"}}, []byte{0xD1, 0x25}, nil))
+	assert.Equal(t, "LD I, 0x300", Format(OxLoadIndex{Opcode{0xA000, "Load Index"}}, []byte{0xA3, 0x00}, nil))
+	assert.Equal(t, "LD I, LONG sprite", Format(OxLoadLongIndex{Opcode{0xF000, "Load Long Index"}}, []byte{0xF0, 0x00, 0x12, 0x34}, func(addr uint16) string {
+		return "sprite"
+	}))
+}
@@ -0,0 +1,72 @@
+package cpu
+
+// TimerRate is how many times a second of emulated time the timers
+// count down
+const TimerRate = 60
+
+// SoundHook is called with true when the sound timer starts counting
+// and false when it reaches zero
+type SoundHook func(playing bool)
+
+// Timers are the 8-bit delay and sound timers. They count down once per
+// Tick, which the caller makes once per 1/TimerRate seconds of emulated
+// time rather than of wall-clock time, so runs are repeatable.
+type Timers struct {
+	delay   byte
+	sound   byte
+	onSound SoundHook
+}
+
+func NewTimers() *Timers {
+	return &Timers{}
+}
+
+// Delay returns the current delay timer
+func (t *Timers) Delay() byte {
+	return t.delay
+}
+
+// Sound returns the current sound timer
+func (t *Timers) Sound() byte {
+	return t.sound
+}
+
+// Playing reports whether the sound timer is counting, and so a tone should sound
+func (t *Timers) Playing() bool {
+	return t.sound != 0
+}
+
+// SetDelay sets the delay timer
+func (t *Timers) SetDelay(value byte) {
+	t.delay = value
+}
+
+// SetSound sets the sound timer, firing the hook if that starts or stops the sound
+func (t *Timers) SetSound(value byte) {
+	was := t.Playing()
+	t.sound = value
+	t.notify(was)
+}
+
+// OnSound sets the hook fired as the sound starts and stops
+func (t *Timers) OnSound(hook SoundHook) {
+	t.onSound = hook
+}
+
+// Tick counts both timers down by one, stopping at zero
+func (t *Timers) Tick() {
+	if t.delay > 0 {
+		t.delay--
+	}
+	if t.sound > 0 {
+		t.sound--
+		t.notify(true)
+	}
+}
+
+// notify fires the hook if the sound has started or stopped since was
+func (t *Timers) notify(was bool) {
+	if now := t.Playing(); now != was && t.onSound != nil {
+		t.onSound(now)
+	}
+}
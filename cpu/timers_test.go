@@ -0,0 +1,56 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimers_Tick(t *testing.T) {
+	timers := NewTimers()
+	timers.SetDelay(2)
+	timers.SetSound(1)
+	timers.Tick()
+	assert.EqualValues(t, 1, timers.Delay())
+	assert.EqualValues(t, 0, timers.Sound())
+	timers.Tick()
+	timers.Tick()
+	assert.EqualValues(t, 0, timers.Delay(), "timers should stop at zero")
+	assert.EqualValues(t, 0, timers.Sound())
+}
+
+func TestTimers_OnSound(t *testing.T) {
+	timers := NewTimers()
+	events := []bool{}
+	timers.OnSound(func(playing bool) {
+		events = append(events, playing)
+	})
+
+	timers.SetSound(2)
+	timers.SetSound(3)
+	assert.Equal(t, []bool{true}, events, "only starting should fire")
+	assert.True(t, timers.Playing())
+
+	for i := 0; i < 5; i++ {
+		timers.Tick()
+	}
+	assert.Equal(t, []bool{true, false}, events)
+	assert.False(t, timers.Playing())
+
+	timers.SetSound(5)
+	timers.SetSound(0)
+	assert.Equal(t, []bool{true, false, true, false}, events, "zeroing should stop the sound")
+}
+
+func TestTimers_deterministic(t *testing.T) {
+	// two seconds of emulated time take the same number of ticks however
+	// quickly they are made
+	cpu := NewCPU(NewRAM(0x1000))
+	cpu.v[0x1] = 0xFF
+	cpu.ExecuteInstruction(0xF115)
+	for i := 0; i < 2*TimerRate; i++ {
+		cpu.TickTimers()
+	}
+	cpu.ExecuteInstruction(0xF207)
+	assert.EqualValues(t, 0xFF-2*TimerRate, cpu.v[0x2])
+}
@@ -0,0 +1,90 @@
+package cpu
+
+// Registers is the register file at one moment
+type Registers struct {
+	PC uint16
+	I  uint16
+	// SP is how many calls deep the stack is
+	SP int
+	V  [16]byte
+	DT byte
+	ST byte
+}
+
+// Registers returns the registers as they stand
+func (c *CPU) Registers() Registers {
+	return Registers{
+		PC: c.pc,
+		I:  c.index,
+		SP: c.StackDepth(),
+		V:  c.v,
+		DT: c.timers.Delay(),
+		ST: c.timers.Sound(),
+	}
+}
+
+// Trace records an instruction Step executed
+type Trace struct {
+	// Bytes holds the opcode, followed by its long operand for F000
+	Bytes []byte
+	// Instruction is what the opcode decoded to, nil if nothing claims it
+	Instruction Instruction
+	Before      Registers
+	After       Registers
+	// Writes are the bytes the instruction wrote to memory, in order
+	Writes []Access
+	// Err is what executing the instruction returned
+	Err error
+}
+
+// Tracer is called with every instruction Step executes
+type Tracer func(Trace)
+
+type tracer struct {
+	id     int
+	tracer Tracer
+}
+
+// Trace calls tracer with every instruction executed from now on, until
+// the returned function is called. Stepping is slower while anything
+// traces.
+func (c *CPU) Trace(t Tracer) func() {
+	c.nextTracer++
+	id := c.nextTracer
+	c.tracers = append(c.tracers, tracer{id, t})
+	return func() {
+		for i, t := range c.tracers {
+			if t.id == id {
+				c.tracers = append(c.tracers[:i:i], c.tracers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// traceInstruction executes an instruction as execute does, recording it
+// for the tracers
+func (c *CPU) traceInstruction() error {
+	t := Trace{Before: c.Registers()}
+	if opcode, err := c.ram.Peek(c.pc, 2); err == nil {
+		t.Bytes = append([]byte(nil), opcode...)
+		if i, ok := c.decoder.Decode(uint16(opcode[0])<<8 | uint16(opcode[1])); ok {
+			t.Instruction = i
+			if long, err := c.ram.Peek(c.pc+2, Length(i)-2); err == nil {
+				t.Bytes = append(t.Bytes, long...)
+			}
+		}
+	}
+	unobserve := c.ram.Observe(func(a Access) {
+		if a.Write {
+			t.Writes = append(t.Writes, a)
+		}
+	})
+	t.Err = c.execute()
+	unobserve()
+	t.After = c.Registers()
+	for _, tracer := range c.tracers {
+		tracer.tracer(t)
+	}
+	return t.Err
+}
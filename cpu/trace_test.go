@@ -0,0 +1,58 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCPU_Trace(t *testing.T) {
+	// A300 6105 F155 0000: store V0 and V1 at 300, then fault
+	cpu := newProgram(t, []byte{0xA3, 0x00, 0x61, 0x05, 0xF1, 0x55, 0x00, 0x00})
+	var traces []Trace
+	untrace := cpu.Trace(func(t Trace) {
+		traces = append(traces, t)
+	})
+	for n := 0; n < 3; n++ {
+		assert.NoError(t, cpu.Step())
+	}
+	assert.Equal(t, InstructionUnknown{0x0000}, cpu.Step())
+	if !assert.Len(t, traces, 4) {
+		return
+	}
+
+	assert.Equal(t, []byte{0xA3, 0x00}, traces[0].Bytes)
+	assert.Equal(t, "Load Index", traces[0].Instruction.Name())
+	assert.Equal(t, Registers{PC: 0x200}, traces[0].Before)
+	assert.Equal(t, Registers{PC: 0x202, I: 0x300}, traces[0].After)
+	assert.EqualValues(t, 5, traces[1].After.V[0x1])
+	assert.Equal(t, []Access{{0x300, 0x00, true, false}, {0x301, 0x05, true, false}}, traces[2].Writes)
+	assert.EqualValues(t, 0x302, traces[2].After.I)
+	assert.Nil(t, traces[3].Instruction)
+	assert.Equal(t, InstructionUnknown{0x0000}, traces[3].Err)
+
+	untrace()
+	cpu.SetPC(0x200)
+	assert.NoError(t, cpu.Step())
+	assert.Len(t, traces, 4, "removed tracers should not be called")
+}
+
+func TestCPU_Trace_longOperand(t *testing.T) {
+	// F000 0300 then spin: I := 0x0300
+	program := []byte{0xF0, 0x00, 0x03, 0x00, 0x12, 0x04}
+	cpu := NewCPU(NewRAM(0x10000), WithMode(ModeXOChip))
+	assert.NoError(t, cpu.ram.Writes(0x200, program))
+	var traces []Trace
+	cpu.Trace(func(t Trace) {
+		traces = append(traces, t)
+	})
+	assert.NoError(t, cpu.Step())
+	assert.NoError(t, cpu.Step())
+	memory, err := cpu.ram.Peek(0x200, uint16(len(program)))
+	assert.NoError(t, err)
+	assert.Equal(t, program, memory, "tracing should not touch memory")
+
+	assert.NoError(t, cpu.ram.Writes(0x200, []byte{0, 0, 0, 0}))
+	assert.Equal(t, []byte{0xF0, 0x00, 0x03, 0x00}, traces[0].Bytes, "traces should not share memory")
+	assert.Equal(t, []byte{0x12, 0x04}, traces[1].Bytes)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+
+	"github.com/Nuxij/goch8p/cpu"
+	"github.com/Nuxij/goch8p/debug"
+)
+
+// debugCommand serves a ROM to one GDB client, loading the breakpoints
+// and monitors an Octo source declares if given one. It exits once the
+// client detaches or kills the program, or on an interrupt.
+func debugCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("debug", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	machineFlags := newMachineFlags(flags)
+	playFlags := newPlayFlags(flags)
+	addr := flags.String("gdb", "localhost:1234", "address to listen for GDB on")
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: goch8p debug [flags] rom|source.8o")
+		flags.PrintDefaults()
+		return 2
+	}
+	m, err := machineFlags.parse()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	d, err := loadDebugger(positional[0], m, cpu.WithInstructionsPerFrame(*playFlags.speed))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	defer d.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	defer listener.Close()
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+	fmt.Fprintf(stdout, "listening on %s\n", listener.Addr())
+	conn, err := listener.Accept()
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0
+		}
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer conn.Close()
+	if err := debug.NewGDBStub(d).Serve(ctx, conn); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// loadDebugger builds a debugger with the ROM at path loaded, compiling
+// it first if it is Octo source
+func loadDebugger(path string, m platform, options ...cpu.Option) (*debug.Debugger, error) {
+	c, p, err := m.load(path, options...)
+	if err != nil {
+		return nil, err
+	}
+	d := debug.New(c)
+	if p != nil {
+		if err := d.Load(p); err != nil {
+			d.Close()
+			return nil, err
+		}
+	}
+	return d, nil
+}
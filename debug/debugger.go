@@ -0,0 +1,305 @@
+// Package debug wraps a cpu.CPU with breakpoints, watchpoints and
+// stepping, for tests and frontends alike
+package debug
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/Nuxij/goch8p/cpu"
+	"github.com/Nuxij/goch8p/octo"
+)
+
+// StopReason says why the debugger stopped the program
+type StopReason int
+
+const (
+	// StopStep is a step finishing
+	StopStep StopReason = iota
+	// StopBreakpoint is the program reaching a breakpoint
+	StopBreakpoint
+	// StopWatchpoint is the program accessing a watched address
+	StopWatchpoint
+	// StopInterrupted is the context being cancelled
+	StopInterrupted
+)
+
+func (r StopReason) String() string {
+	switch r {
+	case StopStep:
+		return "step"
+	case StopBreakpoint:
+		return "breakpoint"
+	case StopWatchpoint:
+		return "watchpoint"
+	case StopInterrupted:
+		return "interrupted"
+	}
+	return "unknown"
+}
+
+// Stop is where and why the program stopped
+type Stop struct {
+	Reason StopReason
+	PC     uint16
+	// Access is the access that hit a watchpoint
+	Access cpu.Access
+}
+
+// WatchKind picks which accesses a watchpoint stops on
+type WatchKind int
+
+const (
+	WatchRead WatchKind = 1 << iota
+	WatchWrite
+	WatchAccess = WatchRead | WatchWrite
+)
+
+// Breakpoint stops the program before it executes the instruction at
+// Addr, if Condition is nil or true. Name is set for breakpoints a
+// program asks for, such as Octo's :breakpoint.
+type Breakpoint struct {
+	Addr      uint16
+	Condition *Expression
+	Name      string
+}
+
+// Watchpoint stops the program after an instruction accesses Addr
+type Watchpoint struct {
+	Addr uint16
+	Kind WatchKind
+}
+
+// NotInSubroutine is returned stepping out at the top level
+type NotInSubroutine struct {
+	pc uint16
+}
+
+func (n NotInSubroutine) Error() string {
+	return fmt.Sprintf("cannot step out at %X: not in a subroutine", n.pc)
+}
+
+// Debugger drives a CPU an instruction at a time, stopping where asked
+type Debugger struct {
+	cpu         *cpu.CPU
+	breakpoints map[uint16]Breakpoint
+	watchpoints map[uint16]WatchKind
+	// stepping is set while an instruction executes, and hit holds the
+	// first access it made to a watched address
+	stepping  bool
+	hit       *cpu.Access
+	unobserve func()
+	monitors  []octo.Monitor
+}
+
+// New attaches a debugger to c, watching its memory until Close
+func New(c *cpu.CPU) *Debugger {
+	d := &Debugger{
+		cpu:         c,
+		breakpoints: make(map[uint16]Breakpoint),
+		watchpoints: make(map[uint16]WatchKind),
+	}
+	d.unobserve = c.Memory().Observe(d.observe)
+	return d
+}
+
+// Close detaches the debugger from the CPU's memory
+func (d *Debugger) Close() {
+	d.unobserve()
+}
+
+// CPU returns the CPU being debugged
+func (d *Debugger) CPU() *cpu.CPU {
+	return d.cpu
+}
+
+func (d *Debugger) observe(a cpu.Access) {
+	if !d.stepping || d.hit != nil {
+		return
+	}
+	// fetching an instruction, operand and all, is executing it, not
+	// reading it
+	kind, ok := d.watchpoints[a.Addr]
+	if ok && !a.Fetch && (a.Write && kind&WatchWrite != 0 || !a.Write && kind&WatchRead != 0) {
+		d.hit = &a
+	}
+}
+
+// Break sets a breakpoint at addr
+func (d *Debugger) Break(addr uint16) {
+	d.breakpoints[addr] = Breakpoint{Addr: addr}
+}
+
+// BreakIf sets a breakpoint at addr that only stops when condition,
+// an Expression, is true
+func (d *Debugger) BreakIf(addr uint16, condition string) error {
+	expression, err := ParseExpression(condition)
+	if err != nil {
+		return err
+	}
+	d.breakpoints[addr] = Breakpoint{Addr: addr, Condition: expression}
+	return nil
+}
+
+// Clear removes the breakpoint at addr
+func (d *Debugger) Clear(addr uint16) {
+	delete(d.breakpoints, addr)
+}
+
+// Breakpoints returns the breakpoints in address order
+func (d *Debugger) Breakpoints() []Breakpoint {
+	breakpoints := make([]Breakpoint, 0, len(d.breakpoints))
+	for _, b := range d.breakpoints {
+		breakpoints = append(breakpoints, b)
+	}
+	sort.Slice(breakpoints, func(i, j int) bool {
+		return breakpoints[i].Addr < breakpoints[j].Addr
+	})
+	return breakpoints
+}
+
+// Watch sets a watchpoint on addr. Reads are those of data: fetching an
+// instruction, the address XO-CHIP's F000 NNNN takes from the word after
+// it included, executes it rather than reading it, so never stops.
+func (d *Debugger) Watch(addr uint16, kind WatchKind) {
+	d.watchpoints[addr] = kind
+}
+
+// Unwatch removes the watchpoint on addr
+func (d *Debugger) Unwatch(addr uint16) {
+	delete(d.watchpoints, addr)
+}
+
+// Watchpoints returns the watchpoints in address order
+func (d *Debugger) Watchpoints() []Watchpoint {
+	watchpoints := make([]Watchpoint, 0, len(d.watchpoints))
+	for addr, kind := range d.watchpoints {
+		watchpoints = append(watchpoints, Watchpoint{addr, kind})
+	}
+	sort.Slice(watchpoints, func(i, j int) bool {
+		return watchpoints[i].Addr < watchpoints[j].Addr
+	})
+	return watchpoints
+}
+
+// Load puts a compiled Octo program into memory, along with the
+// breakpoints and monitors it declares
+func (d *Debugger) Load(p *octo.Program) error {
+	if err := d.cpu.Memory().Poke(p.Origin, p.ROM); err != nil {
+		return err
+	}
+	for _, b := range p.Breakpoints {
+		d.breakpoints[b.Addr] = Breakpoint{Addr: b.Addr, Name: b.Name}
+	}
+	for _, m := range p.Monitors {
+		d.Monitor(m)
+	}
+	return nil
+}
+
+// Monitor adds a monitor, for frontends to show alongside the program
+func (d *Debugger) Monitor(m octo.Monitor) {
+	d.monitors = append(d.monitors, m)
+}
+
+// Monitors returns the monitors in the order they were added
+func (d *Debugger) Monitors() []octo.Monitor {
+	return d.monitors
+}
+
+// Inspect renders the memory a monitor shows, without triggering
+// watchpoints
+func (d *Debugger) Inspect(m octo.Monitor) (string, error) {
+	data, err := d.cpu.Memory().Peek(m.Addr, uint16(m.Size()))
+	if err != nil {
+		return "", err
+	}
+	return m.Render(data), nil
+}
+
+// StepInto executes one instruction, following calls
+func (d *Debugger) StepInto() (Stop, error) {
+	return d.step()
+}
+
+// StepOver executes one instruction, running a 2NNN call through to its
+// return unless a breakpoint or watchpoint stops it first
+func (d *Debugger) StepOver(ctx context.Context) (Stop, error) {
+	opcode, err := d.cpu.Memory().Peek(d.cpu.PC(), 2)
+	if err != nil {
+		return Stop{PC: d.cpu.PC()}, err
+	}
+	if binary.BigEndian.Uint16(opcode)&0xF000 != 0x2000 {
+		return d.step()
+	}
+	depth := d.cpu.StackDepth()
+	return d.run(ctx, func() bool {
+		return d.cpu.StackDepth() <= depth
+	})
+}
+
+// StepOut runs until the current subroutine returns, unless a breakpoint
+// or watchpoint stops it first
+func (d *Debugger) StepOut(ctx context.Context) (Stop, error) {
+	depth := d.cpu.StackDepth()
+	if depth == 0 {
+		return Stop{PC: d.cpu.PC()}, NotInSubroutine{d.cpu.PC()}
+	}
+	return d.run(ctx, func() bool {
+		return d.cpu.StackDepth() < depth
+	})
+}
+
+// Continue runs until a breakpoint or watchpoint stops the program, it
+// fails, or ctx is cancelled
+func (d *Debugger) Continue(ctx context.Context) (Stop, error) {
+	return d.run(ctx, func() bool {
+		return false
+	})
+}
+
+// run steps until done, stopping early at breakpoints other than the
+// one the program starts on, watchpoints, errors and cancellation
+func (d *Debugger) run(ctx context.Context, done func() bool) (Stop, error) {
+	for first := true; ; first = false {
+		if ctx.Err() != nil {
+			return Stop{Reason: StopInterrupted, PC: d.cpu.PC()}, nil
+		}
+		if !first {
+			if hit, err := d.breaks(); hit || err != nil {
+				return Stop{Reason: StopBreakpoint, PC: d.cpu.PC()}, err
+			}
+		}
+		stop, err := d.step()
+		if err != nil || stop.Reason != StopStep || done() {
+			return stop, err
+		}
+	}
+}
+
+// breaks reports whether a breakpoint stops the program where it is
+func (d *Debugger) breaks() (bool, error) {
+	b, ok := d.breakpoints[d.cpu.PC()]
+	if !ok {
+		return false, nil
+	}
+	if b.Condition == nil {
+		return true, nil
+	}
+	value, err := b.Condition.Eval(d.cpu)
+	return value != 0, err
+}
+
+// step executes an instruction, noting any watchpoint it hits
+func (d *Debugger) step() (Stop, error) {
+	d.stepping, d.hit = true, nil
+	err := d.cpu.Step()
+	d.stepping = false
+	stop := Stop{Reason: StopStep, PC: d.cpu.PC()}
+	if d.hit != nil {
+		stop.Reason, stop.Access = StopWatchpoint, *d.hit
+	}
+	return stop, err
+}
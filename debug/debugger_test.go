@@ -0,0 +1,192 @@
+package debug
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Nuxij/goch8p/asm"
+	"github.com/Nuxij/goch8p/cpu"
+	"github.com/Nuxij/goch8p/octo"
+	"github.com/stretchr/testify/assert"
+)
+
+// program counts up in V0, calling a subroutine at 208 that stores V0
+// at 0x300 and nests a call to 210
+var program = asm.MustAssemble(`
+loop:                   ; 200
+    ADD V0, 1
+    CALL store          ; 202
+    JP loop             ; 204
+    db 0, 0             ; 206
+store:
+    LD I, 0x300         ; 208
+    LD [I], V0          ; 20A
+    CALL nested         ; 20C
+    RET                 ; 20E
+nested:
+    LD V1, 1            ; 210
+    RET                 ; 212
+`, asm.Options{}).ROM
+
+func newDebugger(t *testing.T) *Debugger {
+	c := cpu.NewCPU(cpu.NewRAM(0x1000))
+	assert.NoError(t, c.Memory().Poke(0x200, program))
+	return New(c)
+}
+
+// octoProgram counts calls to a subroutine, asking debuggers to stop
+// before each and to show the count
+func octoProgram(t *testing.T) *octo.Program {
+	p, err := octo.Compile("count.8o", []byte(`
+: store
+	:breakpoint before-store
+	i := counter
+	save v0
+	return
+: counter 0
+:monitor counter 1
+:monitor counter "%i times"
+: main
+	loop
+		v0 += 1
+		store
+	again
+`))
+	assert.NoError(t, err)
+	return p
+}
+
+func TestDebugger_Load(t *testing.T) {
+	d := New(cpu.NewCPU(cpu.NewRAM(0x1000)))
+	assert.NoError(t, d.Load(octoProgram(t)))
+	assert.Equal(t, []Breakpoint{{Addr: 0x202, Name: "before-store"}}, d.Breakpoints())
+	assert.Len(t, d.Monitors(), 2)
+
+	ctx := context.Background()
+	for want := 0; want < 3; want++ {
+		stop, err := d.Continue(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, Stop{Reason: StopBreakpoint, PC: 0x202}, stop)
+		shown, err := d.Inspect(d.Monitors()[1])
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("%d times", want), shown)
+	}
+	shown, _ := d.Inspect(d.Monitors()[0])
+	assert.Equal(t, "02", shown)
+}
+
+func TestDebugger_StepInto(t *testing.T) {
+	d := newDebugger(t)
+	for _, want := range []uint16{0x202, 0x208, 0x20A, 0x20C, 0x210} {
+		stop, err := d.StepInto()
+		assert.NoError(t, err)
+		assert.Equal(t, Stop{Reason: StopStep, PC: want}, stop)
+	}
+	assert.Equal(t, 2, d.CPU().StackDepth())
+}
+
+func TestDebugger_StepOver(t *testing.T) {
+	d := newDebugger(t)
+	ctx := context.Background()
+	d.StepInto()
+	stop, err := d.StepOver(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, Stop{Reason: StopStep, PC: 0x204}, stop, "the call should run to its return")
+	assert.EqualValues(t, 1, d.CPU().V(0x1))
+	stop, _ = d.StepOver(ctx)
+	assert.Equal(t, Stop{Reason: StopStep, PC: 0x200}, stop, "other instructions should just step")
+
+	d.Break(0x210)
+	d.StepInto()
+	stop, _ = d.StepOver(ctx)
+	assert.Equal(t, Stop{Reason: StopBreakpoint, PC: 0x210}, stop, "breakpoints inside the call should stop it")
+}
+
+func TestDebugger_StepOut(t *testing.T) {
+	d := newDebugger(t)
+	ctx := context.Background()
+	_, err := d.StepOut(ctx)
+	assert.Equal(t, NotInSubroutine{0x200}, err)
+
+	for d.CPU().PC() != 0x210 {
+		d.StepInto()
+	}
+	stop, err := d.StepOut(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, Stop{Reason: StopStep, PC: 0x20E}, stop)
+	stop, _ = d.StepOut(ctx)
+	assert.Equal(t, Stop{Reason: StopStep, PC: 0x204}, stop)
+	assert.Equal(t, 0, d.CPU().StackDepth())
+}
+
+func TestDebugger_Continue(t *testing.T) {
+	d := newDebugger(t)
+	ctx := context.Background()
+	d.Break(0x208)
+	stop, err := d.Continue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, Stop{Reason: StopBreakpoint, PC: 0x208}, stop)
+	stop, _ = d.Continue(ctx)
+	assert.Equal(t, Stop{Reason: StopBreakpoint, PC: 0x208}, stop, "continuing should leave the breakpoint it is on")
+	assert.EqualValues(t, 2, d.CPU().V(0x0))
+
+	d.Clear(0x208)
+	assert.NoError(t, d.BreakIf(0x208, "V0 == 5"))
+	stop, _ = d.Continue(ctx)
+	assert.Equal(t, Stop{Reason: StopBreakpoint, PC: 0x208}, stop)
+	assert.EqualValues(t, 5, d.CPU().V(0x0))
+	assert.Len(t, d.Breakpoints(), 1)
+	assert.Equal(t, "V0 == 5", d.Breakpoints()[0].Condition.String())
+
+	assert.Error(t, d.BreakIf(0x202, "V0 =="))
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	stop, err = d.Continue(cancelled)
+	assert.NoError(t, err)
+	assert.Equal(t, StopInterrupted, stop.Reason)
+}
+
+func TestDebugger_Watch(t *testing.T) {
+	d := newDebugger(t)
+	ctx := context.Background()
+	d.Watch(0x300, WatchWrite)
+	stop, err := d.Continue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, Stop{StopWatchpoint, 0x20C, cpu.Access{Addr: 0x300, Value: 1, Write: true}}, stop)
+
+	// FX55 has left I at 301
+	d.Watch(0x301, WatchRead)
+	d.CPU().Memory().Poke(0x210, []byte{0xF0, 0x65})
+	stop, err = d.Continue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, Stop{StopWatchpoint, 0x212, cpu.Access{Addr: 0x301, Value: 0}}, stop, "reads by FX65 should stop")
+
+	d.Unwatch(0x300)
+	d.Unwatch(0x301)
+	d.Watch(0x20A, WatchRead)
+	d.Break(0x20C)
+	stop, _ = d.Continue(ctx)
+	assert.Equal(t, Stop{Reason: StopBreakpoint, PC: 0x20C}, stop, "fetching 20A should not stop as a read")
+	assert.Equal(t, []Watchpoint{{0x20A, WatchRead}}, d.Watchpoints())
+	d.Clear(0x20C)
+
+	d.Close()
+	d.Break(0x202)
+	stop, _ = d.Continue(ctx)
+	assert.Equal(t, StopBreakpoint, stop.Reason, "closed debuggers should not watch")
+}
+
+func TestDebugger_Watch_longOperand(t *testing.T) {
+	// I := 0x0300 then spin
+	c := cpu.NewCPU(cpu.NewRAM(0x10000), cpu.WithMode(cpu.ModeXOChip))
+	assert.NoError(t, c.Memory().Poke(0x200, []byte{0xF0, 0x00, 0x03, 0x00, 0x12, 0x04}))
+	d := New(c)
+	d.Watch(0x202, WatchRead)
+	d.Watch(0x203, WatchRead)
+	d.Break(0x204)
+	stop, err := d.Continue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, Stop{Reason: StopBreakpoint, PC: 0x204}, stop, "F000's operand is fetched, not read")
+	assert.EqualValues(t, 0x300, c.Registers().I)
+}
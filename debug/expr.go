@@ -0,0 +1,326 @@
+package debug
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Nuxij/goch8p/cpu"
+)
+
+// ExpressionInvalid is returned parsing an expression that is not one
+type ExpressionInvalid struct {
+	source string
+	pos    int
+	reason string
+}
+
+func (e ExpressionInvalid) Error() string {
+	return fmt.Sprintf("invalid expression %q at %d: %s", e.source, e.pos, e.reason)
+}
+
+// DivisionByZero is returned evaluating an expression that divides by zero
+type DivisionByZero struct{}
+
+func (d DivisionByZero) Error() string {
+	return "division by zero"
+}
+
+// Expression is a C-like integer expression over the machine, such as
+// `V0 == 3 && [I + 1] > 0x10`. It may use the registers V0 to VF, I, PC,
+// DT and ST, memory bytes as [address], numbers in decimal, 0x hex or
+// 0b binary, and the unary - ! ~ and binary * / % + - << >> < <= > >=
+// == != & ^ | && || operators with C's precedence. Anything non-zero is
+// true.
+type Expression struct {
+	source string
+	eval   node
+}
+
+type node func(c *cpu.CPU) (int, error)
+
+// ParseExpression compiles source for evaluating against a CPU
+func ParseExpression(source string) (*Expression, error) {
+	p := &parser{source: source}
+	p.next()
+	eval, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.token != "" {
+		return nil, p.fail("unexpected %q", p.token)
+	}
+	return &Expression{source, eval}, nil
+}
+
+// Eval evaluates the expression against c, reading memory without
+// triggering watchpoints
+func (e *Expression) Eval(c *cpu.CPU) (int, error) {
+	return e.eval(c)
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+// operators lists the binary operators by precedence, loosest first
+var operators = [][]string{
+	{"||"},
+	{"&&"},
+	{"|"},
+	{"^"},
+	{"&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// symbols are the operator tokens, longest first so they match greedily
+var symbols = []string{
+	"||", "&&", "==", "!=", "<=", ">=", "<<", ">>",
+	"|", "^", "&", "<", ">", "+", "-", "*", "/", "%", "!", "~", "(", ")", "[", "]",
+}
+
+type parser struct {
+	source string
+	pos    int
+	// token is the current token and start where it began, "" at the end
+	token string
+	start int
+}
+
+func (p *parser) fail(format string, args ...interface{}) error {
+	return ExpressionInvalid{p.source, p.start, fmt.Sprintf(format, args...)}
+}
+
+// next moves on to the following token
+func (p *parser) next() {
+	for p.pos < len(p.source) && p.source[p.pos] == ' ' {
+		p.pos++
+	}
+	p.start = p.pos
+	if p.pos >= len(p.source) {
+		p.token = ""
+		return
+	}
+	for _, symbol := range symbols {
+		if strings.HasPrefix(p.source[p.pos:], symbol) {
+			p.pos += len(symbol)
+			p.token = symbol
+			return
+		}
+	}
+	end := p.pos
+	for end < len(p.source) && isWord(p.source[end]) {
+		end++
+	}
+	if end == p.pos {
+		end++
+	}
+	p.token = p.source[p.pos:end]
+	p.pos = end
+}
+
+func isWord(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+// binary parses operators of the given precedence level and tighter
+func (p *parser) binary(level int) (node, error) {
+	if level == len(operators) {
+		return p.unary()
+	}
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for contains(operators[level], p.token) {
+		operator := p.token
+		p.next()
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = apply(operator, left, right)
+	}
+	return left, nil
+}
+
+func contains(list []string, token string) bool {
+	for _, item := range list {
+		if item == token {
+			return true
+		}
+	}
+	return false
+}
+
+func apply(operator string, left, right node) node {
+	return func(c *cpu.CPU) (int, error) {
+		a, err := left(c)
+		if err != nil {
+			return 0, err
+		}
+		// the logical operators short circuit
+		switch operator {
+		case "&&":
+			if a == 0 {
+				return 0, nil
+			}
+		case "||":
+			if a != 0 {
+				return 1, nil
+			}
+		}
+		b, err := right(c)
+		if err != nil {
+			return 0, err
+		}
+		switch operator {
+		case "||", "&&":
+			return truth(b != 0), nil
+		case "|":
+			return a | b, nil
+		case "^":
+			return a ^ b, nil
+		case "&":
+			return a & b, nil
+		case "==":
+			return truth(a == b), nil
+		case "!=":
+			return truth(a != b), nil
+		case "<":
+			return truth(a < b), nil
+		case "<=":
+			return truth(a <= b), nil
+		case ">":
+			return truth(a > b), nil
+		case ">=":
+			return truth(a >= b), nil
+		case "<<":
+			return a << uint(b&63), nil
+		case ">>":
+			return a >> uint(b&63), nil
+		case "+":
+			return a + b, nil
+		case "-":
+			return a - b, nil
+		case "*":
+			return a * b, nil
+		case "/", "%":
+			if b == 0 {
+				return 0, DivisionByZero{}
+			}
+			if operator == "/" {
+				return a / b, nil
+			}
+			return a % b, nil
+		}
+		panic("unknown operator " + operator)
+	}
+}
+
+func truth(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (p *parser) unary() (node, error) {
+	switch operator := p.token; operator {
+	case "-", "!", "~":
+		p.next()
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(c *cpu.CPU) (int, error) {
+			a, err := operand(c)
+			switch operator {
+			case "-":
+				return -a, err
+			case "!":
+				return truth(a == 0), err
+			}
+			return ^a, err
+		}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	token := p.token
+	switch {
+	case token == "":
+		return nil, p.fail("unexpected end")
+	case token == "(":
+		p.next()
+		inner, err := p.binary(0)
+		if err != nil {
+			return nil, err
+		}
+		if p.token != ")" {
+			return nil, p.fail("want )")
+		}
+		p.next()
+		return inner, nil
+	case token == "[":
+		p.next()
+		addr, err := p.binary(0)
+		if err != nil {
+			return nil, err
+		}
+		if p.token != "]" {
+			return nil, p.fail("want ]")
+		}
+		p.next()
+		return func(c *cpu.CPU) (int, error) {
+			a, err := addr(c)
+			if err != nil {
+				return 0, err
+			}
+			data, err := c.Memory().Peek(uint16(a), 1)
+			if err != nil {
+				return 0, err
+			}
+			return int(data[0]), nil
+		}, nil
+	case '0' <= token[0] && token[0] <= '9':
+		value, err := strconv.ParseInt(token, 0, 64)
+		if err != nil {
+			return nil, p.fail("bad number %q", token)
+		}
+		p.next()
+		return func(c *cpu.CPU) (int, error) {
+			return int(value), nil
+		}, nil
+	}
+	if register := registerNode(strings.ToUpper(token)); register != nil {
+		p.next()
+		return register, nil
+	}
+	return nil, p.fail("unknown %q", token)
+}
+
+// registerNode returns a node reading the named register, or nil
+func registerNode(name string) node {
+	switch name {
+	case "I":
+		return func(c *cpu.CPU) (int, error) { return int(c.Index()), nil }
+	case "PC":
+		return func(c *cpu.CPU) (int, error) { return int(c.PC()), nil }
+	case "DT":
+		return func(c *cpu.CPU) (int, error) { return int(c.Timers().Delay()), nil }
+	case "ST":
+		return func(c *cpu.CPU) (int, error) { return int(c.Timers().Sound()), nil }
+	}
+	if len(name) == 2 && name[0] == 'V' {
+		if x, err := strconv.ParseUint(name[1:], 16, 8); err == nil {
+			return func(c *cpu.CPU) (int, error) { return int(c.V(byte(x))), nil }
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package debug
+
+import (
+	"testing"
+
+	"github.com/Nuxij/goch8p/cpu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseExpression(t *testing.T) {
+	c := cpu.NewCPU(cpu.NewRAM(0x1000))
+	c.SetV(0x0, 3)
+	c.SetV(0xF, 1)
+	c.SetIndex(0x300)
+	c.Memory().Poke(0x301, []byte{0x42})
+
+	tests := []struct {
+		source string
+		want   int
+	}{
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"V0", 3},
+		{"vf == 1", 1},
+		{"V0 == 3 && VF != 1", 0},
+		{"V0 == 4 || VF", 1},
+		{"I", 0x300},
+		{"PC", 0x200},
+		{"[I + 1]", 0x42},
+		{"[0x301] > 0x40", 1},
+		{"0b101 | 0x10", 0x15},
+		{"1 << 4 >> 2", 4},
+		{"-V0 + 10", 7},
+		{"!V0", 0},
+		{"~0 & 0xFF", 0xFF},
+		{"7 % 4 - 10 / 5", 1},
+		{"1 < 2 == 1", 1},
+		{"DT + ST", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			e, err := ParseExpression(tt.source)
+			assert.NoError(t, err)
+			got, err := e.Eval(c)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseExpression_invalid(t *testing.T) {
+	tests := []struct {
+		source string
+		err    ExpressionInvalid
+	}{
+		{"", ExpressionInvalid{"", 0, "unexpected end"}},
+		{"1 +", ExpressionInvalid{"1 +", 3, "unexpected end"}},
+		{"(1", ExpressionInvalid{"(1", 2, "want )"}},
+		{"[1", ExpressionInvalid{"[1", 2, "want ]"}},
+		{"VG", ExpressionInvalid{"VG", 0, `unknown "VG"`}},
+		{"0xZZ", ExpressionInvalid{"0xZZ", 0, `bad number "0xZZ"`}},
+		{"1 2", ExpressionInvalid{"1 2", 2, `unexpected "2"`}},
+		{"1 $ 2", ExpressionInvalid{"1 $ 2", 2, `unexpected "$"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			_, err := ParseExpression(tt.source)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func TestExpression_Eval_errors(t *testing.T) {
+	c := cpu.NewCPU(cpu.NewRAM(0x1000))
+	e, _ := ParseExpression("1 / V0")
+	_, err := e.Eval(c)
+	assert.Equal(t, DivisionByZero{}, err)
+	e, _ = ParseExpression("[0x2000]")
+	_, err = e.Eval(c)
+	assert.Error(t, err, "reading unmapped memory should fail")
+	e, _ = ParseExpression("0 && 1 / 0")
+	_, err = e.Eval(c)
+	assert.NoError(t, err, "&& should short circuit")
+}
@@ -0,0 +1,516 @@
+package debug
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/Nuxij/goch8p/cpu"
+)
+
+// The registers as GDB numbers them. They are sent big endian, as the
+// CHIP-8 stores words, V0 to VF a byte each and the rest two bytes.
+const (
+	RegisterI  = 16
+	RegisterSP = 17
+	RegisterPC = 18
+	registers  = 19
+)
+
+// Signals reported to GDB when the program stops
+const (
+	signalInterrupt = 2
+	signalIllegal   = 4
+	signalTrap      = 5
+	signalSegfault  = 11
+)
+
+// TargetXML describes the registers to GDB, so they show with their
+// CHIP-8 names
+var TargetXML = targetXML()
+
+func targetXML() string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0"?>` + "\n")
+	b.WriteString(`<!DOCTYPE target SYSTEM "gdb-target.dtd">` + "\n")
+	b.WriteString(`<target version="1.0">` + "\n")
+	b.WriteString(`  <feature name="org.goch8p.chip8">` + "\n")
+	for x := 0; x < 16; x++ {
+		fmt.Fprintf(&b, `    <reg name="v%x" bitsize="8" type="uint8" regnum="%d"/>`+"\n", x, x)
+	}
+	fmt.Fprintf(&b, `    <reg name="i" bitsize="16" type="data_ptr" regnum="%d"/>`+"\n", RegisterI)
+	fmt.Fprintf(&b, `    <reg name="sp" bitsize="16" type="uint16" regnum="%d"/>`+"\n", RegisterSP)
+	fmt.Fprintf(&b, `    <reg name="pc" bitsize="16" type="code_ptr" regnum="%d"/>`+"\n", RegisterPC)
+	b.WriteString("  </feature>\n")
+	b.WriteString("</target>\n")
+	return b.String()
+}
+
+// GDBStub serves the GDB remote serial protocol, letting a GDB client
+// read and write registers and memory, set breakpoints and watchpoints,
+// and step or continue. While a client is attached the stub drives the
+// CPU, so nothing else should run it.
+//
+// SP reads as the stack depth and ignores writes.
+type GDBStub struct {
+	debugger *Debugger
+	noAck    bool
+	// last is the last packet sent, for sending again if it is refused
+	last string
+}
+
+func NewGDBStub(d *Debugger) *GDBStub {
+	return &GDBStub{debugger: d}
+}
+
+// ListenAndServe listens on addr, such as "localhost:1234", serving one
+// client at a time until ctx is cancelled
+func (s *GDBStub) ListenAndServe(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		err = s.Serve(ctx, conn)
+		conn.Close()
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+	}
+}
+
+// event is something a client sent: a packet, an ack or an interrupt
+type event struct {
+	packet    string
+	valid     bool
+	ack       byte
+	interrupt bool
+	err       error
+}
+
+// Serve speaks to one client over conn until it detaches, kills the
+// program, goes away or ctx is cancelled
+func (s *GDBStub) Serve(ctx context.Context, conn io.ReadWriter) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.noAck = false
+	events := make(chan event)
+	go read(ctx, conn, events)
+	for {
+		var e event
+		select {
+		case <-ctx.Done():
+			return nil
+		case e = <-events:
+		}
+		switch {
+		case e.err != nil:
+			if errors.Is(e.err, io.EOF) {
+				return nil
+			}
+			return e.err
+		case e.ack == '-':
+			if err := s.write(conn, s.last); err != nil {
+				return err
+			}
+		case e.ack != 0, e.interrupt:
+		case !e.valid:
+			if _, err := conn.Write([]byte("-")); err != nil {
+				return err
+			}
+		default:
+			if !s.noAck {
+				if _, err := conn.Write([]byte("+")); err != nil {
+					return err
+				}
+			}
+			reply, done := s.handle(ctx, e.packet, events)
+			if err := s.send(conn, reply); err != nil {
+				return err
+			}
+			if done {
+				return nil
+			}
+		}
+	}
+}
+
+// read turns what the client sends into events until it fails
+func read(ctx context.Context, conn io.Reader, events chan<- event) {
+	r := bufio.NewReader(conn)
+	emit := func(e event) bool {
+		select {
+		case events <- e:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			emit(event{err: err})
+			return
+		}
+		var e event
+		switch b {
+		case '+', '-':
+			e.ack = b
+		case 0x03:
+			e.interrupt = true
+		case '$':
+			data, err := r.ReadString('#')
+			if err != nil {
+				emit(event{err: err})
+				return
+			}
+			sum := make([]byte, 2)
+			if _, err := io.ReadFull(r, sum); err != nil {
+				emit(event{err: err})
+				return
+			}
+			e.packet = data[:len(data)-1]
+			want, err := strconv.ParseUint(string(sum), 16, 8)
+			e.valid = err == nil && byte(want) == checksum(e.packet)
+		default:
+			continue
+		}
+		if !emit(e) {
+			return
+		}
+	}
+}
+
+func checksum(data string) byte {
+	sum := byte(0)
+	for i := 0; i < len(data); i++ {
+		sum += data[i]
+	}
+	return sum
+}
+
+func (s *GDBStub) send(w io.Writer, data string) error {
+	s.last = data
+	return s.write(w, data)
+}
+
+func (s *GDBStub) write(w io.Writer, data string) error {
+	_, err := fmt.Fprintf(w, "$%s#%02x", data, checksum(data))
+	return err
+}
+
+// handle answers a packet, reporting whether the session is over. An
+// empty reply tells the client the packet is unsupported.
+func (s *GDBStub) handle(ctx context.Context, packet string, events <-chan event) (string, bool) {
+	if packet == "" {
+		return "", false
+	}
+	d := s.debugger
+	args := packet[1:]
+	switch packet[0] {
+	case '?':
+		return fmt.Sprintf("S%02x", signalTrap), false
+	case 'g':
+		return s.readRegisters(), false
+	case 'G':
+		return s.writeRegisters(args), false
+	case 'p':
+		n, err := strconv.ParseUint(args, 16, 8)
+		if err != nil || n >= registers {
+			return "E01", false
+		}
+		return registerHex(d.CPU(), int(n)), false
+	case 'P':
+		parts := strings.SplitN(args, "=", 2)
+		n, err := strconv.ParseUint(parts[0], 16, 8)
+		if err != nil || n >= registers || len(parts) != 2 {
+			return "E01", false
+		}
+		value, err := hex.DecodeString(parts[1])
+		if err != nil || len(value) != registerSize(int(n)) {
+			return "E01", false
+		}
+		setRegister(d.CPU(), int(n), value)
+		return "OK", false
+	case 'm':
+		addr, length, err := addressLength(args)
+		if err != nil {
+			return "E01", false
+		}
+		data, err := d.CPU().Memory().Peek(addr, length)
+		if err != nil {
+			return "E0E", false
+		}
+		return hex.EncodeToString(data), false
+	case 'M':
+		parts := strings.SplitN(args, ":", 2)
+		addr, length, err := addressLength(parts[0])
+		if err != nil || len(parts) != 2 {
+			return "E01", false
+		}
+		data, err := hex.DecodeString(parts[1])
+		if err != nil || len(data) != int(length) {
+			return "E01", false
+		}
+		if err := d.CPU().Memory().Poke(addr, data); err != nil {
+			return "E0E", false
+		}
+		return "OK", false
+	case 'Z', 'z':
+		return s.point(packet[0] == 'Z', args), false
+	case 's':
+		stop, err := d.StepInto()
+		return stopReply(stop, err), false
+	case 'c':
+		return s.resume(ctx, events)
+	case 'D':
+		return "OK", true
+	case 'k':
+		return "", true
+	case 'H':
+		return "OK", false
+	case 'q', 'Q':
+		return s.query(packet), false
+	}
+	return "", false
+}
+
+// resume continues until the program stops or the client interrupts
+// it, reporting whether the client has gone away meanwhile
+func (s *GDBStub) resume(ctx context.Context, events <-chan event) (string, bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var stop Stop
+	var err error
+	gone := false
+	done := make(chan struct{})
+	go func() {
+		stop, err = s.debugger.Continue(ctx)
+		close(done)
+	}()
+	for {
+		select {
+		case <-done:
+			return stopReply(stop, err), gone
+		case e := <-events:
+			if e.interrupt || e.err != nil {
+				gone = gone || e.err != nil
+				cancel()
+			}
+		}
+	}
+}
+
+func (s *GDBStub) query(packet string) string {
+	switch {
+	case strings.HasPrefix(packet, "qSupported"):
+		return "PacketSize=1000;qXfer:features:read+;swbreak+;QStartNoAckMode+"
+	case packet == "QStartNoAckMode":
+		s.noAck = true
+		return "OK"
+	case strings.HasPrefix(packet, "qXfer:features:read:target.xml:"):
+		offset, length, err := addressLength(strings.TrimPrefix(packet, "qXfer:features:read:target.xml:"))
+		if err != nil {
+			return "E01"
+		}
+		if int(offset) >= len(TargetXML) {
+			return "l"
+		}
+		end := int(offset) + int(length)
+		if end >= len(TargetXML) {
+			return "l" + TargetXML[offset:]
+		}
+		return "m" + TargetXML[offset:end]
+	case packet == "qAttached":
+		return "1"
+	case packet == "qC":
+		return "QC1"
+	case packet == "qfThreadInfo":
+		return "m1"
+	case packet == "qsThreadInfo":
+		return "l"
+	case strings.HasPrefix(packet, "qRcmd,"):
+		command, err := hex.DecodeString(strings.TrimPrefix(packet, "qRcmd,"))
+		if err != nil {
+			return "E01"
+		}
+		return hex.EncodeToString([]byte(s.command(string(command))))
+	}
+	return ""
+}
+
+// command answers GDB's monitor command, listing the breakpoints or
+// showing the monitors a program declares
+func (s *GDBStub) command(command string) string {
+	var b strings.Builder
+	switch strings.TrimSpace(command) {
+	case "breakpoints":
+		for _, bp := range s.debugger.Breakpoints() {
+			fmt.Fprintf(&b, "%03X", bp.Addr)
+			if bp.Name != "" {
+				fmt.Fprintf(&b, " %s", bp.Name)
+			}
+			if bp.Condition != nil {
+				fmt.Fprintf(&b, " if %s", bp.Condition)
+			}
+			b.WriteString("\n")
+		}
+	case "monitors":
+		for _, m := range s.debugger.Monitors() {
+			value, err := s.debugger.Inspect(m)
+			if err != nil {
+				value = err.Error()
+			}
+			fmt.Fprintf(&b, "%s (%03X): %s\n", m.Name, m.Addr, value)
+		}
+	default:
+		b.WriteString("monitor commands: breakpoints, monitors\n")
+	}
+	return b.String()
+}
+
+// point sets or clears a breakpoint or watchpoint from a Z or z packet
+func (s *GDBStub) point(set bool, args string) string {
+	parts := strings.Split(args, ",")
+	if len(parts) < 2 {
+		return "E01"
+	}
+	addr, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return "E01"
+	}
+	d := s.debugger
+	kinds := map[string]WatchKind{"2": WatchWrite, "3": WatchRead, "4": WatchAccess}
+	switch {
+	case parts[0] == "0" && set:
+		d.Break(uint16(addr))
+	case parts[0] == "0":
+		d.Clear(uint16(addr))
+	case kinds[parts[0]] != 0 && set:
+		d.Watch(uint16(addr), kinds[parts[0]])
+	case kinds[parts[0]] != 0:
+		d.Unwatch(uint16(addr))
+	default:
+		return ""
+	}
+	return "OK"
+}
+
+// stopReply tells the client why the program stopped
+func stopReply(stop Stop, err error) string {
+	var unknown cpu.InstructionUnknown
+	switch {
+	case cpu.IsHalted(err):
+		return "W00"
+	case errors.As(err, &unknown):
+		return fmt.Sprintf("S%02x", signalIllegal)
+	case err != nil:
+		return fmt.Sprintf("S%02x", signalSegfault)
+	}
+	switch stop.Reason {
+	case StopBreakpoint:
+		return fmt.Sprintf("T%02xswbreak:;", signalTrap)
+	case StopWatchpoint:
+		kind := "rwatch"
+		if stop.Access.Write {
+			kind = "watch"
+		}
+		return fmt.Sprintf("T%02x%s:%x;", signalTrap, kind, stop.Access.Addr)
+	case StopInterrupted:
+		return fmt.Sprintf("S%02x", signalInterrupt)
+	}
+	return fmt.Sprintf("S%02x", signalTrap)
+}
+
+func (s *GDBStub) readRegisters() string {
+	var b strings.Builder
+	for n := 0; n < registers; n++ {
+		b.WriteString(registerHex(s.debugger.CPU(), n))
+	}
+	return b.String()
+}
+
+func (s *GDBStub) writeRegisters(args string) string {
+	data, err := hex.DecodeString(args)
+	if err != nil || len(data) != 16+3*2 {
+		return "E01"
+	}
+	c := s.debugger.CPU()
+	for n := 0; n < 16; n++ {
+		setRegister(c, n, data[n:n+1])
+	}
+	for n := RegisterI; n < registers; n++ {
+		at := 16 + (n-RegisterI)*2
+		setRegister(c, n, data[at:at+2])
+	}
+	return "OK"
+}
+
+// registerHex returns register n as big endian hex
+func registerHex(c *cpu.CPU, n int) string {
+	switch n {
+	case RegisterI:
+		return fmt.Sprintf("%04x", c.Index())
+	case RegisterSP:
+		return fmt.Sprintf("%04x", c.StackDepth())
+	case RegisterPC:
+		return fmt.Sprintf("%04x", c.PC())
+	}
+	return fmt.Sprintf("%02x", c.V(byte(n)))
+}
+
+// registerSize returns how many bytes register n is, as TargetXML says
+func registerSize(n int) int {
+	if n < RegisterI {
+		return 1
+	}
+	return 2
+}
+
+// setRegister sets register n from big endian bytes, registerSize(n) of them
+func setRegister(c *cpu.CPU, n int, value []byte) {
+	switch n {
+	case RegisterI:
+		c.SetIndex(binary.BigEndian.Uint16(value))
+	case RegisterSP:
+	case RegisterPC:
+		c.SetPC(binary.BigEndian.Uint16(value))
+	default:
+		c.SetV(byte(n), value[0])
+	}
+}
+
+// addressLength parses the hex "addr,length" GDB sends
+func addressLength(args string) (uint16, uint16, error) {
+	parts := strings.SplitN(args, ",", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("want addr,length, got %q", args)
+	}
+	addr, err := strconv.ParseUint(parts[0], 16, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	length, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	return uint16(addr), uint16(length), nil
+}
@@ -0,0 +1,170 @@
+package debug
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/Nuxij/goch8p/cpu"
+	"github.com/stretchr/testify/assert"
+)
+
+// gdbClient speaks just enough of the protocol to drive a stub
+type gdbClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// attach serves d over a pipe, returning the client end and the error
+// Serve finishes with
+func attach(t *testing.T, d *Debugger) (*gdbClient, <-chan error) {
+	server, conn := net.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- NewGDBStub(d).Serve(context.Background(), server)
+		server.Close()
+	}()
+	return &gdbClient{t, conn, bufio.NewReader(conn)}, done
+}
+
+func (c *gdbClient) send(packet string) {
+	fmt.Fprintf(c.conn, "$%s#%02x", packet, checksum(packet))
+	ack, err := c.r.ReadByte()
+	assert.NoError(c.t, err)
+	assert.Equal(c.t, byte('+'), ack, "packet %q should be acknowledged", packet)
+}
+
+func (c *gdbClient) reply() string {
+	_, err := c.r.ReadString('$')
+	assert.NoError(c.t, err)
+	data, err := c.r.ReadString('#')
+	assert.NoError(c.t, err)
+	sum := make([]byte, 2)
+	c.r.Read(sum)
+	data = strings.TrimSuffix(data, "#")
+	assert.Equal(c.t, fmt.Sprintf("%02x", checksum(data)), string(sum))
+	c.conn.Write([]byte("+"))
+	return data
+}
+
+func (c *gdbClient) ask(packet string) string {
+	c.send(packet)
+	return c.reply()
+}
+
+func TestGDBStub(t *testing.T) {
+	d := newDebugger(t)
+	client, done := attach(t, d)
+
+	assert.Contains(t, client.ask("qSupported:swbreak+"), "qXfer:features:read+")
+	assert.Equal(t, "S05", client.ask("?"))
+
+	xml := ""
+	for {
+		chunk := client.ask(fmt.Sprintf("qXfer:features:read:target.xml:%x,40", len(xml)))
+		xml += chunk[1:]
+		if chunk[0] == 'l' {
+			break
+		}
+	}
+	assert.Equal(t, TargetXML, xml)
+	assert.Contains(t, xml, `<reg name="pc" bitsize="16" type="code_ptr" regnum="18"/>`)
+
+	// V0-VF, I, SP, PC
+	assert.Equal(t, strings.Repeat("00", 16)+"0000"+"0000"+"0200", client.ask("g"))
+	assert.Equal(t, "OK", client.ask("P3=7f"))
+	assert.Equal(t, "OK", client.ask("P10=0300"))
+	assert.Equal(t, "7f", client.ask("p3"))
+	assert.EqualValues(t, 0x300, d.CPU().Index())
+	assert.Equal(t, "OK", client.ask("G"+strings.Repeat("01", 16)+"0123"+"0005"+"0200"))
+	assert.EqualValues(t, 0x01, d.CPU().V(0xF))
+	assert.EqualValues(t, 0x123, d.CPU().Index())
+	assert.Equal(t, "0000", client.ask("p11"), "SP should ignore writes")
+	assert.Equal(t, "E01", client.ask("P12=02"), "PC is two bytes")
+	assert.Equal(t, "E01", client.ask("P3=0102"), "V3 is one byte")
+	assert.Equal(t, "E01", client.ask("P10="))
+	assert.EqualValues(t, 0x200, d.CPU().PC())
+	assert.EqualValues(t, 0x123, d.CPU().Index())
+	assert.Equal(t, "E01", client.ask("p13"))
+
+	assert.Equal(t, "70012208", client.ask("m200,4"))
+	assert.Equal(t, "OK", client.ask("M300,2:abcd"))
+	assert.Equal(t, "abcd", client.ask("m300,2"))
+	assert.Equal(t, "E0E", client.ask("m2000,1"))
+
+	assert.Equal(t, "S05", client.ask("s"))
+	assert.Equal(t, "0202", client.ask("p12"))
+	assert.Equal(t, "OK", client.ask("Z0,20c,2"))
+	assert.Equal(t, "T05swbreak:;", client.ask("c"))
+	assert.Equal(t, "020c", client.ask("p12"))
+	assert.Equal(t, "OK", client.ask("z0,20c,2"))
+	assert.Equal(t, "OK", client.ask("Z2,300,1"))
+	assert.Equal(t, "T05watch:300;", client.ask("c"))
+	assert.Equal(t, "OK", client.ask("z2,300,1"))
+	assert.Equal(t, "", client.ask("vCont?"), "unsupported packets should get an empty reply")
+
+	// nothing stops the program now, so interrupt it
+	client.send("c")
+	client.conn.Write([]byte{0x03})
+	assert.Equal(t, "S02", client.reply())
+
+	assert.Equal(t, "OK", client.ask("D"))
+	assert.NoError(t, <-done)
+}
+
+func TestGDBStub_monitor(t *testing.T) {
+	d := newDebugger(t)
+	assert.NoError(t, d.Load(octoProgram(t)))
+	client, done := attach(t, d)
+
+	command := func(command string) string {
+		reply, err := hex.DecodeString(client.ask("qRcmd," + hex.EncodeToString([]byte(command))))
+		assert.NoError(t, err)
+		return string(reply)
+	}
+	assert.Equal(t, "202 before-store\n", command("breakpoints"))
+	assert.Equal(t, "counter (208): 00\ncounter (208): 0 times\n", command("monitors"))
+	assert.Contains(t, command("help"), "monitor commands")
+
+	assert.Equal(t, "OK", client.ask("D"))
+	assert.NoError(t, <-done)
+}
+
+func TestGDBStub_badChecksum(t *testing.T) {
+	client, done := attach(t, newDebugger(t))
+	client.conn.Write([]byte("$g#00"))
+	nak, _ := client.r.ReadByte()
+	assert.Equal(t, byte('-'), nak)
+	assert.Equal(t, "OK", client.ask("QStartNoAckMode"))
+	fmt.Fprintf(client.conn, "$%s#%02x", "p12", checksum("p12"))
+	assert.Equal(t, "0200", client.reply(), "no ack should be sent once acks are off")
+	client.conn.Close()
+	assert.NoError(t, <-done)
+}
+
+func TestStopReply(t *testing.T) {
+	tests := []struct {
+		name string
+		stop Stop
+		err  error
+		want string
+	}{
+		{"step", Stop{Reason: StopStep}, nil, "S05"},
+		{"breakpoint", Stop{Reason: StopBreakpoint}, nil, "T05swbreak:;"},
+		{"read watchpoint", Stop{Reason: StopWatchpoint, Access: cpu.Access{Addr: 0x3A}}, nil, "T05rwatch:3a;"},
+		{"interrupted", Stop{Reason: StopInterrupted}, nil, "S02"},
+		{"exited", Stop{}, cpu.Halted{}, "W00"},
+		{"unknown instruction", Stop{}, cpu.InstructionUnknown{}, "S04"},
+		{"fault", Stop{}, fmt.Errorf("Stack overflow"), "S0b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, stopReply(tt.stop, tt.err))
+		})
+	}
+}
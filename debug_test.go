@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gdbPacket frames data as a GDB packet with its checksum
+func gdbPacket(data string) string {
+	var sum byte
+	for _, b := range []byte(data) {
+		sum += b
+	}
+	return fmt.Sprintf("$%s#%02x", data, sum)
+}
+
+func TestDebugCommand(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "spin.8o")
+	assert.NoError(t, os.WriteFile(source, []byte(": main\n  v0 := 5\n  loop again\n"), 0644))
+
+	out, stdout := io.Pipe()
+	var stderr strings.Builder
+	done := make(chan int, 1)
+	go func() {
+		done <- debugCommand([]string{"-gdb", "127.0.0.1:0", source}, stdout, &stderr)
+		stdout.Close()
+	}()
+	listening, err := bufio.NewReader(out).ReadString('\n')
+	assert.NoError(t, err)
+	conn, err := net.Dial("tcp", strings.TrimSpace(strings.TrimPrefix(listening, "listening on ")))
+	assert.NoError(t, err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	fmt.Fprint(conn, gdbPacket("m200,4"))
+	reply, err := r.ReadString('#')
+	assert.NoError(t, err)
+	assert.Equal(t, "+$60051202#", reply, "the Octo source should be compiled and loaded")
+	r.Discard(2)
+	fmt.Fprint(conn, "+"+gdbPacket("D"))
+	reply, err = r.ReadString('#')
+	assert.NoError(t, err)
+	assert.Equal(t, "+$OK#", reply)
+	assert.Equal(t, 0, <-done, stderr.String())
+}
+
+func TestDebugCommand_missing(t *testing.T) {
+	var stdout, stderr strings.Builder
+	assert.Equal(t, 2, debugCommand([]string{filepath.Join(t.TempDir(), "missing.ch8")}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "no such file")
+	assert.Empty(t, stdout.String())
+}
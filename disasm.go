@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Nuxij/goch8p/disasm"
+)
+
+// disasmCommand disassembles a ROM, to stdout unless -o names a file
+func disasmCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("disasm", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	machineFlags := newMachineFlags(flags)
+	out := flags.String("o", "", "assembly to write, stdout if empty")
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: goch8p disasm [flags] rom [-o game.s]")
+		flags.PrintDefaults()
+		return 2
+	}
+	m, err := machineFlags.parse()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	listing := disasm.Disassemble(data, disasm.Options{Mode: m.Mode, Origin: m.Origin})
+	if *out == "" {
+		_, err = listing.WriteTo(stdout)
+	} else {
+		err = os.WriteFile(*out, []byte(listing.String()), 0644)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,299 @@
+// Package disasm turns ROMs into assembly, tracing control flow from the
+// entry point to tell code apart from data
+package disasm
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/Nuxij/goch8p/cpu"
+)
+
+// Options tune a disassembly
+type Options struct {
+	// Mode picks the instruction set, ModeChip8 being the default
+	Mode cpu.Mode
+	// Origin is where the ROM is loaded, cpu.DefaultOrigin if zero
+	Origin uint16
+	// Entries are more addresses known to be code, beyond Origin
+	Entries []uint16
+}
+
+// XrefKind says how an address is referred to
+type XrefKind int
+
+const (
+	// XrefCall is a 2NNN call
+	XrefCall XrefKind = iota
+	// XrefJump is a 1NNN or BNNN jump
+	XrefJump
+	// XrefIndex is I being loaded with the address, usually for data
+	XrefIndex
+)
+
+func (k XrefKind) String() string {
+	switch k {
+	case XrefCall:
+		return "called from"
+	case XrefJump:
+		return "jumped to from"
+	case XrefIndex:
+		return "used by"
+	}
+	return "unknown"
+}
+
+// Xref is a reference to an address from the instruction at From
+type Xref struct {
+	From uint16
+	Kind XrefKind
+}
+
+// Line is an instruction, or a byte of data if Instruction is nil
+type Line struct {
+	Addr        uint16
+	Bytes       []byte
+	Instruction cpu.Instruction
+	// Text is the line as assembly, without label or comment
+	Text string
+}
+
+// Listing is a disassembled ROM
+type Listing struct {
+	Mode   cpu.Mode
+	Origin uint16
+	Lines  []Line
+	// Labels names the addresses referred to, and Xrefs lists what
+	// refers to each
+	Labels map[uint16]string
+	Xrefs  map[uint16][]Xref
+}
+
+// tracer follows the control flow through a ROM
+type tracer struct {
+	rom     []byte
+	origin  uint16
+	mode    cpu.Mode
+	decoder *cpu.Decoder
+	// code holds the instruction starting at each address found to be code
+	code  map[uint16]cpu.Instruction
+	xrefs map[uint16][]Xref
+}
+
+// Disassemble traces rom from its origin and entries, following jumps,
+// calls and both ways out of skips. Whatever is never reached is data.
+func Disassemble(rom []byte, options Options) *Listing {
+	origin := options.Origin
+	if origin == 0 {
+		origin = cpu.DefaultOrigin
+	}
+	t := &tracer{
+		rom:     rom,
+		origin:  origin,
+		mode:    options.Mode,
+		decoder: options.Mode.Decoder(),
+		code:    make(map[uint16]cpu.Instruction),
+		xrefs:   make(map[uint16][]Xref),
+	}
+	queue := append([]uint16{origin}, options.Entries...)
+	for len(queue) > 0 {
+		addr := queue[len(queue)-1]
+		queue = t.trace(addr, queue[:len(queue)-1])
+	}
+	return t.listing()
+}
+
+// word returns the opcode at addr, if the ROM holds one there
+func (t *tracer) word(addr uint16) (uint16, bool) {
+	return cpu.ROMWord(t.rom, t.origin, addr)
+}
+
+// decode returns the instruction at addr and how long it is
+func (t *tracer) decode(addr uint16) (cpu.Instruction, uint16, bool) {
+	return cpu.DecodeROM(t.decoder, t.rom, t.origin, addr)
+}
+
+// trace follows straight-line code from addr, returning the queue with
+// any branches it finds added
+func (t *tracer) trace(addr uint16, queue []uint16) []uint16 {
+	for {
+		if _, seen := t.code[addr]; seen {
+			return queue
+		}
+		i, length, ok := t.decode(addr)
+		if !ok {
+			return queue
+		}
+		t.code[addr] = i
+		opcode, _ := t.word(addr)
+		next := addr + length
+		switch i.Code() {
+		case 0x1000:
+			t.refer(opcode&0x0FFF, addr, XrefJump)
+			return append(queue, opcode&0x0FFF)
+		case 0xB000:
+			// which entry of the table is taken depends on V0, so take
+			// the run of jumps at its base to be the table
+			t.refer(opcode&0x0FFF, addr, XrefJump)
+			queue = append(queue, opcode&0x0FFF)
+			for entry := opcode&0x0FFF + 2; ; entry += 2 {
+				if word, ok := t.word(entry); !ok || word&0xF000 != 0x1000 {
+					break
+				}
+				queue = append(queue, entry)
+			}
+			return queue
+		case 0x2000:
+			t.refer(opcode&0x0FFF, addr, XrefCall)
+			queue = append(queue, opcode&0x0FFF)
+		case 0x00EE, 0x00FD:
+			return queue
+		case 0xA000:
+			t.refer(opcode&0x0FFF, addr, XrefIndex)
+		case 0xF000:
+			long, _ := t.word(addr + 2)
+			t.refer(long, addr, XrefIndex)
+		case 0x3000, 0x4000, 0x5000, 0x9000, 0xE09E, 0xE0A1:
+			if _, skipped, ok := t.decode(next); ok {
+				queue = append(queue, next+skipped)
+			}
+		}
+		addr = next
+	}
+}
+
+func (t *tracer) refer(to, from uint16, kind XrefKind) {
+	t.xrefs[to] = append(t.xrefs[to], Xref{from, kind})
+}
+
+// listing lays the ROM out as lines, naming every address referred to
+// that a line starts at
+func (t *tracer) listing() *Listing {
+	l := &Listing{
+		Mode:   t.mode,
+		Origin: t.origin,
+		Labels: make(map[uint16]string),
+		Xrefs:  make(map[uint16][]Xref),
+	}
+	end := int(t.origin) + len(t.rom)
+	for at := int(t.origin); at < end; {
+		addr := uint16(at)
+		if i, ok := t.code[addr]; ok {
+			length := int(cpu.Length(i))
+			l.Lines = append(l.Lines, Line{Addr: addr, Bytes: t.rom[at-int(t.origin) : at-int(t.origin)+length], Instruction: i})
+			at += length
+			continue
+		}
+		l.Lines = append(l.Lines, Line{Addr: addr, Bytes: t.rom[at-int(t.origin) : at-int(t.origin)+1]})
+		at++
+	}
+	for _, line := range l.Lines {
+		xrefs := t.xrefs[line.Addr]
+		if line.Addr == t.origin {
+			l.Labels[line.Addr] = "start"
+		}
+		if len(xrefs) == 0 {
+			continue
+		}
+		sort.Slice(xrefs, func(i, j int) bool {
+			return xrefs[i].From < xrefs[j].From
+		})
+		l.Xrefs[line.Addr] = xrefs
+		if _, ok := l.Labels[line.Addr]; !ok {
+			l.Labels[line.Addr] = labelFor(line, xrefs)
+		}
+	}
+	for n, line := range l.Lines {
+		l.Lines[n].Text = l.format(line)
+	}
+	return l
+}
+
+// labelFor names an address by the most telling way it is referred to
+func labelFor(line Line, xrefs []Xref) string {
+	prefix := "data"
+	if line.Instruction != nil {
+		prefix = "label"
+	}
+	for _, x := range xrefs {
+		if x.Kind == XrefCall {
+			prefix = "sub"
+			break
+		}
+	}
+	return fmt.Sprintf("%s_%03X", prefix, line.Addr)
+}
+
+// format writes a line as assembly, referring to addresses by label
+func (l *Listing) format(line Line) string {
+	if line.Instruction == nil {
+		return fmt.Sprintf("db 0b%08b", line.Bytes[0])
+	}
+	return cpu.Format(line.Instruction, line.Bytes, l.address)
+}
+
+// address returns the label for addr, or addr in hex if it has none
+func (l *Listing) address(addr uint16) string {
+	if label, ok := l.Labels[addr]; ok {
+		return label
+	}
+	return fmt.Sprintf("0x%03X", addr)
+}
+
+// sprite draws a byte as a row of pixels
+func sprite(b byte) string {
+	return strings.NewReplacer("0", ".", "1", "#").Replace(fmt.Sprintf("%08b", b))
+}
+
+// WriteTo writes the listing as assembly, with labels, cross references
+// and each line's address and bytes in comments
+func (l *Listing) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	size := 0
+	for _, line := range l.Lines {
+		size += len(line.Bytes)
+	}
+	fmt.Fprintf(&b, "; %d bytes from 0x%03X, disassembled as %s\n", size, l.Origin, l.Mode)
+	for _, line := range l.Lines {
+		if label, ok := l.Labels[line.Addr]; ok {
+			b.WriteString("\n")
+			if xrefs := l.Xrefs[line.Addr]; len(xrefs) > 0 {
+				fmt.Fprintf(&b, "%-32s; %s\n", label+":", describe(xrefs))
+			} else {
+				fmt.Fprintf(&b, "%s:\n", label)
+			}
+		}
+		comment := fmt.Sprintf("%03X: %X", line.Addr, line.Bytes)
+		if line.Instruction == nil {
+			comment = fmt.Sprintf("%03X: %s", line.Addr, sprite(line.Bytes[0]))
+		}
+		fmt.Fprintf(&b, "    %-28s; %s\n", line.Text, comment)
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (l *Listing) String() string {
+	var b strings.Builder
+	l.WriteTo(&b)
+	return b.String()
+}
+
+// describe lists cross references by kind, as "called from 204, 20C"
+func describe(xrefs []Xref) string {
+	var parts []string
+	for _, kind := range []XrefKind{XrefCall, XrefJump, XrefIndex} {
+		var from []string
+		for _, x := range xrefs {
+			if x.Kind == kind {
+				from = append(from, fmt.Sprintf("%03X", x.From))
+			}
+		}
+		if len(from) > 0 {
+			parts = append(parts, kind.String()+" "+strings.Join(from, ", "))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
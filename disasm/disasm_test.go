@@ -0,0 +1,161 @@
+package disasm
+
+import (
+	"testing"
+
+	"github.com/Nuxij/goch8p/cpu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisassemble(t *testing.T) {
+	rom := []byte{
+		0x00, 0xE0, // 200: CLS
+		0xA2, 0x10, // 202: LD I, 210
+		0x22, 0x0A, // 204: CALL 20A
+		0x12, 0x04, // 206: JP 204
+		0xFF, 0xFF, // 208: never reached
+		0x30, 0x01, // 20A: SE V0, 1
+		0x60, 0x05, // 20C: LD V0, 5
+		0x00, 0xEE, // 20E: RET
+		0xF0, 0x90, 0x90, // 210: sprite
+	}
+	want := `; 19 bytes from 0x200, disassembled as CHIP-8
+
+start:
+    CLS                         ; 200: 00E0
+    LD I, data_210              ; 202: A210
+
+label_204:                      ; jumped to from 206
+    CALL sub_20A                ; 204: 220A
+    JP label_204                ; 206: 1204
+    db 0b11111111               ; 208: ########
+    db 0b11111111               ; 209: ########
+
+sub_20A:                        ; called from 204
+    SE V0, 0x01                 ; 20A: 3001
+    LD V0, 0x05                 ; 20C: 6005
+    RET                         ; 20E: 00EE
+
+data_210:                       ; used by 202
+    db 0b11110000               ; 210: ####....
+    db 0b10010000               ; 211: #..#....
+    db 0b10010000               ; 212: #..#....
+`
+	assert.Equal(t, want, Disassemble(rom, Options{}).String())
+}
+
+// code returns the addresses a listing found code at
+func code(l *Listing) []uint16 {
+	var addrs []uint16
+	for _, line := range l.Lines {
+		if line.Instruction != nil {
+			addrs = append(addrs, line.Addr)
+		}
+	}
+	return addrs
+}
+
+func TestDisassemble_flow(t *testing.T) {
+	tests := []struct {
+		name    string
+		rom     []byte
+		options Options
+		code    []uint16
+	}{
+		{
+			"skips follow both ways",
+			[]byte{0x30, 0x01, 0x12, 0x08, 0x00, 0xEE, 0xFF, 0xFF, 0x00, 0xEE},
+			Options{},
+			[]uint16{0x200, 0x202, 0x204, 0x208},
+		},
+		{
+			"skips over long loads on XO-CHIP",
+			[]byte{0xE1, 0x9E, 0xF0, 0x00, 0x12, 0x34, 0x00, 0xEE},
+			Options{Mode: cpu.ModeXOChip},
+			[]uint16{0x200, 0x202, 0x206},
+		},
+		{
+			"jump tables start at their base",
+			[]byte{0xB2, 0x04, 0xFF, 0xFF, 0x12, 0x00, 0x12, 0x04},
+			Options{},
+			[]uint16{0x200, 0x204, 0x206},
+		},
+		{
+			"unknown opcodes end the trace",
+			[]byte{0x60, 0x01, 0x00, 0x00, 0x60, 0x02},
+			Options{},
+			[]uint16{0x200},
+		},
+		{
+			"SUPER-CHIP exits end the trace",
+			[]byte{0x00, 0xFD, 0x60, 0x02},
+			Options{Mode: cpu.ModeSuperChip},
+			[]uint16{0x200},
+		},
+		{
+			"extra entries",
+			[]byte{0x00, 0xEE, 0xAA, 0xAA, 0x00, 0xEE},
+			Options{Entries: []uint16{0x204}},
+			[]uint16{0x200, 0x204},
+		},
+		{
+			"other origins",
+			[]byte{0x16, 0x02, 0x00, 0xEE},
+			Options{Origin: 0x600},
+			[]uint16{0x600, 0x602},
+		},
+		{
+			"jumps outside the ROM",
+			[]byte{0x13, 0x00},
+			Options{},
+			[]uint16{0x200},
+		},
+		{
+			"a truncated last instruction",
+			[]byte{0x60, 0x01, 0x60},
+			Options{},
+			[]uint16{0x200},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.code, code(Disassemble(tt.rom, tt.options)))
+		})
+	}
+}
+
+func TestDisassemble_text(t *testing.T) {
+	tests := []struct {
+		rom  []byte
+		mode cpu.Mode
+		want string
+	}{
+		{[]byte{0xD1, 0x2F}, cpu.ModeChip8, "DRW V1, V2, 15"},
+		{[]byte{0xF3, 0x55}, cpu.ModeChip8, "LD [I], V3"},
+		{[]byte{0xB3, 0x00}, cpu.ModeChip8, "JP V0, 0x300"},
+		{[]byte{0x00, 0xC4}, cpu.ModeSuperChip, "SCD 4"},
+		{[]byte{0xF2, 0x01}, cpu.ModeXOChip, "PLANE 2"},
+		{[]byte{0xF0, 0x00, 0xAB, 0xCD}, cpu.ModeXOChip, "LD I, LONG 0xABCD"},
+		{[]byte{0xF0, 0x00, 0x02, 0x04, 0x00, 0xEE}, cpu.ModeXOChip, "LD I, LONG label_204"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			l := Disassemble(tt.rom, Options{Mode: tt.mode})
+			assert.Equal(t, tt.want, l.Lines[0].Text)
+		})
+	}
+}
+
+func TestDisassemble_xrefs(t *testing.T) {
+	rom := []byte{
+		0x22, 0x08, // 200: CALL 208
+		0x22, 0x08, // 202: CALL 208
+		0x12, 0x08, // 204: JP 208
+		0x00, 0x00, // 206
+		0x00, 0xEE, // 208: RET
+	}
+	l := Disassemble(rom, Options{})
+	assert.Equal(t, "sub_208", l.Labels[0x208])
+	assert.Equal(t, []Xref{{0x200, XrefCall}, {0x202, XrefCall}, {0x204, XrefJump}}, l.Xrefs[0x208])
+	assert.Contains(t, l.String(), "sub_208:                        ; called from 200, 202; jumped to from 204\n")
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisasmCommand(t *testing.T) {
+	dir := t.TempDir()
+	rom := filepath.Join(dir, "spin.ch8")
+	// LD V0, 5 then spin
+	assert.NoError(t, os.WriteFile(rom, []byte{0x60, 0x05, 0x12, 0x02}, 0644))
+
+	var stdout, stderr strings.Builder
+	assert.Equal(t, 0, disasmCommand([]string{rom}, &stdout, &stderr), stderr.String())
+	assert.Contains(t, stdout.String(), "; 4 bytes from 0x200")
+	assert.Contains(t, stdout.String(), "; 200: 6005")
+
+	out := filepath.Join(dir, "spin.s")
+	assert.Equal(t, 0, disasmCommand([]string{rom, "-o", out, "-origin", "0x600"}, &stdout, &stderr), stderr.String())
+	listing, err := os.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Contains(t, string(listing), "; 600: 6005")
+
+	stderr.Reset()
+	assert.Equal(t, 2, disasmCommand([]string{"-origin", "start", rom}, &stdout, &stderr))
+	assert.Equal(t, "bad origin \"start\"\n", stderr.String())
+}
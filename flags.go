@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/Nuxij/goch8p/cpu"
+	"github.com/Nuxij/goch8p/octo"
+	"github.com/Nuxij/goch8p/terminal"
+)
+
+// machineFlags are the flags describing the machine a ROM is written
+// for, shared by every command reading one
+type machineFlags struct {
+	flags  *flag.FlagSet
+	mode   *string
+	quirks *string
+	origin *string
+}
+
+func newMachineFlags(flags *flag.FlagSet) *machineFlags {
+	return &machineFlags{
+		flags:  flags,
+		mode:   flags.String("mode", "chip8", "instruction set: chip8, schip or xochip"),
+		quirks: flags.String("quirks", "", "quirk preset: vip, chip48, schip or xochip, the mode's own by default"),
+		origin: flags.String("origin", "0x200", "address the ROM is loaded at"),
+	}
+}
+
+// platform is the machine machineFlags name, once checked
+type platform struct {
+	Mode   cpu.Mode
+	Quirks cpu.Quirks
+	Origin uint16
+}
+
+// modeQuirks is the quirk preset of the machine each mode comes from,
+// which -quirks defaults to
+var modeQuirks = map[cpu.Mode]string{
+	cpu.ModeChip8:     "vip",
+	cpu.ModeSuperChip: "schip",
+	cpu.ModeXOChip:    "xochip",
+}
+
+// set reports whether the flag name was given rather than left as the
+// default
+func (m *machineFlags) set(name string) bool {
+	set := false
+	m.flags.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
+
+// parse checks the flags, with an error naming the first bad one
+func (m *machineFlags) parse() (platform, error) {
+	mode, ok := cpu.Modes[*m.mode]
+	if !ok {
+		return platform{}, fmt.Errorf("unknown mode %q", *m.mode)
+	}
+	preset := *m.quirks
+	if !m.set("quirks") {
+		preset = modeQuirks[mode]
+	}
+	quirks, ok := cpu.QuirkPresets[preset]
+	if !ok {
+		return platform{}, fmt.Errorf("unknown quirk preset %q", preset)
+	}
+	origin, err := strconv.ParseUint(*m.origin, 0, 16)
+	if err != nil {
+		return platform{}, fmt.Errorf("bad origin %q", *m.origin)
+	}
+	return platform{mode, quirks, uint16(origin)}, nil
+}
+
+// newCPU builds a CPU for the machine with rom loaded at its origin
+func (p platform) newCPU(rom []byte, options ...cpu.Option) (*cpu.CPU, error) {
+	options = append([]cpu.Option{cpu.WithMode(p.Mode), cpu.WithQuirks(p.Quirks)}, options...)
+	c := cpu.NewCPU(cpu.NewRAM(p.Mode.MemorySize()), options...)
+	if err := c.Memory().Poke(p.Origin, rom); err != nil {
+		return nil, err
+	}
+	if p.Origin != cpu.DefaultOrigin {
+		c.SetPC(p.Origin)
+	}
+	return c, nil
+}
+
+// load builds a CPU for the machine with the ROM at path loaded,
+// compiling it first if it is Octo source, in which case the program is
+// returned too for the breakpoints and monitors it declares
+func (p platform) load(path string, options ...cpu.Option) (*cpu.CPU, *octo.Program, error) {
+	if filepath.Ext(path) != ".8o" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		c, err := p.newCPU(data, options...)
+		return c, nil, err
+	}
+	program, err := octo.CompileFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := p.newCPU(nil, options...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := c.Memory().Poke(program.Origin, program.ROM); err != nil {
+		return nil, nil, err
+	}
+	return c, program, nil
+}
+
+// playFlags are the flags for playing a ROM, shared by the frontends
+type playFlags struct {
+	speed *int
+	scale *int
+	keys  *string
+}
+
+func newPlayFlags(flags *flag.FlagSet) *playFlags {
+	return &playFlags{
+		speed: flags.Int("speed", cpu.DefaultInstructionsPerFrame, "instructions per frame"),
+		scale: flags.Int("scale", 1, "size of each pixel of the screen"),
+		keys:  flags.String("keys", terminal.DefaultLayout, "keyboard keys for the keypad keys 0 to F"),
+	}
+}
+
+// keymap parses the key layout
+func (p *playFlags) keymap() (terminal.Keymap, error) {
+	return terminal.ParseLayout(*p.keys)
+}
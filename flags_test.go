@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Nuxij/goch8p/cpu"
+)
+
+func TestMachineFlags_quirks(t *testing.T) {
+	for _, test := range []struct {
+		args   []string
+		quirks cpu.Quirks
+	}{
+		{nil, cpu.QuirksCOSMACVIP},
+		{[]string{"-mode", "schip"}, cpu.QuirksSuperChip},
+		{[]string{"-mode", "xochip"}, cpu.QuirksXOChip},
+		{[]string{"-mode", "xochip", "-quirks", "vip"}, cpu.QuirksCOSMACVIP},
+	} {
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		machineFlags := newMachineFlags(flags)
+		assert.NoError(t, flags.Parse(test.args))
+		m, err := machineFlags.parse()
+		assert.NoError(t, err, test.args)
+		assert.Equal(t, test.quirks, m.Quirks, "-quirks should default to the mode's own: %v", test.args)
+	}
+}
@@ -0,0 +1,135 @@
+//go:build imgui
+// +build imgui
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/AllenDang/giu"
+
+	"github.com/Nuxij/goch8p/cpu"
+	"github.com/Nuxij/goch8p/gfx"
+	"github.com/Nuxij/goch8p/machine"
+)
+
+func init() {
+	frontends["imgui"] = playWindow
+}
+
+// playWindow plays the session in a window until it is closed. The CPU
+// runs a frame every 1/TimerRate seconds on its own goroutine, taking the
+// keys the window last saw held, or rewinds one while backspace is held.
+func playWindow(s session) (runErr, err error) {
+	screen := &gfx.ImScreen{
+		Window: giu.NewMasterWindow("goch8p: "+filepath.Base(s.rom), 1280, 720, 0),
+		// the window's pixels are small, so each scale is 16 of them
+		Scale: 16 * s.scale,
+	}
+	var (
+		mu   sync.Mutex
+		down [cpu.Keys]bool
+		// rewinding counts down the frames to rewind rather than run,
+		// refreshed each time the window sees backspace held
+		rewinding int
+	)
+	screen.Controls.Rewind = func() {
+		mu.Lock()
+		defer mu.Unlock()
+		rewinding = 2
+	}
+	screen.Controls.Keypad = func(held func(key rune) bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		for r, key := range s.keymap {
+			down[key] = held(r)
+		}
+	}
+	if err := screen.Init(64, 32); err != nil {
+		return nil, err
+	}
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(time.Second / cpu.TimerRate)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			mu.Lock()
+			for key, d := range down {
+				if d {
+					s.cpu.Keypad().Press(byte(key))
+				} else {
+					s.cpu.Keypad().Release(byte(key))
+				}
+			}
+			rewind := rewinding > 0
+			if rewind {
+				rewinding--
+			}
+			mu.Unlock()
+			var err error
+			if rewind {
+				// with no history the machine just stays paused
+				var nothing cpu.NothingToRewind
+				if err = s.cpu.Rewind(1); errors.As(err, &nothing) {
+					err = nil
+				}
+			} else {
+				err = s.cpu.RunFrame()
+			}
+			screen.Update(windowInfo(s.cpu, err == nil), windowPixels(s.cpu.Frame()))
+			if err != nil {
+				runErr = err
+				return
+			}
+		}
+	}()
+	err = screen.Start()
+	close(stop)
+	<-stopped
+	return runErr, err
+}
+
+// windowInfo describes c as the window shows it
+func windowInfo(c *cpu.CPU, running bool) machine.Ch8pInfo {
+	r := c.Registers()
+	info := machine.Ch8pInfo{
+		Name:     "goch8p",
+		Tick:     uint16(c.Frame().Number),
+		PC:       r.PC,
+		V:        machine.Registers(r.V),
+		I:        r.I,
+		DrawFlag: true,
+		Running:  running,
+	}
+	info.RAM, _ = c.Memory().Peek(0, 0x1000)
+	if opcode, err := c.Memory().Peek(r.PC, 2); err == nil {
+		info.Opcode = fmt.Sprintf("%02X%02X", opcode[0], opcode[1])
+	}
+	for _, addr := range c.CallStack() {
+		info.Stack.Push(addr)
+	}
+	return info
+}
+
+// windowPixels samples f down to the window's 64x32, a byte per pixel
+func windowPixels(f cpu.Frame) []byte {
+	pixels := make([]byte, 64*32)
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 64; x++ {
+			if f.Pixel(uint16(x)*f.Width/64, uint16(y)*f.Height/32) {
+				pixels[y*64+x] = 1
+			}
+		}
+	}
+	return pixels
+}
@@ -14,4 +14,14 @@ type Display interface {
 	Init(width, height int) error
 	Start() error
 	Update(info machine.Ch8pInfo, pixels []byte)
-}
\ No newline at end of file
+}
+
+// Controls are the emulator actions frontends bind keys to
+type Controls struct {
+	// Rewind is called once a frame while the rewind key, backspace, is
+	// held, and would usually call CPU.Rewind(1)
+	Rewind func()
+	// Keypad is called once a frame with down, which reports whether a
+	// keyboard key, named by its character, is held
+	Keypad func(down func(key rune) bool)
+}
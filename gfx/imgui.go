@@ -3,6 +3,7 @@ package gfx
 import (
 	"image"
 	"image/color"
+	"unicode"
 
 	"github.com/AllenDang/giu"
 	"github.com/Nuxij/goch8p/machine"
@@ -18,6 +19,9 @@ type ImScreen struct {
 	texture *giu.Texture
 	memoryWidget *giu.MemoryEditorWidget
 	Shortcuts []giu.WindowShortcut
+	Controls Controls
+	// Scale sizes each pixel of the screen, 16 if zero
+	Scale int
 }
 
  func (s *ImScreen) Init(width, height int) error {
@@ -48,6 +52,18 @@ func (s *ImScreen) Start() error {
 }
 
 func (s *ImScreen) Draw() {
+	if s.Controls.Rewind != nil && giu.IsKeyDown(giu.KeyBackspace) {
+		s.Controls.Rewind()
+	}
+	if s.Controls.Keypad != nil {
+		s.Controls.Keypad(func(key rune) bool {
+			return giu.IsKeyDown(giu.Key(unicode.ToUpper(key)))
+		})
+	}
+	scale := s.Scale
+	if scale == 0 {
+		scale = 16
+	}
 	stack := []interface{}{}
 	stackPointer := s.info.Stack[len(s.info.Stack)-1]
 	for i := 0; i < 16; i++ {
@@ -77,7 +93,7 @@ func (s *ImScreen) Draw() {
 				}),
 				
 				giu.Child().Layout(
-					giu.Image(s.texture).Size(64*16, 32*16),
+					giu.Image(s.texture).Size(float32(64*scale), float32(32*scale)),
 					giu.Custom(func() {
 						if s.info.Running {
 							giu.Label("RUNNING")
@@ -36,18 +36,21 @@ type Firmware struct {
 	ram           machine.Memory
 	info     chan machine.Ch8pInfo
 	events   chan tea.Msg
+	controls Controls
 }
 
 type TeaScreen struct {
 	width    , height int
 	firmware *Firmware
 	mug *tea.Program
+	Controls Controls
 }
 
 func (t *TeaScreen) Init(width, height int) error {
 	t.width = width
 	t.height = height
 	t.firmware = NewFirmware(width, height)
+	t.firmware.controls = t.Controls
 	t.mug = tea.NewProgram(t.firmware, tea.WithMouseCellMotion())
 	return nil
 }
@@ -72,7 +75,7 @@ func NewFirmware(width, height int) *Firmware {
 		info:   make(chan machine.Ch8pInfo, 1),
 	}
 	for i := 0; i < fw.width*fw.height; i++ {
-		fw.ram.WriteByte(uint16(i), 9)
+		fw.ram.Write8(uint16(i), 9)
 	}
 	return fw
 }
@@ -89,16 +92,21 @@ func (fw *Firmware) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if k := msg.String(); k == "ctrl+c" || k == "q" || k == "esc" {
 				return fw, tea.Quit
 			}
+			// terminals send no key releases, so holding rewinds by
+			// the key repeating
+			if msg.Type == tea.KeyBackspace && fw.controls.Rewind != nil {
+				fw.controls.Rewind()
+			}
 		// Is it a key press?
 		case PixelMsg:
 			fw.info <- msg.Info
 			for i, b := range msg.Pixels {
-				fw.ram.WriteByte(uint16(i), b)
+				fw.ram.Write8(uint16(i), b)
 			}
 		case tea.MouseMsg:
 			// left click
 			if msg.Type == tea.MouseLeft {
-				fw.ram.WriteByte(uint16(msg.X+msg.Y*fw.width), 0xFF)
+				fw.ram.Write8(uint16(msg.X+msg.Y*fw.width), 0xFF)
 			}
 	}
 
@@ -117,7 +125,7 @@ func mapView(fw *Firmware) string {
 	var s string
 	for y := 0; y < fw.height; y++ {
 		for x := 0; x < fw.width; x++ {
-			s += fmt.Sprintf("%v", fw.ram.ReadByte(uint16(x+y*fw.width)))
+			s += fmt.Sprintf("%v", fw.ram.Read8(uint16(x+y*fw.width)))
 		}
 		s += "\n"
 	}
@@ -0,0 +1,106 @@
+// Package headless runs ROMs with no display or terminal, for checking
+// their behaviour in pipelines: a set number of frames, then the screen
+// as an image and the machine's state as JSON
+package headless
+
+import (
+	"encoding/hex"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+
+	"github.com/Nuxij/goch8p/cpu"
+	"github.com/Nuxij/goch8p/trace"
+)
+
+// Palette colours the pixels of a frame by their bit per plane, so the
+// first plane is white and XO-CHIP's second adds the greys
+var Palette = color.Palette{
+	color.Gray{0x00},
+	color.Gray{0xFF},
+	color.Gray{0xAA},
+	color.Gray{0x55},
+}
+
+// Run runs frames frames, stopping short at the first error, which it
+// returns. A program exiting with 00FD returns a cpu.Halted error.
+func Run(c *cpu.CPU, frames int) error {
+	for n := 0; n < frames; n++ {
+		if err := c.RunFrame(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Image draws f scaled up by scale, coloured from Palette
+func Image(f cpu.Frame, scale int) *image.Paletted {
+	if scale < 1 {
+		scale = 1
+	}
+	img := image.NewPaletted(image.Rect(0, 0, int(f.Width)*scale, int(f.Height)*scale), Palette)
+	for y := 0; y < int(f.Height); y++ {
+		for x := 0; x < int(f.Width); x++ {
+			c := f.Color(uint16(x), uint16(y))
+			if c == 0 {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(x*scale+dx, y*scale+dy, c)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// WritePNG writes f as a PNG scaled up by scale
+func WritePNG(w io.Writer, f cpu.Frame, scale int) error {
+	return png.Encode(w, Image(f, scale))
+}
+
+// Dump is the state of a machine after a run, for writing as JSON
+type Dump struct {
+	Mode string `json:"mode"`
+	// Frames counts the frames finished
+	Frames    uint64          `json:"frames"`
+	Registers trace.Registers `json:"registers"`
+	// Stack holds the return addresses, outermost first
+	Stack []uint16 `json:"stack"`
+	// Memory is the whole of memory in hex
+	Memory string `json:"memory"`
+	// Halted says the program exited, and Error is any fault that
+	// stopped it
+	Halted bool   `json:"halted"`
+	Error  string `json:"error,omitempty"`
+}
+
+// NewDump captures c's state, with err the error its run ended with
+func NewDump(c *cpu.CPU, err error) Dump {
+	d := Dump{
+		Mode:      c.Mode().String(),
+		Frames:    c.Frame().Number,
+		Registers: trace.Registers(c.Registers()),
+		Stack:     c.CallStack(),
+		Halted:    cpu.IsHalted(err),
+	}
+	if d.Stack == nil {
+		d.Stack = []uint16{}
+	}
+	// read in pages, as a read cannot span the whole 64KiB
+	var memory []byte
+	for start := 0; start < c.Mode().MemorySize(); start += 0x1000 {
+		page, merr := c.Memory().Peek(uint16(start), 0x1000)
+		if merr != nil {
+			break
+		}
+		memory = append(memory, page...)
+	}
+	d.Memory = hex.EncodeToString(memory)
+	if err != nil && !d.Halted {
+		d.Error = err.Error()
+	}
+	return d
+}
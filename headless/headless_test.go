@@ -0,0 +1,71 @@
+package headless
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Nuxij/goch8p/cpu"
+)
+
+// glyph draws the 0 glyph at 5,5 then spins
+var glyph = []byte{0x60, 0x05, 0xF0, 0x29, 0xD0, 0x05, 0x12, 0x06}
+
+func load(t *testing.T, program []byte, options ...cpu.Option) *cpu.CPU {
+	c := cpu.NewCPU(cpu.NewRAM(0x10000), options...)
+	assert.NoError(t, c.Memory().Poke(0x200, program))
+	return c
+}
+
+func TestRun(t *testing.T) {
+	c := load(t, glyph)
+	assert.NoError(t, Run(c, 3))
+	assert.EqualValues(t, 3, c.Frame().Number)
+
+	// LD V0, 1; 0000
+	c = load(t, []byte{0x60, 0x01, 0x00, 0x00})
+	assert.Equal(t, cpu.InstructionUnknown{}, Run(c, 3))
+
+	// EXIT
+	c = load(t, []byte{0x00, 0xFD}, cpu.WithMode(cpu.ModeSuperChip))
+	assert.True(t, cpu.IsHalted(Run(c, 3)))
+}
+
+func TestWritePNG(t *testing.T) {
+	c := load(t, glyph)
+	assert.NoError(t, Run(c, 1))
+	var b bytes.Buffer
+	assert.NoError(t, WritePNG(&b, c.Frame(), 2))
+	img, err := png.Decode(&b)
+	assert.NoError(t, err)
+	assert.Equal(t, 128, img.Bounds().Dx())
+	assert.Equal(t, 64, img.Bounds().Dy())
+	r, _, _, _ := img.At(11, 11).RGBA()
+	assert.EqualValues(t, 0xFFFF, r, "the glyph's corner should be white")
+	r, _, _, _ = img.At(13, 13).RGBA()
+	assert.EqualValues(t, 0, r, "the glyph's middle should be black")
+}
+
+func TestNewDump(t *testing.T) {
+	c := load(t, []byte{0x60, 0x01, 0x22, 0x06, 0x00, 0x00, 0x00, 0x00})
+	err := Run(c, 1)
+	d := NewDump(c, err)
+	assert.Equal(t, "CHIP-8", d.Mode)
+	assert.EqualValues(t, 0, d.Frames)
+	assert.EqualValues(t, 1, d.Registers.V[0])
+	assert.EqualValues(t, 0x208, d.Registers.PC)
+	assert.Equal(t, []uint16{0x204}, d.Stack)
+	assert.Equal(t, "unknown instruction: 0", d.Error)
+	assert.False(t, d.Halted)
+	assert.Equal(t, 0x2000, len(d.Memory))
+	assert.True(t, strings.HasPrefix(d.Memory[0x400:], "600122060000"))
+
+	c = load(t, []byte{0x00, 0xFD}, cpu.WithMode(cpu.ModeXOChip))
+	d = NewDump(c, Run(c, 1))
+	assert.True(t, d.Halted)
+	assert.Empty(t, d.Error)
+	assert.Equal(t, 0x20000, len(d.Memory))
+}
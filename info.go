@@ -0,0 +1,131 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Nuxij/goch8p/cpu"
+	"github.com/Nuxij/goch8p/disasm"
+)
+
+// romInfo describes a ROM, as infoCommand prints it
+type romInfo struct {
+	Name   string `json:"name"`
+	Size   int    `json:"size"`
+	SHA1   string `json:"sha1"`
+	Origin uint16 `json:"origin"`
+	// Mode is the mode given, or else the oldest mode with every
+	// instruction traced
+	Mode string `json:"mode"`
+	// Code counts the bytes traced to instructions, and Data the rest
+	Code         int      `json:"code"`
+	Instructions int      `json:"instructions"`
+	Data         int      `json:"data"`
+	Subroutines  []string `json:"subroutines"`
+}
+
+// guessModes are the modes a ROM might be written for, oldest first
+var guessModes = []cpu.Mode{cpu.ModeChip8, cpu.ModeSuperChip, cpu.ModeXOChip}
+
+// describe traces rom as the newest of modes, and reports the oldest of
+// them with every instruction found. Given guessModes it traces as
+// XO-CHIP, which every other mode is a subset of, guessing the mode.
+func describe(name string, rom []byte, origin uint16, modes []cpu.Mode) romInfo {
+	sum := sha1.Sum(rom)
+	info := romInfo{
+		Name:        name,
+		Size:        len(rom),
+		SHA1:        hex.EncodeToString(sum[:]),
+		Origin:      origin,
+		Subroutines: []string{},
+	}
+	guess := 0
+	if int(origin)+len(rom) > modes[0].MemorySize() {
+		guess = len(modes) - 1
+	}
+	decoders := make([]*cpu.Decoder, len(modes))
+	for n, m := range modes {
+		decoders[n] = m.Decoder()
+	}
+	listing := disasm.Disassemble(rom, disasm.Options{Mode: modes[len(modes)-1], Origin: origin})
+	for _, line := range listing.Lines {
+		if line.Instruction == nil {
+			info.Data += len(line.Bytes)
+			continue
+		}
+		info.Code += len(line.Bytes)
+		info.Instructions++
+		opcode := uint16(line.Bytes[0])<<8 | uint16(line.Bytes[1])
+		for guess < len(modes)-1 {
+			if _, ok := decoders[guess].Decode(opcode); ok {
+				break
+			}
+			guess++
+		}
+	}
+	info.Mode = modes[guess].String()
+	for addr, xrefs := range listing.Xrefs {
+		for _, x := range xrefs {
+			if x.Kind == disasm.XrefCall {
+				info.Subroutines = append(info.Subroutines, listing.Labels[addr])
+				break
+			}
+		}
+	}
+	sort.Strings(info.Subroutines)
+	return info
+}
+
+// infoCommand prints what can be told about a ROM without running it,
+// guessing its mode unless -mode is given
+func infoCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("info", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	machineFlags := newMachineFlags(flags)
+	asJSON := flags.Bool("json", false, "print the information as JSON")
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: goch8p info [flags] rom")
+		flags.PrintDefaults()
+		return 2
+	}
+	m, err := machineFlags.parse()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	rom := positional[0]
+	data, err := os.ReadFile(rom)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	modes := guessModes
+	if machineFlags.set("mode") {
+		modes = []cpu.Mode{m.Mode}
+	}
+	info := describe(filepath.Base(rom), data, m.Origin, modes)
+	if *asJSON {
+		out, _ := json.MarshalIndent(info, "", "  ")
+		fmt.Fprintf(stdout, "%s\n", out)
+		return 0
+	}
+	fmt.Fprintf(stdout, "name:         %s\n", info.Name)
+	fmt.Fprintf(stdout, "size:         %d bytes at 0x%03X\n", info.Size, info.Origin)
+	fmt.Fprintf(stdout, "sha1:         %s\n", info.SHA1)
+	fmt.Fprintf(stdout, "mode:         %s\n", info.Mode)
+	fmt.Fprintf(stdout, "code:         %d bytes in %d instructions\n", info.Code, info.Instructions)
+	fmt.Fprintf(stdout, "data:         %d bytes\n", info.Data)
+	fmt.Fprintf(stdout, "subroutines:  %d %v\n", len(info.Subroutines), info.Subroutines)
+	return 0
+}
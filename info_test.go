@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Nuxij/goch8p/cpu"
+)
+
+func TestDescribe(t *testing.T) {
+	// CALL 0x206; JP 0x202; a byte of data; RET
+	chip8 := []byte{0x22, 0x06, 0x12, 0x02, 0xFF, 0xFF, 0x00, 0xEE}
+	info := describe("sub.ch8", chip8, 0x200, guessModes)
+	assert.Equal(t, cpu.ModeChip8.String(), info.Mode)
+	assert.Equal(t, 6, info.Code)
+	assert.Equal(t, 3, info.Instructions)
+	assert.Equal(t, 2, info.Data)
+	assert.Equal(t, []string{"sub_206"}, info.Subroutines)
+	assert.Equal(t, "37c124f311deb8d14185b60e49d57d8275c23d4d", info.SHA1)
+
+	// HIGH then spin
+	assert.Equal(t, cpu.ModeSuperChip.String(), describe("hires.ch8", []byte{0x00, 0xFF, 0x12, 0x02}, 0x200, guessModes).Mode)
+	// PLANE 3, HIGH then spin: the newest instruction decides
+	assert.Equal(t, cpu.ModeXOChip.String(), describe("planes.ch8", []byte{0xF3, 0x01, 0x00, 0xFF, 0x12, 0x04}, 0x200, guessModes).Mode)
+}
+
+func TestInfoCommand(t *testing.T) {
+	rom := filepath.Join(t.TempDir(), "spin.ch8")
+	assert.NoError(t, os.WriteFile(rom, []byte{0x12, 0x00}, 0644))
+
+	var stdout, stderr strings.Builder
+	assert.Equal(t, 0, infoCommand([]string{rom}, &stdout, &stderr), stderr.String())
+	assert.Contains(t, stdout.String(), "name:         spin.ch8\n")
+	assert.Contains(t, stdout.String(), "code:         2 bytes in 1 instructions\n")
+
+	stdout.Reset()
+	assert.Equal(t, 0, infoCommand([]string{"-json", rom}, &stdout, &stderr), stderr.String())
+	var info romInfo
+	assert.NoError(t, json.Unmarshal([]byte(stdout.String()), &info))
+	assert.Equal(t, 2, info.Size)
+	assert.EqualValues(t, 0x200, info.Origin)
+	assert.Empty(t, info.Subroutines)
+
+	// flags go anywhere, and a mode given is used rather than guessed
+	stdout.Reset()
+	assert.Equal(t, 0, infoCommand([]string{rom, "-json", "-mode", "schip", "-origin", "0x300"}, &stdout, &stderr), stderr.String())
+	assert.NoError(t, json.Unmarshal([]byte(stdout.String()), &info))
+	assert.Equal(t, cpu.ModeSuperChip.String(), info.Mode)
+	assert.EqualValues(t, 0x300, info.Origin)
+}
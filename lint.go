@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Nuxij/goch8p/lint"
+)
+
+// lintCommand reports likely bugs in a ROM, exiting 1 if any is an error
+func lintCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	machineFlags := newMachineFlags(flags)
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: goch8p lint [flags] rom")
+		flags.PrintDefaults()
+		return 2
+	}
+	m, err := machineFlags.parse()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	rom := positional[0]
+	data, err := os.ReadFile(rom)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	findings := lint.Lint(data, lint.Options{Mode: m.Mode, Quirks: m.Quirks, Origin: m.Origin})
+	errors := 0
+	for _, f := range findings {
+		fmt.Fprintf(stdout, "%s:%s\n", rom, f)
+		if f.Severity == lint.Error {
+			errors++
+		}
+	}
+	if len(findings) > 0 {
+		fmt.Fprintf(stderr, "%d errors, %d warnings\n", errors, len(findings)-errors)
+	}
+	if lint.HasErrors(findings) {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,579 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Nuxij/goch8p/cpu"
+)
+
+// value is what the analysis knows of a register: its value if it is
+// the same on every path, or nothing
+type value struct {
+	known bool
+	v     uint16
+}
+
+func known(v uint16) value {
+	return value{true, v}
+}
+
+// join is what is known of a register that may hold a or b
+func (a value) join(b value) value {
+	if a == b {
+		return a
+	}
+	return value{}
+}
+
+// state is what the analysis knows of the registers at a point
+type state struct {
+	v [16]value
+	i value
+}
+
+func (s state) join(o state) state {
+	for x := range s.v {
+		s.v[x] = s.v[x].join(o.v[x])
+	}
+	s.i = s.i.join(o.i)
+	return s
+}
+
+// point is an address reached inside a subroutine, each subroutine being
+// analysed apart so every return goes back to its own callers
+type point struct {
+	sub uint16
+	pc  uint16
+}
+
+// write is memory an instruction writes, from to before end
+type write struct {
+	pc   uint16
+	from uint16
+	end  int
+	// what names the instruction, such as FX55
+	what string
+}
+
+type analysis struct {
+	rom     []byte
+	origin  uint16
+	mode    cpu.Mode
+	quirks  cpu.Quirks
+	decoder *cpu.Decoder
+	size    int
+
+	states map[point]state
+	queue  []point
+	// exits is the join of the states each subroutine returns with
+	exits map[uint16]state
+	// callers lists where each subroutine is called from
+	callers map[uint16][]point
+	// calls maps each subroutine to those it calls, and where from
+	calls map[uint16]map[uint16]uint16
+
+	// code holds each instruction reached, and from where it was first
+	// reached
+	code   map[uint16]cpu.Instruction
+	from   map[uint16]uint16
+	refs   map[uint16]bool
+	writes []write
+
+	findings []Finding
+	reported map[string]bool
+}
+
+func newAnalysis(rom []byte, options Options) *analysis {
+	return &analysis{
+		rom:      rom,
+		origin:   options.Origin,
+		mode:     options.Mode,
+		quirks:   options.Quirks,
+		decoder:  options.Mode.Decoder(),
+		size:     options.Mode.MemorySize(),
+		states:   make(map[point]state),
+		exits:    make(map[uint16]state),
+		callers:  make(map[uint16][]point),
+		calls:    make(map[uint16]map[uint16]uint16),
+		code:     make(map[uint16]cpu.Instruction),
+		from:     make(map[uint16]uint16),
+		refs:     make(map[uint16]bool),
+		reported: make(map[string]bool),
+	}
+}
+
+// report adds a finding, once however often the analysis comes across it
+func (a *analysis) report(addr uint16, severity Severity, check Check, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	key := fmt.Sprintf("%X %s %s", addr, check, message)
+	if a.reported[key] {
+		return
+	}
+	a.reported[key] = true
+	a.findings = append(a.findings, Finding{addr, severity, check, message})
+}
+
+// word returns the opcode at addr, if the ROM holds one there
+func (a *analysis) word(addr uint16) (uint16, bool) {
+	return cpu.ROMWord(a.rom, a.origin, addr)
+}
+
+// decode returns the instruction at addr and how long it is
+func (a *analysis) decode(addr uint16) (cpu.Instruction, uint16, bool) {
+	return cpu.DecodeROM(a.decoder, a.rom, a.origin, addr)
+}
+
+// flow joins s into what is known at p from the instruction at from,
+// analysing p again if that tells it anything new
+func (a *analysis) flow(p point, s state, from uint16) {
+	if old, ok := a.states[p]; ok {
+		if s = old.join(s); s == old {
+			return
+		}
+	}
+	if _, ok := a.from[p.pc]; !ok {
+		a.from[p.pc] = from
+	}
+	a.states[p] = s
+	a.queue = append(a.queue, p)
+}
+
+// run follows every path from the origin until nothing more is learnt
+func (a *analysis) run() {
+	a.flow(point{a.origin, a.origin}, state{}, a.origin)
+	for len(a.queue) > 0 {
+		p := a.queue[len(a.queue)-1]
+		a.queue = a.queue[:len(a.queue)-1]
+		a.step(p, a.states[p])
+	}
+}
+
+// step interprets the instruction at p in state s, flowing on to each
+// instruction that can follow it
+func (a *analysis) step(p point, s state) {
+	i, length, ok := a.decode(p.pc)
+	if !ok {
+		what := "is not a valid instruction"
+		if _, in := a.word(p.pc); !in {
+			what = "is outside the ROM"
+		}
+		a.report(p.pc, Error, CheckInvalid, "execution reaches 0x%03X, from 0x%03X, which %s", p.pc, a.from[p.pc], what)
+		return
+	}
+	a.code[p.pc] = i
+	op, _ := a.word(p.pc)
+	x, y := op>>8&0xF, op>>4&0xF
+	nn, nnn := op&0xFF, op&0xFFF
+	next := point{p.sub, p.pc + length}
+
+	switch i.Code() {
+	case 0x00EE:
+		a.ret(p, s)
+		return
+	case 0x00FD:
+		return
+	case 0x1000:
+		a.flow(point{p.sub, nnn}, s, p.pc)
+		return
+	case 0x2000:
+		a.call(p, s, nnn)
+		return
+	case 0xB000:
+		a.jumpTable(p, s, op)
+		return
+	case 0x3000, 0x4000, 0x5000, 0x9000, 0xE09E, 0xE0A1:
+		a.skip(p, s, op, next)
+		return
+	case 0x6000:
+		s.v[x] = known(nn)
+	case 0x7000:
+		if s.v[x].known {
+			s.v[x] = known((s.v[x].v + nn) & 0xFF)
+		}
+	case 0x8000, 0x8001, 0x8002, 0x8003, 0x8004, 0x8005, 0x8006, 0x8007, 0x800E:
+		s = a.arithmetic(s, op)
+	case 0xA000:
+		s.i = known(nnn)
+		a.refs[nnn] = true
+	case 0xF000:
+		long, _ := a.word(p.pc + 2)
+		s.i = known(long)
+		a.refs[long] = true
+	case 0xC000, 0xF007, 0xF00A:
+		s.v[x] = value{}
+	case 0xF01E:
+		if s.i.known && s.v[x].known {
+			s.i = known(s.i.v + s.v[x].v)
+		} else {
+			s.i = value{}
+		}
+	case 0xF029:
+		s.i = value{}
+		if s.v[x].known {
+			s.i = known(cpu.FontAddress + s.v[x].v&0xF*uint16(len(cpu.Font{})))
+		}
+	case 0xF030:
+		s.i = value{}
+		if s.v[x].known {
+			s.i = known(cpu.BigFontAddress + s.v[x].v&0xF*uint16(len(cpu.BigFont{})))
+		}
+	case 0xF033:
+		a.access(p.pc, s, 3, "FX33")
+	case 0xF055:
+		a.access(p.pc, s, x+1, "FX55")
+		s.i = a.advance(s.i, x)
+	case 0xF065:
+		a.access(p.pc, s, x+1, "")
+		for r := uint16(0); r <= x; r++ {
+			s.v[r] = value{}
+		}
+		s.i = a.advance(s.i, x)
+	case 0x5002:
+		a.access(p.pc, s, span(x, y), "5XY2")
+	case 0x5003:
+		a.access(p.pc, s, span(x, y), "")
+		low, high := x, y
+		if low > high {
+			low, high = high, low
+		}
+		for r := low; r <= high; r++ {
+			s.v[r] = value{}
+		}
+	case 0xF002:
+		a.access(p.pc, s, 16, "")
+	case 0xF085:
+		for r := uint16(0); r <= x; r++ {
+			s.v[r] = value{}
+		}
+	case 0xD000:
+		rows := op & 0xF
+		if rows == 0 && a.mode >= cpu.ModeSuperChip {
+			rows = 32
+		}
+		a.access(p.pc, s, rows, "")
+		s.v[0xF] = value{}
+	}
+	a.flow(next, s, p.pc)
+}
+
+// span is how many registers VX to VY covers, either way round
+func span(x, y uint16) uint16 {
+	if x > y {
+		return x - y + 1
+	}
+	return y - x + 1
+}
+
+// advance moves I past V0 to VX as FX55 and FX65 do under the quirks
+func (a *analysis) advance(i value, x uint16) value {
+	if !i.known {
+		return i
+	}
+	switch a.quirks.Index {
+	case cpu.IndexToX:
+		return known(i.v + x)
+	case cpu.IndexUnchanged:
+		return i
+	default:
+		return known(i.v + x + 1)
+	}
+}
+
+// arithmetic interprets the 8XYN instructions
+func (a *analysis) arithmetic(s state, op uint16) state {
+	x, y := op>>8&0xF, op>>4&0xF
+	vx, vy := s.v[x], s.v[y]
+	both := vx.known && vy.known
+	result, flag := value{}, value{}
+	switch op & 0xF {
+	case 0x0:
+		s.v[x] = vy
+		return s
+	case 0x1, 0x2, 0x3:
+		if both {
+			switch op & 0xF {
+			case 0x1:
+				result = known(vx.v | vy.v)
+			case 0x2:
+				result = known(vx.v & vy.v)
+			case 0x3:
+				result = known(vx.v ^ vy.v)
+			}
+		}
+		if !a.quirks.LogicResetsVF {
+			s.v[x] = result
+			return s
+		}
+		flag = known(0)
+	case 0x4:
+		if both {
+			sum := vx.v + vy.v
+			result, flag = known(sum&0xFF), known(sum>>8)
+		}
+	case 0x5, 0x7:
+		if op&0xF == 0x7 {
+			vx, vy = vy, vx
+		}
+		if both {
+			result, flag = known((vx.v-vy.v)&0xFF), known(0)
+			if vx.v >= vy.v {
+				flag = known(1)
+			}
+		}
+	case 0x6, 0xE:
+		source := vy
+		if a.quirks.ShiftVX {
+			source = vx
+		}
+		if source.known {
+			if op&0xF == 0x6 {
+				result, flag = known(source.v>>1), known(source.v&1)
+			} else {
+				result, flag = known(source.v<<1&0xFF), known(source.v>>7)
+			}
+		}
+	}
+	s.v[x] = result
+	s.v[0xF] = flag
+	return s
+}
+
+// access checks the memory an instruction touches from I, named by what
+// if it writes
+func (a *analysis) access(pc uint16, s state, length uint16, what string) {
+	if !s.i.known {
+		return
+	}
+	end := int(s.i.v) + int(length)
+	if end > a.size {
+		a.report(pc, Error, CheckBounds, "I is 0x%03X, so %d bytes from it run past the end of memory at 0x%03X", s.i.v, length, a.size)
+		end = a.size
+	}
+	if what == "" {
+		return
+	}
+	if s.i.v < a.origin {
+		a.report(pc, Error, CheckFontWrite, "%s writes to 0x%03X, clobbering the font area below 0x%03X", what, s.i.v, a.origin)
+	}
+	a.writes = append(a.writes, write{pc, s.i.v, end, what})
+}
+
+// ret returns from the subroutine p is in to everywhere it is called from
+func (a *analysis) ret(p point, s state) {
+	if p.sub == a.origin {
+		a.report(p.pc, Error, CheckReturn, "00EE returns with nothing on the stack")
+		return
+	}
+	if old, ok := a.exits[p.sub]; ok {
+		if s = old.join(s); s == old {
+			return
+		}
+	}
+	a.exits[p.sub] = s
+	for _, site := range a.callers[p.sub] {
+		a.flow(point{site.sub, site.pc + 2}, s, p.pc)
+	}
+}
+
+// call enters the subroutine at target, carrying on after the call with
+// what the subroutine returns with once it is known to return
+func (a *analysis) call(p point, s state, target uint16) {
+	if a.calls[p.sub] == nil {
+		a.calls[p.sub] = make(map[uint16]uint16)
+	}
+	if _, ok := a.calls[p.sub][target]; !ok {
+		a.calls[p.sub][target] = p.pc
+	}
+	seen := false
+	for _, site := range a.callers[target] {
+		seen = seen || site == p
+	}
+	if !seen {
+		a.callers[target] = append(a.callers[target], p)
+	}
+	a.flow(point{target, target}, s, p.pc)
+	if exit, ok := a.exits[target]; ok {
+		a.flow(point{p.sub, p.pc + 2}, exit, p.pc)
+	}
+}
+
+// jumpTable follows BNNN, exactly if the register it adds is known and
+// otherwise to the base and the run of jumps after it, which is how jump
+// tables are usually laid out
+func (a *analysis) jumpTable(p point, s state, op uint16) {
+	base, offset := op&0xFFF, s.v[0]
+	if a.quirks.JumpVX {
+		offset = s.v[op>>8&0xF]
+	}
+	if offset.known {
+		a.flow(point{p.sub, base + offset.v}, s, p.pc)
+		return
+	}
+	a.flow(point{p.sub, base}, s, p.pc)
+	for entry := base + 2; ; entry += 2 {
+		if word, ok := a.word(entry); !ok || word&0xF000 != 0x1000 {
+			return
+		}
+		a.flow(point{p.sub, entry}, s, p.pc)
+	}
+}
+
+// skip follows both ways out of a conditional skip, or just the one taken
+// if the registers compared are known
+func (a *analysis) skip(p point, s state, op uint16, next point) {
+	skipped := next
+	if _, length, ok := a.decode(next.pc); ok {
+		skipped.pc += length
+	} else {
+		skipped.pc += 2
+	}
+	vx, vy := s.v[op>>8&0xF], s.v[op>>4&0xF]
+	taken, decided := false, false
+	switch op & 0xF000 {
+	case 0x3000, 0x4000:
+		if vx.known {
+			taken, decided = vx.v == op&0xFF, true
+		}
+	case 0x5000, 0x9000:
+		if vx.known && vy.known {
+			taken, decided = vx.v == vy.v, true
+		}
+	}
+	if op&0xF000 == 0x4000 || op&0xF000 == 0x9000 {
+		taken = !taken
+	}
+	if !decided || !taken {
+		a.flow(next, s, p.pc)
+	}
+	if !decided || taken {
+		a.flow(skipped, s, p.pc)
+	}
+}
+
+// checkOverlaps reports instructions reached inside other instructions
+func (a *analysis) checkOverlaps() {
+	for addr, i := range a.code {
+		for inside := addr + 1; inside < addr+cpu.Length(i); inside++ {
+			if _, ok := a.code[inside]; ok {
+				a.report(inside, Error, CheckMisaligned, "execution reaches 0x%03X, from 0x%03X, which is inside the instruction at 0x%03X", inside, a.from[inside], addr)
+			}
+		}
+	}
+}
+
+// checkDepth reports call chains deeper than the stack, and recursion
+func (a *analysis) checkDepth() {
+	depths := make(map[uint16]int)
+	onPath := make(map[uint16]bool)
+	var deepest func(sub uint16) int
+	deepest = func(sub uint16) int {
+		if depth, ok := depths[sub]; ok {
+			return depth
+		}
+		onPath[sub] = true
+		depth := 0
+		for _, callee := range sortedKeys(a.calls[sub]) {
+			if onPath[callee] {
+				a.report(a.calls[sub][callee], Warning, CheckRecursion, "call to 0x%03X recurses, so how deep the stack gets cannot be bounded", callee)
+				continue
+			}
+			if d := deepest(callee) + 1; d > depth {
+				depth = d
+			}
+		}
+		onPath[sub] = false
+		depths[sub] = depth
+		return depth
+	}
+	stack := cpu.NewStack(0x10).MaxSize()
+	if depth := deepest(a.origin); depth > stack {
+		var chain []string
+		sub, at := a.origin, a.origin
+		for depths[sub] > 0 {
+			for _, callee := range sortedKeys(a.calls[sub]) {
+				if !onPath[callee] && depths[callee]+1 == depths[sub] {
+					chain = append(chain, fmt.Sprintf("0x%03X", callee))
+					if len(chain) == stack+1 {
+						at = a.calls[sub][callee]
+					}
+					sub = callee
+					break
+				}
+			}
+		}
+		a.report(at, Error, CheckStackDepth, "calls nest %d deep, overflowing the %d entry stack: %s", depth, stack, strings.Join(chain, " > "))
+	}
+}
+
+// checkWrites reports writes to memory that is executed
+func (a *analysis) checkWrites() {
+	for _, w := range a.writes {
+		for addr := int(w.from); addr < w.end; addr++ {
+			if a.executed(uint16(addr)) {
+				a.report(w.pc, Warning, CheckSelfModifies, "%s writes to 0x%03X, which is executed as code", w.what, addr)
+				break
+			}
+		}
+	}
+}
+
+// executed reports whether addr is part of an instruction reached
+func (a *analysis) executed(addr uint16) bool {
+	for back := 0; back < 4 && int(addr)-back >= int(a.origin); back++ {
+		start := addr - uint16(back)
+		if i, ok := a.code[start]; ok && addr < start+cpu.Length(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkUnreachable reports runs of the ROM that are never reached but
+// decode as instructions throughout, and are not referred to as data
+func (a *analysis) checkUnreachable() {
+	end := a.origin + uint16(len(a.rom))
+	for addr := a.origin; addr < end; {
+		if a.executed(addr) {
+			addr++
+			continue
+		}
+		start := addr
+		for addr < end && !a.executed(addr) {
+			addr++
+		}
+		if n, ok := a.decodesAsCode(start, addr); ok {
+			a.report(start, Warning, CheckUnreachable, "0x%03X-0x%03X is never reached, but holds %d instructions", start, addr-1, n)
+		}
+	}
+}
+
+// decodesAsCode reports whether from to end is a run of instructions,
+// ending in a jump or return as code does, that nothing loads I with
+func (a *analysis) decodesAsCode(from, end uint16) (int, bool) {
+	n, last := 0, uint16(0)
+	for addr := from; addr < end; n++ {
+		if a.refs[addr] {
+			return 0, false
+		}
+		i, length, ok := a.decode(addr)
+		if !ok || addr+length > end {
+			return 0, false
+		}
+		last = i.Code()
+		addr += length
+	}
+	return n, last == 0x1000 || last == 0x00EE || last == 0x00FD
+}
+
+func sortedKeys(m map[uint16]uint16) []uint16 {
+	keys := make([]uint16, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+	return keys
+}
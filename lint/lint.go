@@ -0,0 +1,93 @@
+// Package lint finds likely bugs in ROMs without running them, by
+// abstract interpretation: following every path through the program
+// while tracking what can be known of the registers along the way
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Nuxij/goch8p/cpu"
+)
+
+// Severity is how sure a finding is to be a bug
+type Severity int
+
+const (
+	// Warning is something that may be intended, such as code that
+	// rewrites itself
+	Warning Severity = iota
+	// Error is something that breaks the program when it is reached
+	Error
+)
+
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// Check names what a finding is about, for filtering findings
+type Check string
+
+const (
+	CheckUnreachable  Check = "unreachable"
+	CheckMisaligned   Check = "misaligned"
+	CheckInvalid      Check = "invalid"
+	CheckStackDepth   Check = "stack-depth"
+	CheckRecursion    Check = "recursion"
+	CheckReturn       Check = "return"
+	CheckFontWrite    Check = "font-write"
+	CheckBounds       Check = "bounds"
+	CheckSelfModifies Check = "self-modifying"
+)
+
+// Finding is a likely bug at an address
+type Finding struct {
+	Addr     uint16
+	Severity Severity
+	Check    Check
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("0x%03X: %s: %s (%s)", f.Addr, f.Severity, f.Message, f.Check)
+}
+
+// Options describe the machine the ROM is written for
+type Options struct {
+	// Mode picks the instruction set, ModeChip8 being the default
+	Mode cpu.Mode
+	// Quirks decide how FX55, FX65 and BNNN behave
+	Quirks cpu.Quirks
+	// Origin is where the ROM is loaded, cpu.DefaultOrigin if zero
+	Origin uint16
+}
+
+// Lint analyses rom, returning what it finds in address order
+func Lint(rom []byte, options Options) []Finding {
+	if options.Origin == 0 {
+		options.Origin = cpu.DefaultOrigin
+	}
+	a := newAnalysis(rom, options)
+	a.run()
+	a.checkOverlaps()
+	a.checkDepth()
+	a.checkWrites()
+	a.checkUnreachable()
+	sort.SliceStable(a.findings, func(i, j int) bool {
+		return a.findings[i].Addr < a.findings[j].Addr
+	})
+	return a.findings
+}
+
+// HasErrors reports whether any of the findings is an Error
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
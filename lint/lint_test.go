@@ -0,0 +1,169 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Nuxij/goch8p/asm"
+	"github.com/Nuxij/goch8p/cpu"
+)
+
+// checks lists findings as their address and check, for comparing
+func checks(findings []Finding) []string {
+	var s []string
+	for _, f := range findings {
+		s = append(s, fmt.Sprintf("%03X %s", f.Addr, f.Check))
+	}
+	return s
+}
+
+// nested calls depth subroutines, each calling the next
+func nested(depth int) string {
+	var b strings.Builder
+	b.WriteString("    CALL sub1\nloop:\n    JP loop\n")
+	for n := 1; n < depth; n++ {
+		fmt.Fprintf(&b, "sub%d:\n    CALL sub%d\n    RET\n", n, n+1)
+	}
+	fmt.Fprintf(&b, "sub%d:\n    RET\n", depth)
+	return b.String()
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{"clean", `
+    LD I, sprite        ; 200
+    CALL draw           ; 202
+loop:
+    JP loop             ; 204
+draw:
+    DRW V0, V0, 1       ; 206
+    RET                 ; 208
+sprite:
+    db 0xFF             ; 20A
+`, nil},
+		{"unreachable", `
+    JP end              ; 200
+    LD V0, 1            ; 202
+    JP end              ; 204
+end:
+    JP end              ; 206
+`, []string{"202 unreachable"}},
+		{"misaligned", `
+    LD V1, 0x12         ; 200
+    JP 0x201            ; 202
+`, []string{"201 misaligned", "202 misaligned", "212 invalid"}},
+		{"invalid", `
+    LD V0, 1            ; 200
+    db 0xFF, 0xFF       ; 202
+`, []string{"202 invalid"}},
+		{"falls off the end", `
+    LD V0, 1            ; 200
+`, []string{"202 invalid"}},
+		{"stack depth", nested(16), nil},
+		{"stack overflow", nested(17), []string{"240 stack-depth"}},
+		{"recursion", `
+    CALL sub            ; 200
+loop:
+    JP loop             ; 202
+sub:
+    SE V0, 0            ; 204
+    CALL sub            ; 206
+    RET                 ; 208
+`, []string{"206 recursion"}},
+		{"empty stack", `
+    RET                 ; 200
+`, []string{"200 return"}},
+		{"font write", `
+    LD I, 0x1F0         ; 200
+    LD [I], V2          ; 202
+loop:
+    JP loop             ; 204
+`, []string{"202 font-write"}},
+		{"font write through call", `
+    CALL point          ; 200
+    LD B, V0            ; 202
+loop:
+    JP loop             ; 204
+point:
+    LD I, 0x010         ; 206
+    RET                 ; 208
+`, []string{"202 font-write"}},
+		{"sprite bounds", `
+    LD I, 0xFFD         ; 200
+    DRW V0, V0, 4       ; 202
+loop:
+    JP loop             ; 204
+`, []string{"202 bounds"}},
+		{"store bounds", `
+    LD I, 0xFF0         ; 200
+    LD V0, 0x0F         ; 202
+    ADD I, V0           ; 204
+    LD [I], V1          ; 206
+loop:
+    JP loop             ; 208
+`, []string{"206 bounds"}},
+		{"self-modifying", `
+    LD I, patch         ; 200
+    LD V0, 0x61         ; 202
+    LD [I], V0          ; 204
+patch:
+    LD V1, 0            ; 206
+loop:
+    JP loop             ; 208
+`, []string{"204 self-modifying"}},
+		{"known skips", `
+    LD V0, 1            ; 200
+    SE V0, 1            ; 202
+    RET                 ; 204
+loop:
+    JP loop             ; 206
+`, []string{"204 unreachable"}},
+		{"jump table", `
+    RND V0, 2           ; 200
+    JP V0, table        ; 202
+table:
+    JP left             ; 204
+    JP right            ; 206
+left:
+    JP left             ; 208
+right:
+    JP right            ; 20A
+`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := asm.MustAssemble(tt.source, asm.Options{})
+			findings := Lint(p.ROM, Options{Quirks: cpu.QuirksCOSMACVIP})
+			assert.Equal(t, tt.want, checks(findings))
+		})
+	}
+}
+
+func TestLint_messages(t *testing.T) {
+	p := asm.MustAssemble(nested(17), asm.Options{})
+	findings := Lint(p.ROM, Options{})
+	if assert.Len(t, findings, 1) {
+		assert.Equal(t, "0x240: error: calls nest 17 deep, overflowing the 16 entry stack: "+
+			"0x204 > 0x208 > 0x20C > 0x210 > 0x214 > 0x218 > 0x21C > 0x220 > 0x224 > "+
+			"0x228 > 0x22C > 0x230 > 0x234 > 0x238 > 0x23C > 0x240 > 0x244 (stack-depth)", findings[0].String())
+	}
+	assert.True(t, HasErrors(findings))
+
+	p = asm.MustAssemble(`
+    JP end              ; 200
+    CLS                 ; 202
+    JP end              ; 204
+end:
+    JP end              ; 206
+`, asm.Options{})
+	findings = Lint(p.ROM, Options{})
+	assert.Equal(t, []Finding{{0x202, Warning, CheckUnreachable, "0x202-0x205 is never reached, but holds 2 instructions"}}, findings)
+	assert.False(t, HasErrors(findings))
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintCommand(t *testing.T) {
+	dir := t.TempDir()
+	clean := filepath.Join(dir, "clean.ch8")
+	broken := filepath.Join(dir, "broken.ch8")
+	// JP 0x200
+	assert.NoError(t, os.WriteFile(clean, []byte{0x12, 0x00}, 0644))
+	// LD I, 0x100; LD [I], V0; JP 0x204
+	assert.NoError(t, os.WriteFile(broken, []byte{0xA1, 0x00, 0xF0, 0x55, 0x12, 0x04}, 0644))
+
+	var stdout, stderr strings.Builder
+	assert.Equal(t, 0, lintCommand([]string{clean}, &stdout, &stderr))
+	assert.Empty(t, stdout.String())
+
+	assert.Equal(t, 1, lintCommand([]string{"-quirks", "schip", broken}, &stdout, &stderr))
+	assert.Equal(t, broken+":0x202: error: FX55 writes to 0x100, clobbering the font area below 0x200 (font-write)\n", stdout.String())
+	assert.Equal(t, "1 errors, 0 warnings\n", stderr.String())
+
+	stderr.Reset()
+	assert.Equal(t, 2, lintCommand([]string{clean, "-mode", "chip9"}, &stdout, &stderr), "flags after the ROM should count")
+	assert.Equal(t, "unknown mode \"chip9\"\n", stderr.String())
+}
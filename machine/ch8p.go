@@ -1,15 +1,32 @@
 package machine
 
 import (
-	"encoding/binary"
-	"fmt"
 	"time"
 )
 
-type Counters map[rune]uint16
-type Registers map[uint8]byte
+// Counter names one of the machine's 16-bit counters
+type Counter uint8
 
-// Ch8p is the CHIP-8 machine itself
+const (
+	// CounterPC is the program counter
+	CounterPC Counter = iota
+	// CounterI is the index register
+	CounterI
+	// CounterSP is the stack pointer
+	CounterSP
+	// CounterTick counts the cycles run
+	CounterTick
+	counters
+)
+
+// Counters holds each Counter, indexed by it
+type Counters [counters]uint16
+
+// Registers holds V0 to VF
+type Registers [16]byte
+
+// Ch8p is the CHIP-8 machine itself. Nothing it does in a cycle
+// allocates, so long sessions run at a steady speed in steady memory.
 type Ch8p struct {
 	V        Registers
 	Counters Counters
@@ -19,26 +36,48 @@ type Ch8p struct {
 	Keyboard Memory
 	Delay    *time.Ticker
 	Sound    *time.Ticker
+	// Running gates Cycle, and DrawFlag says the last cycle changed GFX
+	Running  bool
+	DrawFlag bool
+	// LastOp holds the most recent instructions executed
+	LastOp History
+}
+
+// NewCh8p builds a machine with 4KiB of RAM holding the fonts, a 64x32
+// screen and the program counter at 0x200, ready for a ROM
+func NewCh8p() *Ch8p {
+	c := &Ch8p{
+		GFX:      make(Memory, 64*32),
+		RAM:      make(Memory, 0x1000),
+		Keyboard: make(Memory, 16),
+	}
+	c.LoadFonts()
+	c.WriteCounter(CounterPC, 0x200)
+	return c
+}
+
+// LoadROM puts rom into memory at 0x200 and sets the machine running
+func (c *Ch8p) LoadROM(rom []byte) {
+	c.RAM.WriteBytes(0x200, rom)
+	c.Running = true
 }
 
 func (c *Ch8p) Cycle() {
 	speed := 1
 	if c.Running {
 		for i := 0; i < speed; i++ {
-			opcode := c.ReadInstruction()
+			op := NewOp(c.ReadInstruction())
 			c.DrawFlag = false
-			op := NewOp(opcode)
-			if op.Op() == 0x0000 {
+			if op.Code == 0x0000 {
 				continue
 			} else {
 				c.IncrementProgramCounter()
-				op.Execute(c, op)
-				c.LastOp = fmt.Sprintf("%v\n", op) + c.LastOp
+				op.Execute(c)
+				c.LastOp.Push(op)
 			}
 		}
-		c.IncrementCounter('T')	
+		c.IncrementCounter(CounterTick)
 	}
-	
 }
 
 // LoadFonts will put each of the fonts in Fonts into memory
@@ -46,62 +85,61 @@ func (c *Ch8p) LoadFonts() {
 	for i, font := range Fonts {
 		c.RAM.WriteBytes(uint16(i*5), font[:])
 	}
-	c.LastOp = ""
+	c.LastOp.Clear()
 }
 
 func (c *Ch8p) DrawSprite(x, y uint16, height uint16) {
-	sprite := c.ReadRAMBytes(c.ReadCounter('I'), height)
+	sprite := c.ReadRAMBytes(c.ReadCounter(CounterI), height)
 	for yPos, b := range sprite {
 		for xPos := uint16(0); xPos < 8; xPos++ {
-			if x + xPos >= 64 || y + uint16(yPos) >= 32 {
+			if x+xPos >= 64 || y+uint16(yPos) >= 32 {
 				continue
 			}
-			pos := (y + uint16(yPos)) * 64 + x + xPos
-			onScreen := c.GFX.ReadByte(pos)
+			pos := (y+uint16(yPos))*64 + x + xPos
+			onScreen := c.GFX.Read8(pos)
 			toBe := b & (0x80 >> xPos)
 			if toBe != 0 && onScreen != 0 {
-				c.GFX.WriteByte(pos, 0)
-				c.WriteRegister('F', 1)
+				c.GFX.Write8(pos, 0)
+				c.WriteRegister(0xF, 1)
 			} else if toBe != 0 && onScreen == 0 {
-				c.GFX.WriteByte(pos, 1)
+				c.GFX.Write8(pos, 1)
 			}
 		}
 	}
-	c.WriteCounter('I', 0)
+	c.WriteCounter(CounterI, 0)
 	c.DrawFlag = true
 }
 
 func (c *Ch8p) ClearScreen() {
 	for i := 0; i < len(c.GFX); i++ {
-		c.GFX.WriteByte(uint16(i), 0)
+		c.GFX.Write8(uint16(i), 0)
 	}
 	c.DrawFlag = true
 }
 
 func (c *Ch8p) IncrementProgramCounter() uint16 {
-	pc := c.ReadCounter('P')
-	c.WriteCounter('P', pc+2)
+	pc := c.ReadCounter(CounterPC)
+	c.WriteCounter(CounterPC, pc+2)
 	return pc
 }
 
 func (c *Ch8p) ReadInstruction() uint16 {
-	pc := c.ReadCounter('P')
-	opbytes := c.ReadRAMBytes(pc, 2)
-	opcode := binary.BigEndian.Uint16(opbytes)
-	return opcode
+	return c.RAM.ReadWord(c.ReadCounter(CounterPC))
 }
 
 // ReadRAM does what it says on the tin
 func (c *Ch8p) ReadRAM(addr uint16) byte {
-	return c.RAM.ReadByte(addr)
+	return c.RAM.Read8(addr)
 }
 func (c *Ch8p) ReadRAMBytes(addr uint16, length uint16) []byte {
 	return c.RAM.ReadBytes(addr, length)
 }
+
 // WriteRAM does what it says on the tin
 func (c *Ch8p) WriteRAM(addr uint16, value byte) {
-	c.RAM.WriteByte(addr, value)
+	c.RAM.Write8(addr, value)
 }
+
 // WriteRAMBytes does WriteRAM but for a slice of bytes
 func (c *Ch8p) WriteRAMBytes(addr uint16, bytes []byte) {
 	c.RAM.WriteBytes(addr, bytes)
@@ -109,56 +147,25 @@ func (c *Ch8p) WriteRAMBytes(addr uint16, bytes []byte) {
 
 // ReadRegister does what it says on the tin
 func (c *Ch8p) ReadRegister(reg uint8) byte {
-	return c.V[reg]
+	return c.V[reg&0xF]
 }
+
 // WriteRegister does what it says on the tin
 func (c *Ch8p) WriteRegister(reg uint8, value byte) {
-	c.V[reg] = value
+	c.V[reg&0xF] = value
 }
+
 // ReadCounter does what it says on the tin
-func (c *Ch8p) ReadCounter(reg rune) uint16 {
+func (c *Ch8p) ReadCounter(reg Counter) uint16 {
 	return c.Counters[reg]
 }
+
 // WriteCounter does what it says on the tin
-func (c *Ch8p) WriteCounter(reg rune, value uint16) {
+func (c *Ch8p) WriteCounter(reg Counter, value uint16) {
 	c.Counters[reg] = value
 }
+
 // IncrementCounter does what it says on the tin
-func (c *Ch8p) IncrementCounter(reg rune) {
+func (c *Ch8p) IncrementCounter(reg Counter) {
 	c.Counters[reg]++
 }
-
-// func (c *Ch8p) Debug() {
-// 	tm.Clear()
-
-// 	tm.MoveCursor(1, 1)
-
-// 	tm.Printf(`opcode: %x
-// pc: %d
-// sp: %d
-// i: %d
-// ---Registers---
-// V0: %d
-// V1: %d
-// V2: %d
-// V3: %d
-// V4: %d
-// V5: %d
-// V6: %d
-// V7: %d
-// V8: %d
-// V9: %d
-// VA: %d
-// VB: %d
-// VC: %d
-// VD: %d
-// VE: %d
-// VF: %d\n`,
-// 		0x00, c.Counters['P'], c.Counters['S'], c.Counters['I'],
-// 		c.V.Get('0'), c.V.Get('1'), c.V.Get('2'), c.V.Get('3'),
-// 		c.V.Get('4'), c.V.Get('5'), c.V.Get('6'), c.V.Get('7'),
-// 		c.V.Get('8'), c.V.Get('9'), c.V.Get('A'), c.V.Get('B'),
-// 		c.V.Get('C'), c.V.Get('D'), c.V.Get('E'), c.V.Get('F'),
-// 	)
-// 	tm.Flush()
-// }
@@ -0,0 +1,61 @@
+package machine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// drawing loads V0, points I at the 0 glyph, draws it and jumps back
+var drawing = []byte{0x60, 0x05, 0xA0, 0x00, 0xD0, 0x05, 0x12, 0x00}
+
+func TestCh8p_Cycle(t *testing.T) {
+	c := NewCh8p()
+	c.LoadROM(drawing)
+	for n := 0; n < 3; n++ {
+		c.Cycle()
+	}
+	assert.EqualValues(t, 5, c.ReadRegister(0x0))
+	assert.EqualValues(t, 0x206, c.ReadCounter(CounterPC))
+	assert.EqualValues(t, 3, c.ReadCounter(CounterTick))
+	assert.True(t, c.DrawFlag)
+	assert.EqualValues(t, 1, c.GFX.Read8(5*64+5), "the glyph's corner should be lit")
+	assert.Equal(t, "D005 [Draw]\nA000 [Load:I]\n6005 [Load]\n", c.LastOp.String())
+
+	c.Cycle()
+	assert.EqualValues(t, 0x200, c.ReadCounter(CounterPC))
+}
+
+func TestHistory(t *testing.T) {
+	var h History
+	for n := 0; n < HistorySize+3; n++ {
+		h.Push(NewOp(0x6000 | uint16(n)))
+	}
+	assert.Equal(t, HistorySize, h.Len(), "the history should stop growing")
+	assert.EqualValues(t, 0x6000|HistorySize+2, h.At(0).Code)
+	assert.EqualValues(t, 0x6003, h.At(HistorySize-1).Code)
+	h.Clear()
+	assert.Empty(t, h.String())
+}
+
+func TestNewOp(t *testing.T) {
+	assert.Equal(t, "SkipIfNotPressed", NewOp(0xE1A1).Name())
+	assert.Equal(t, Op{}, NewOp(0xE1FF), "unknown opcodes should decode as nothing")
+	assert.Nil(t, NewOper(0xE1FF))
+}
+
+func TestCh8p_Cycle_allocations(t *testing.T) {
+	c := NewCh8p()
+	c.LoadROM(drawing)
+	assert.Zero(t, testing.AllocsPerRun(1000, c.Cycle))
+}
+
+func BenchmarkCh8p_Cycle(b *testing.B) {
+	c := NewCh8p()
+	c.LoadROM(drawing)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		c.Cycle()
+	}
+}
@@ -0,0 +1,48 @@
+package machine
+
+import "strings"
+
+// HistorySize is how many instructions a History keeps
+const HistorySize = 32
+
+// History is a ring of the most recent instructions executed, the oldest
+// dropping off as new ones come in so it never grows
+type History struct {
+	ops   [HistorySize]Op
+	next  int
+	count int
+}
+
+// Push records op as the most recent instruction
+func (h *History) Push(op Op) {
+	h.ops[h.next] = op
+	h.next = (h.next + 1) % HistorySize
+	if h.count < HistorySize {
+		h.count++
+	}
+}
+
+// Clear forgets every instruction
+func (h *History) Clear() {
+	*h = History{}
+}
+
+// Len returns how many instructions the history holds
+func (h *History) Len() int {
+	return h.count
+}
+
+// At returns the nth most recent instruction, 0 being the last
+func (h *History) At(n int) Op {
+	return h.ops[(h.next-1-n+2*HistorySize)%HistorySize]
+}
+
+// String lists the instructions a line each, most recent first
+func (h *History) String() string {
+	var b strings.Builder
+	for n := 0; n < h.count; n++ {
+		b.WriteString(h.At(n).String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
@@ -1,17 +1,17 @@
 package machine
 
 type Ch8pInfo struct {
-	Name     string    `json:"name",default:"joeks ch8p"`
-	Version  string    `json:"version",default:"0.0.1"`
-	Tick     uint16    `json:"tick",default:"0"`
-	Opcode   string    `json:"opcode",default:"none"`
-	RAM      []byte    `json:"ram",default:"[]"`
-	PC       uint16    `json:"pc",default:"0"`
-	V        Registers `json:"v",default:"[]"`
-	I        uint16    `json:"i",default:"0"`
-	Stack    Stack     `json:"stack",default:"[]"`
-	DrawFlag bool      `json:"drawflag",default:"false"`
-	Running  bool      `json:"running",default:"false"`
+	Name     string    `json:"name" default:"joeks ch8p"`
+	Version  string    `json:"version" default:"0.0.1"`
+	Tick     uint16    `json:"tick" default:"0"`
+	Opcode   string    `json:"opcode" default:"none"`
+	RAM      []byte    `json:"ram" default:"[]"`
+	PC       uint16    `json:"pc" default:"0"`
+	V        Registers `json:"v" default:"[]"`
+	I        uint16    `json:"i" default:"0"`
+	Stack    Stack     `json:"stack" default:"[]"`
+	DrawFlag bool      `json:"drawflag" default:"false"`
+	Running  bool      `json:"running" default:"false"`
 }
 
 // 5-high sprite for fonts
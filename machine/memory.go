@@ -8,16 +8,16 @@ import (
 // Memory is a byte array uint16 class
 type Memory []byte
 
-func (m Memory) ReadByte(addr uint16) byte {
+func (m Memory) Read8(addr uint16) byte {
 	return m[addr]
 }
 func (m Memory) ReadWord(addr uint16) uint16 {
-	return binary.LittleEndian.Uint16(m[addr : addr+1])
+	return binary.BigEndian.Uint16(m[addr : addr+2])
 }
 func (m Memory) ReadBytes(addr uint16, length uint16) []byte {
 	return m[addr : addr+length]
 }
-func (m Memory) WriteByte(addr uint16, value byte) {
+func (m Memory) Write8(addr uint16, value byte) {
 	m[addr] = value
 }
 func (m Memory) WriteWord(addr uint16, value uint16) {
@@ -25,14 +25,10 @@ func (m Memory) WriteWord(addr uint16, value uint16) {
 	m[addr+1] = byte(value)
 }
 func (m Memory) WriteBytes(addr uint16, bytes []byte) {
-	for i, b := range bytes {
-		fmt.Sprintf("%v", b)
-		m[addr+uint16(i)] = b
-	}
-	fmt.Println()
+	copy(m[addr:], bytes)
 }
 func (m Memory) String() string {
-	return fmt.Sprintf("%v", m)
+	return fmt.Sprintf("%v", []byte(m))
 }
 
 // type ByteMemory []byte
@@ -44,11 +40,11 @@ func (m Memory) String() string {
 
 // func (m *ByteMemory) WriteBytes(addr Int, bytes []byte) {
 // 	for i, b := range bytes {
-// 		m.WriteByte(addr+Int(i), b)
+// 		m.Write8(addr+Int(i), b)
 // 	}
 // }
 
-// func (m *ByteMemory) WriteByte(addr Int, value byte) {
+// func (m *ByteMemory) Write8(addr Int, value byte) {
 // 	if addr < 0 && addr >= Int(len(*m)) {
 // 		panic("Trying to write outside of memory")
 // 	}
@@ -58,7 +54,7 @@ func (m Memory) String() string {
 // 	(*m)[addr] = value
 // }
 
-// func (m *ByteMemory) ReadByte(addr Int) byte {
+// func (m *ByteMemory) Read8(addr Int) byte {
 // 	if addr < 0 && addr >= Int(len(*m)) {
 // 		panic("Trying to read outside of memory")
 // 	}
@@ -2,67 +2,74 @@ package machine
 
 import "fmt"
 
-// Oper interface allows Ops to override execution behavior
+// Oper executes one class of instruction. Each is decoded once, into
+// opers, and Ops are passed by value, so executing them allocates nothing.
 type Oper interface {
 	Name() string
-	Execute(c *Ch8p, op OpCode)
+	Execute(c *Ch8p, op Op)
 }
 
-type OpCode interface {
-	Oper
-	Op() uint16
-	OpClass() byte
-	X() byte
-	Y() byte
-	XY() (byte, byte)
-	N() byte
-	KK() uint16
-	NNN() uint16
-	Name() string
-}
-
-// Op will store a code and Execute via an Oper
+// Op is a decoded instruction, its code and the Oper that executes it
 type Op struct {
 	Code uint16
-	name string
-	call func(c *Ch8p)
+	oper Oper
 }
+
 // Name prints the name to match Oper
 func (o Op) Name() string {
-	return o.name
+	if o.oper == nil {
+		return "Unknown"
+	}
+	return o.oper.Name()
 }
+
+// Execute executes the op on c
+func (o Op) Execute(c *Ch8p) {
+	if o.oper != nil {
+		o.oper.Execute(c, o)
+	}
+}
+
 // String implements the Stringer interface
 func (o Op) String() string {
 	return fmt.Sprintf("%X [%v]", o.Code, o.Name())
 }
+
 // Op returns the opcode only
 func (o Op) Op() uint16 {
 	return o.Code & 0xF000
 }
+
 // OpClass returns the opcode's highest byte only
 func (o Op) OpClass() byte {
 	return byte(o.Op() >> 12)
 }
+
 // X returns the byte in position X of the opcode
 func (o Op) X() byte {
 	return byte(o.Code & 0x0F00 >> 8)
 }
+
 // Y returns the byte in position Y of the opcode
 func (o Op) Y() byte {
 	return byte(o.Code & 0x00F0 >> 4)
 }
+
 // XY returns the X and Y bytes of the opcode
 func (o Op) XY() (byte, byte) {
 	return o.X(), o.Y()
 }
+
 // N returns the nibble in position N of the opcode (the lowest 4 bits)
 func (o Op) N() byte {
 	return byte(o.Code & 0x000F)
 }
+
 // KK returns the lowest byte of the opcode
 func (o Op) KK() uint16 {
 	return o.Code & 0x00FF
 }
+
 // NNN returns everything but the opcode itself (the lowest three bytes)
 func (o Op) NNN() uint16 {
 	return o.Code & 0x0FFF
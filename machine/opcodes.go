@@ -1,86 +1,61 @@
 package machine
 
-import "fmt"
-
-// NewOp calls NewOper and returns it cast to an OpCode
-func NewOp(opcode uint16) OpCode {
-	return NewOper(opcode).(OpCode)
+// opers holds the Oper for each class of instruction, indexed by
+// OpClass, but for class E, which skips holds
+var opers = [16]Oper{
+	0x0: OperSys{},
+	0x1: OperJump{},
+	0x2: OperCall{},
+	0x3: OperSE{},
+	0x4: OperSNE{},
+	0x5: OperSEXY{},
+	0x6: OperLD{},
+	0x7: OperADD{},
+	0x8: OperBit{},
+	0x9: OperSNEXY{},
+	0xA: OperLDI{},
+	0xB: OperJPV0{},
+	0xC: OperRND{},
+	0xD: OperDRW{},
+	0xF: OperSpecial{},
 }
-// NewOper returns a new Oper with the given code.
-func NewOper(opcode uint16) Oper {
+
+// skips holds the Opers for class E, EX9E and EXA1
+var skips = [2]Oper{OperSKP{}, OperSKNP{}}
+
+// NewOp decodes opcode, looking up its Oper among those decoded ahead of
+// time. An opcode with no Oper decodes as Code 0, which does nothing.
+func NewOp(opcode uint16) Op {
 	op := Op{Code: opcode}
-	var oper Oper
-	switch op.OpClass() {
-	case 0x0:
-		op.name = "Sys"
-		oper = OperSys{op}
-	case 0x1:
-		op.name = "Jump"
-		oper = OperJump{op}
-	case 0x2:
-		op.name = "Call"
-		oper = OperCall{op}
-	case 0x3:
-		op.name = "SkipEqual"
-		oper = OperSE{op}
-	case 0x4:
-		op.name = "SkipNotEqual"
-		oper = OperSNE{op}
-	case 0x5:
-		op.name = "SkipEqual:XY"
-		oper = OperSEXY{op}
-	case 0x6:
-		op.name = "Load"
-		oper = OperLD{op}
-	case 0x7:
-		op.name = "Add"
-		oper = OperADD{op}
-	case 0x8:
-		op.name = "Bit"
-		oper = OperBit{op}
-	case 0x9:
-		op.name = "SkipNotEqual:XY"
-		oper = OperSNEXY{op}
-	case 0xA:
-		op.name = "Load:I"
-		oper = OperLDI{op}
-	case 0xB:
-		op.name = "JumpV0"
-		oper = OperJPV0{op}
-	case 0xC:
-		op.name = "RandomByte"
-		oper = OperRND{op}
-	case 0xD:
-		op.name = "Draw"
-		oper = OperDRW{op}
-	case 0xE:
-		switch op.KK() {
-		case 0x9E:
-			op.name = "SkipIfPressed"
-			oper = OperSKP{op}
-		case 0xA1:
-			op.name = "SkipIfNotPressed"
-			oper = OperSKNP{op}
-		}
-	case 0xF:
-		op.name = "Special"
-		oper = OperSpecial{op}
-	default:
-	fmt.Printf("Unknown opcode: %X\n", opcode)
-	op.name = "Unknown"
-	op.Code = 0x0
-	oper = OperSys{op}
+	switch {
+	case op.OpClass() != 0xE:
+		op.oper = opers[op.OpClass()]
+	case op.KK() == 0x9E:
+		op.oper = skips[0]
+	case op.KK() == 0xA1:
+		op.oper = skips[1]
 	}
-	oper.(OpCode).call = func(c *Ch8p) {
-		
+	if op.oper == nil {
+		op.Code = 0x0
 	}
-	return oper
+	return op
+}
+
+// NewOper returns the Oper executing opcode, nil if there is none
+func NewOper(opcode uint16) Oper {
+	return NewOp(opcode).oper
 }
 
 // OperSys are the system instructions.
-type OperSys struct{ Op }
+type OperSys struct{}
+
+// Name of the op
+func (OperSys) Name() string {
+	return "Sys"
+}
+
 // Execute the op
-func (o OperSys) Execute(c *Ch8p, op OpCode) {
+func (o OperSys) Execute(c *Ch8p, op Op) {
 	switch op.KK() {
 	case 0x00E0:
 		c.ClearScreen()
@@ -90,23 +65,41 @@ func (o OperSys) Execute(c *Ch8p, op OpCode) {
 }
 
 // OperJump sets the program counter to the address in the operand.
-type OperJump struct{ Op }
+type OperJump struct{}
+
+// Name of the op
+func (OperJump) Name() string {
+	return "Jump"
+}
+
 // Execute the op
-func (o OperJump) Execute(c *Ch8p, op OpCode) {
-	c.WriteCounter('P', op.NNN())
+func (o OperJump) Execute(c *Ch8p, op Op) {
+	c.WriteCounter(CounterPC, op.NNN())
 }
 
 // OperCall calls a subroutine (likely by pushing to the stack and program counter).
-type OperCall struct{ Op }
+type OperCall struct{}
+
+// Name of the op
+func (OperCall) Name() string {
+	return "Call"
+}
+
 // Execute the op
-func (o OperCall) Execute(c *Ch8p, op OpCode) {
+func (o OperCall) Execute(c *Ch8p, op Op) {
 	// c.CallSubroutine(op.NNN())
 }
 
 // OperSE is the skip if equal instruction.
-type OperSE struct{ Op }
+type OperSE struct{}
+
+// Name of the op
+func (OperSE) Name() string {
+	return "SkipEqual"
+}
+
 // Execute the op
-func (o OperSE) Execute(c *Ch8p, op OpCode) {
+func (o OperSE) Execute(c *Ch8p, op Op) {
 	Vx := c.ReadRegister(op.X())
 	if Vx == op.N() {
 		c.IncrementProgramCounter()
@@ -114,9 +107,15 @@ func (o OperSE) Execute(c *Ch8p, op OpCode) {
 }
 
 // OperSNE is the skip if not equal instruction.
-type OperSNE struct{ Op }
+type OperSNE struct{}
+
+// Name of the op
+func (OperSNE) Name() string {
+	return "SkipNotEqual"
+}
+
 // Execute the op
-func (o OperSNE) Execute(c *Ch8p, op OpCode) {
+func (o OperSNE) Execute(c *Ch8p, op Op) {
 	Vx := c.ReadRegister(op.X())
 	if Vx != op.N() {
 		c.IncrementProgramCounter()
@@ -124,9 +123,15 @@ func (o OperSNE) Execute(c *Ch8p, op OpCode) {
 }
 
 // OperSEXY will skip if X and Y registers are equal
-type OperSEXY struct{ Op }
+type OperSEXY struct{}
+
+// Name of the op
+func (OperSEXY) Name() string {
+	return "SkipEqual:XY"
+}
+
 // Execute the op
-func (o OperSEXY) Execute(c *Ch8p, op OpCode) {
+func (o OperSEXY) Execute(c *Ch8p, op Op) {
 	Vx := c.ReadRegister(op.X())
 	if Vx != op.N() {
 		c.IncrementProgramCounter()
@@ -134,9 +139,15 @@ func (o OperSEXY) Execute(c *Ch8p, op OpCode) {
 }
 
 // OperSNEXY will skip if X and Y registers are not equal
-type OperSNEXY struct{ Op }
+type OperSNEXY struct{}
+
+// Name of the op
+func (OperSNEXY) Name() string {
+	return "SkipNotEqual:XY"
+}
+
 // Execute the op
-func (o OperSNEXY) Execute(c *Ch8p, op OpCode) {
+func (o OperSNEXY) Execute(c *Ch8p, op Op) {
 	Vx := c.ReadRegister(op.X())
 	if Vx != op.N() {
 		c.IncrementProgramCounter()
@@ -144,25 +155,43 @@ func (o OperSNEXY) Execute(c *Ch8p, op OpCode) {
 }
 
 // OperLD will load the value in the operand into the register.
-type OperLD struct{ Op }
+type OperLD struct{}
+
+// Name of the op
+func (OperLD) Name() string {
+	return "Load"
+}
+
 // Execute the op
-func (o OperLD) Execute(c *Ch8p, op OpCode) {
+func (o OperLD) Execute(c *Ch8p, op Op) {
 	c.WriteRegister(op.X(), op.N())
 }
 
 // OperADD adds the value in the operand to the register.
-type OperADD struct{ Op }
+type OperADD struct{}
+
+// Name of the op
+func (OperADD) Name() string {
+	return "Add"
+}
+
 // Execute the op
-func (o OperADD) Execute(c *Ch8p, op OpCode) {
+func (o OperADD) Execute(c *Ch8p, op Op) {
 	Vx := c.ReadRegister(op.X())
 	Vy := c.ReadRegister(op.N())
 	c.WriteRegister(op.X(), Vx+Vy)
 }
 
 // OperBit will handle BitWise operations and other related operations.
-type OperBit struct{ Op }
+type OperBit struct{}
+
+// Name of the op
+func (OperBit) Name() string {
+	return "Bit"
+}
+
 // Execute the op
-func (o OperBit) Execute(c *Ch8p, op OpCode) {
+func (o OperBit) Execute(c *Ch8p, op Op) {
 	switch op.N() {
 	case 0x0:
 		c.WriteRegister(op.X(), c.ReadRegister(op.Y()))
@@ -186,30 +215,54 @@ func (o OperBit) Execute(c *Ch8p, op OpCode) {
 }
 
 // OperLDI will load the value in the operand into the 'I' counter.
-type OperLDI struct{ Op }
+type OperLDI struct{}
+
+// Name of the op
+func (OperLDI) Name() string {
+	return "Load:I"
+}
+
 // Execute the op
-func (o OperLDI) Execute(c *Ch8p, op OpCode) {
-	c.WriteCounter('I', op.NNN())
+func (o OperLDI) Execute(c *Ch8p, op Op) {
+	c.WriteCounter(CounterI, op.NNN())
 }
 
 // OperJPV0 will jump to the address in the operand plus the value in the 'V0' register.
-type OperJPV0 struct{ Op }
+type OperJPV0 struct{}
+
+// Name of the op
+func (OperJPV0) Name() string {
+	return "JumpV0"
+}
+
 // Execute the op
-func (o OperJPV0) Execute(c *Ch8p, op OpCode) {
+func (o OperJPV0) Execute(c *Ch8p, op Op) {
 	// c.JumpToAddress(op.NNN() + c.ReadRegister(0))
 }
 
 // OperRND will set the register to a random number between 0 and the operand.
-type OperRND struct{ Op }
+type OperRND struct{}
+
+// Name of the op
+func (OperRND) Name() string {
+	return "RandomByte"
+}
+
 // Execute the op
-func (o OperRND) Execute(c *Ch8p, op OpCode) {
+func (o OperRND) Execute(c *Ch8p, op Op) {
 	// c.WriteRegister(op.X(), c.Random(op.N()))
 }
 
 // OperDRW will draw a sprite at the x,y position from X,Y registers in the operand.
-type OperDRW struct{ Op }
+type OperDRW struct{}
+
+// Name of the op
+func (OperDRW) Name() string {
+	return "Draw"
+}
+
 // Execute the op
-func (o OperDRW) Execute(c *Ch8p, op OpCode) {
+func (o OperDRW) Execute(c *Ch8p, op Op) {
 	Vx := op.X()
 	Vy := op.Y()
 	X := uint16(c.ReadRegister(Vx) & 63)
@@ -219,27 +272,45 @@ func (o OperDRW) Execute(c *Ch8p, op OpCode) {
 }
 
 // OperSKP will skip the next instruction if the key in the operand is pressed.
-type OperSKP struct{ Op }
+type OperSKP struct{}
+
+// Name of the op
+func (OperSKP) Name() string {
+	return "SkipIfPressed"
+}
+
 // Execute the op
-func (o OperSKP) Execute(c *Ch8p, op OpCode) {
+func (o OperSKP) Execute(c *Ch8p, op Op) {
 	// if c.KeyPressed(op.X()) {
 	// 	c.IncrementProgramCounter()
 	// }
 }
 
 // OperSKNP will skip the next instruction if the key in the operand is not pressed.
-type OperSKNP struct{ Op }
+type OperSKNP struct{}
+
+// Name of the op
+func (OperSKNP) Name() string {
+	return "SkipIfNotPressed"
+}
+
 // Execute the op
-func (o OperSKNP) Execute(c *Ch8p, op OpCode) {
+func (o OperSKNP) Execute(c *Ch8p, op Op) {
 	// if c.KeyPressed(op.X()) {
 	// 	c.IncrementProgramCounter()
 	// }
 }
 
 // OperSpecial will handle special operations like delay, sound + input.
-type OperSpecial struct{ Op }
+type OperSpecial struct{}
+
+// Name of the op
+func (OperSpecial) Name() string {
+	return "Special"
+}
+
 // Execute the op
-func (o OperSpecial) Execute(c *Ch8p, op OpCode) {
+func (o OperSpecial) Execute(c *Ch8p, op Op) {
 	// if o.KK() == 0x07 {
 	// 	c.ReadDelayTimer(o.X())
 	// }
@@ -268,4 +339,4 @@ func (o OperSpecial) Execute(c *Ch8p, op OpCode) {
 	// 	c.LoadRegisters(o.X())
 	// }
 
-}
\ No newline at end of file
+}
@@ -1,11 +1,82 @@
+// Command goch8p is the emulator and the tools around it
+//
+//	goch8p run [--frontend=tea|imgui|headless] [-speed 10] [-scale 1] [-keys x123qweasdzc4rfv] game.ch8|game.8o
+//	goch8p run --headless [-frames 600] [-png game.png] [-state game.json] game.ch8|game.8o
+//	goch8p debug [-gdb localhost:1234] game.ch8|game.8o
+//	goch8p disasm [-mode chip8] [-origin 0x200] game.ch8 [-o game.s]
+//	goch8p asm [-mode chip8] [-origin 0x200] game.s|game.8o [-o game.ch8]
+//	goch8p info [-json] game.ch8
+//	goch8p lint [-mode chip8] [-quirks vip] game.ch8
+//	goch8p aot [-mode chip8] [-quirks vip] game.ch8 -o game.go
+//	goch8p trace run [-frames 60] [-format json|text] [-from 0x200] [-to 0x300] [-class 1,2,D] [-o out] game.ch8
+//	goch8p trace diff ours.jsonl theirs.jsonl
+//	goch8p profile [-frames 600] [-symbols game.8o] [-o profile.pb.gz] game.ch8
+//	goch8p coverage run [-frames 600] [-o game.cov] game.ch8
+//	goch8p coverage report [-html] [-o report.html] game.ch8 game.cov...
+//
+// Every command but trace diff takes -mode, -quirks and -origin, -quirks
+// defaulting to the preset of the mode's own machine, and run and debug
+// take -speed, -scale and -keys too.
 package main
 
-import "github.com/Nuxij/goch8p/mmu"
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// command runs a subcommand with its arguments, returning the exit code
+type command func(args []string, stdout, stderr io.Writer) int
+
+var commands = map[string]command{
+	"run":      runCommand,
+	"debug":    debugCommand,
+	"disasm":   disasmCommand,
+	"asm":      asmCommand,
+	"info":     infoCommand,
+	"lint":     lintCommand,
+	"aot":      aotCommand,
+	"trace":    traceCommand,
+	"profile":  profileCommand,
+	"coverage": coverageCommand,
+}
 
 func main() {
-	realmemoryblock := mmu.NewByteMemory(0x1000)
-	mapped := &mmu.MappedMemory{}
-	
-	mapped.AddBlock(0x0, realmemoryblock)
-	mapped.Read(0x1)
-}
\ No newline at end of file
+	if len(os.Args) < 2 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	run, ok := commands[os.Args[1]]
+	if !ok {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	os.Exit(run(os.Args[2:], os.Stdout, os.Stderr))
+}
+
+func usage(w io.Writer) {
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(w, "usage: goch8p <command> [flags]\ncommands: %v\n", names)
+}
+
+// parseInterspersed parses flags wherever they come among the arguments,
+// returning the arguments that are not flags
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
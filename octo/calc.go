@@ -0,0 +1,147 @@
+package octo
+
+import "math"
+
+// unaries are the prefix operators :calc understands
+var unaries = map[string]func(float64) float64{
+	"-":     func(a float64) float64 { return -a },
+	"~":     func(a float64) float64 { return float64(^int(a)) },
+	"!":     func(a float64) float64 { return truth(a == 0) },
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+	"exp":   math.Exp,
+	"log":   math.Log,
+	"abs":   math.Abs,
+	"sqrt":  math.Sqrt,
+	"ceil":  math.Ceil,
+	"floor": math.Floor,
+	"sign": func(a float64) float64 {
+		switch {
+		case a < 0:
+			return -1
+		case a > 0:
+			return 1
+		}
+		return 0
+	},
+}
+
+// binaries are the infix operators :calc understands
+var binaries = map[string]func(a, b float64) float64{
+	"+":   func(a, b float64) float64 { return a + b },
+	"-":   func(a, b float64) float64 { return a - b },
+	"*":   func(a, b float64) float64 { return a * b },
+	"/":   func(a, b float64) float64 { return a / b },
+	"%":   func(a, b float64) float64 { return math.Mod(a, b) },
+	"&":   func(a, b float64) float64 { return float64(int(a) & int(b)) },
+	"|":   func(a, b float64) float64 { return float64(int(a) | int(b)) },
+	"^":   func(a, b float64) float64 { return float64(int(a) ^ int(b)) },
+	"<<":  func(a, b float64) float64 { return float64(int(a) << uint(int(b)&63)) },
+	">>":  func(a, b float64) float64 { return float64(int(a) >> uint(int(b)&63)) },
+	"pow": math.Pow,
+	"min": math.Min,
+	"max": math.Max,
+	"<":   func(a, b float64) float64 { return truth(a < b) },
+	"<=":  func(a, b float64) float64 { return truth(a <= b) },
+	">":   func(a, b float64) float64 { return truth(a > b) },
+	">=":  func(a, b float64) float64 { return truth(a >= b) },
+	"==":  func(a, b float64) float64 { return truth(a == b) },
+	"!=":  func(a, b float64) float64 { return truth(a != b) },
+}
+
+func truth(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// calc evaluates the tokens of a { } expression. As in Octo, binary
+// operators all share one precedence and group to the right, so
+// { 2 * 3 + 1 } is 8; parentheses group as usual. Names are constants,
+// labels, HERE for the current address, PI and E, and @ reads a byte
+// already compiled.
+func (c *compiler) calc(tokens []token) (float64, error) {
+	e := &evaluator{compiler: c, tokens: tokens}
+	value, err := e.expression()
+	if err != nil {
+		return 0, err
+	}
+	if e.pos < len(tokens) {
+		return 0, c.fail(tokens[e.pos], "unexpected %q in expression", tokens[e.pos].text)
+	}
+	return value, nil
+}
+
+type evaluator struct {
+	compiler *compiler
+	tokens   []token
+	pos      int
+}
+
+func (e *evaluator) expression() (float64, error) {
+	left, err := e.term()
+	if err != nil {
+		return 0, err
+	}
+	if e.pos >= len(e.tokens) {
+		return left, nil
+	}
+	operator, ok := binaries[e.tokens[e.pos].text]
+	if !ok {
+		return left, nil
+	}
+	e.pos++
+	right, err := e.expression()
+	if err != nil {
+		return 0, err
+	}
+	return operator(left, right), nil
+}
+
+func (e *evaluator) term() (float64, error) {
+	if e.pos >= len(e.tokens) {
+		return 0, e.compiler.fail(e.tokens[len(e.tokens)-1], "expression ends early")
+	}
+	t := e.tokens[e.pos]
+	e.pos++
+	if t.text == "(" {
+		value, err := e.expression()
+		if err != nil {
+			return 0, err
+		}
+		if e.pos >= len(e.tokens) || e.tokens[e.pos].text != ")" {
+			return 0, e.compiler.fail(t, "unclosed (")
+		}
+		e.pos++
+		return value, nil
+	}
+	if t.text == "@" {
+		addr, err := e.term()
+		if err != nil {
+			return 0, err
+		}
+		return float64(e.compiler.peek(int(addr))), nil
+	}
+	if operator, ok := unaries[t.text]; ok {
+		value, err := e.term()
+		if err != nil {
+			return 0, err
+		}
+		return operator(value), nil
+	}
+	switch t.text {
+	case "HERE":
+		return float64(e.compiler.here), nil
+	case "PI":
+		return math.Pi, nil
+	case "E":
+		return math.E, nil
+	}
+	if value, ok := e.compiler.constants[t.text]; ok {
+		return value, nil
+	}
+	value, err := e.compiler.value(t)
+	return float64(value), err
+}
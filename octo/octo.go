@@ -0,0 +1,1063 @@
+// Package octo compiles Octo, the high level assembly language most
+// modern CHIP-8 and XO-CHIP programs are written in, into ROMs
+package octo
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Nuxij/goch8p/asm"
+	"github.com/Nuxij/goch8p/cpu"
+)
+
+// Origin is where Octo programs are loaded and start running
+const Origin = cpu.DefaultOrigin
+
+// Breakpoint is a :breakpoint, asking debuggers to stop at Addr
+type Breakpoint struct {
+	Name string
+	Addr uint16
+}
+
+// Monitor is a :monitor, asking debuggers to show the memory at Addr.
+// It shows Length bytes in hex or, if Format is set, the bytes Format
+// consumes.
+type Monitor struct {
+	// Name is the address as written, such as a label
+	Name   string
+	Addr   uint16
+	Length int
+	Format string
+}
+
+// Size returns how many bytes the monitor shows
+func (m Monitor) Size() int {
+	if m.Format == "" {
+		return m.Length
+	}
+	size := 0
+	for n := 0; n < len(m.Format)-1; n++ {
+		if m.Format[n] == '%' {
+			if m.Format[n+1] != '%' {
+				size++
+			}
+			n++
+		}
+	}
+	return size
+}
+
+// Render writes data, Size bytes from Addr, as the monitor shows it.
+// Format may use %i for a byte in decimal, %x in hex, %b in binary and
+// %% for a percent sign.
+func (m Monitor) Render(data []byte) string {
+	if m.Format == "" {
+		return fmt.Sprintf("% X", data)
+	}
+	var b strings.Builder
+	for n := 0; n < len(m.Format); n++ {
+		if m.Format[n] != '%' || n+1 == len(m.Format) {
+			b.WriteByte(m.Format[n])
+			continue
+		}
+		n++
+		if m.Format[n] == '%' {
+			b.WriteByte('%')
+			continue
+		}
+		if len(data) == 0 {
+			break
+		}
+		switch m.Format[n] {
+		case 'x':
+			fmt.Fprintf(&b, "%02X", data[0])
+		case 'b':
+			fmt.Fprintf(&b, "%08b", data[0])
+		default:
+			fmt.Fprintf(&b, "%d", data[0])
+		}
+		data = data[1:]
+	}
+	return b.String()
+}
+
+// Program is a compiled Octo program with its debug metadata
+type Program struct {
+	asm.Program
+	Breakpoints []Breakpoint
+	Monitors    []Monitor
+}
+
+// SourceInvalid is returned compiling Octo that has a mistake in it
+type SourceInvalid struct {
+	file   string
+	line   int
+	reason string
+}
+
+func (s SourceInvalid) Error() string {
+	return fmt.Sprintf("%s:%d: %s", s.file, s.line, s.reason)
+}
+
+// Compile compiles Octo source, naming it name in errors. It follows
+// Octo's language: labels with : name, a main label to start at,
+// register aliases, :const, :calc, :macro, :next, :org, :byte,
+// :pointer, :unpack, structured if/then, if/begin/else/end and
+// loop/while/again, along with :breakpoint and :monitor for debuggers.
+func Compile(name string, source []byte) (*Program, error) {
+	tokens := tokenize(string(source))
+	// the first pass finds every label, for the second to refer forwards
+	first := newCompiler(name, tokens, nil)
+	if err := first.compile(); err != nil {
+		return nil, err
+	}
+	second := newCompiler(name, tokens, first.labels)
+	if err := second.compile(); err != nil {
+		return nil, err
+	}
+	for label, addr := range second.labels {
+		if first.labels[label] != addr {
+			return nil, SourceInvalid{name, second.defined[label], fmt.Sprintf("%s moves depending on labels defined after it", label)}
+		}
+	}
+	return second.program(), nil
+}
+
+// CompileFile compiles the Octo source file at path
+func CompileFile(path string) (*Program, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(path, source)
+}
+
+type token struct {
+	text string
+	line int
+}
+
+// tokenize splits source on whitespace, dropping # comments and keeping
+// "quoted strings" whole
+func tokenize(source string) []token {
+	var tokens []token
+	for n, line := range strings.Split(source, "\n") {
+		for at := 0; at < len(line); {
+			switch c := line[at]; {
+			case c == '#':
+				at = len(line)
+			case c == ' ' || c == '\t' || c == '\r':
+				at++
+			case c == '"':
+				end := len(line)
+				if close := strings.IndexByte(line[at+1:], '"'); close >= 0 {
+					end = at + close + 2
+				}
+				tokens = append(tokens, token{line[at:end], n + 1})
+				at = end
+			default:
+				end := strings.IndexAny(line[at:], " \t\r")
+				if end < 0 {
+					end = len(line) - at
+				}
+				tokens = append(tokens, token{line[at : at+end], n + 1})
+				at += end
+			}
+		}
+	}
+	return tokens
+}
+
+// macro is a :macro, whose body has its arguments substituted when used
+type macro struct {
+	args []string
+	body []token
+}
+
+// block is an open if/begin, else or loop
+type block struct {
+	kind string
+	// addr is the loop's start, or the jump to patch at the block's end
+	addr int
+	// whiles are the jumps out of a loop, patched at again
+	whiles []int
+}
+
+type compiler struct {
+	name   string
+	tokens []token
+	pos    int
+	// last is the token most recently taken, for errors at the end
+	last token
+	rom  []byte
+	used []bool
+	here int
+	// labels are those defined so far and previous those the first pass
+	// found, with defined giving the line each was defined on
+	labels    map[string]int
+	previous  map[string]int
+	defined   map[string]int
+	constants map[string]float64
+	aliases   map[string]int
+	macros    map[string]macro
+	blocks    []block
+	// next is a :next label waiting for the next instruction
+	next *token
+	// jumpsToMain says 0x200 holds a jump to main, which main is not
+	// right after
+	jumpsToMain bool
+	expansions  int
+	breakpoints []Breakpoint
+	monitors    []Monitor
+}
+
+func newCompiler(name string, tokens []token, previous map[string]int) *compiler {
+	return &compiler{
+		name:      name,
+		tokens:    append([]token{}, tokens...),
+		here:      Origin,
+		labels:    make(map[string]int),
+		previous:  previous,
+		defined:   make(map[string]int),
+		constants: make(map[string]float64),
+		aliases:   make(map[string]int),
+		macros:    make(map[string]macro),
+	}
+}
+
+func (c *compiler) fail(t token, format string, args ...interface{}) error {
+	return SourceInvalid{c.name, t.line, fmt.Sprintf(format, args...)}
+}
+
+func (c *compiler) compile() error {
+	// reserve 0x200 for a jump to main, dropped if main comes first
+	c.jumpsToMain = true
+	if err := c.word(0); err != nil {
+		return err
+	}
+	for c.pos < len(c.tokens) {
+		if err := c.statement(c.take()); err != nil {
+			return err
+		}
+	}
+	if len(c.blocks) > 0 {
+		return c.fail(c.last, "%s is never closed", c.blocks[len(c.blocks)-1].kind)
+	}
+	if c.next != nil {
+		return c.fail(*c.next, ":next %s is not followed by an instruction", c.next.text)
+	}
+	main, ok := c.labels["main"]
+	if !ok {
+		return c.fail(c.last, "there is no main label to start at")
+	}
+	if c.jumpsToMain {
+		c.patch(Origin, 0x1000|main)
+	}
+	return nil
+}
+
+func (c *compiler) program() *Program {
+	p := &Program{
+		Program: asm.Program{
+			Origin: Origin,
+			ROM:    c.rom,
+			Labels: make(map[string]uint16),
+		},
+		Breakpoints: c.breakpoints,
+		Monitors:    c.monitors,
+	}
+	for name, addr := range c.labels {
+		p.Labels[name] = uint16(addr)
+	}
+	return p
+}
+
+// take returns the next token
+func (c *compiler) take() token {
+	if c.pos < len(c.tokens) {
+		c.last = c.tokens[c.pos]
+		c.pos++
+		return c.last
+	}
+	// an empty token at the end fails every check with the last line
+	return token{line: c.last.line}
+}
+
+// peek returns the byte compiled at addr, or zero
+func (c *compiler) peek(addr int) byte {
+	if at := addr - Origin; at >= 0 && at < len(c.rom) {
+		return c.rom[at]
+	}
+	return 0
+}
+
+// emit compiles bytes at the current address
+func (c *compiler) emit(bytes ...byte) error {
+	for _, b := range bytes {
+		if c.here >= 0x10000 {
+			return c.fail(c.last, "program is past the end of memory")
+		}
+		at := c.here - Origin
+		if at < 0 {
+			return c.fail(c.last, "%X is below the program origin %X", c.here, Origin)
+		}
+		for len(c.rom) <= at {
+			c.rom, c.used = append(c.rom, 0), append(c.used, false)
+		}
+		if c.used[at] {
+			return c.fail(c.last, "%X is compiled twice", c.here)
+		}
+		c.rom[at], c.used[at] = b, true
+		c.here++
+	}
+	return nil
+}
+
+func (c *compiler) word(w int) error {
+	return c.emit(byte(w>>8), byte(w))
+}
+
+// instruction compiles an opcode, placing any :next label on its second
+// byte
+func (c *compiler) instruction(opcode int) error {
+	if err := c.placeNext(1); err != nil {
+		return err
+	}
+	return c.word(opcode)
+}
+
+func (c *compiler) placeNext(offset int) error {
+	if c.next == nil {
+		return nil
+	}
+	next := *c.next
+	c.next = nil
+	return c.label(next, c.here+offset)
+}
+
+// patch replaces the address of the jump at addr
+func (c *compiler) patch(addr, opcode int) {
+	at := addr - Origin
+	c.rom[at], c.rom[at+1] = byte(opcode>>8), byte(opcode)
+}
+
+func (c *compiler) label(t token, addr int) error {
+	if err := c.checkName(t); err != nil {
+		return err
+	}
+	c.labels[t.text] = addr
+	c.defined[t.text] = t.line
+	return nil
+}
+
+// checkName fails if t cannot name something new
+func (c *compiler) checkName(t token) error {
+	if t.text == "" {
+		return c.fail(t, "a name is missing")
+	}
+	if _, ok := number(t.text); ok || keywords[t.text] {
+		return c.fail(t, "%s cannot be used as a name", t.text)
+	}
+	if _, ok := c.register(t); ok {
+		return c.fail(t, "%s is a register", t.text)
+	}
+	_, label := c.labels[t.text]
+	_, constant := c.constants[t.text]
+	_, isMacro := c.macros[t.text]
+	if label || constant || isMacro {
+		return c.fail(t, "%s is already defined", t.text)
+	}
+	return nil
+}
+
+// keywords are the words statements start or go on with
+var keywords = map[string]bool{
+	":": true, ":=": true, "+=": true, "-=": true, "=-": true, "|=": true, "&=": true,
+	"^=": true, ">>=": true, "<<=": true, "==": true, "!=": true, "<": true, ">": true,
+	"<=": true, ">=": true, "key": true, "-key": true, "hex": true, "bighex": true,
+	"random": true, "delay": true, "buzzer": true, "pitch": true, "i": true, "long": true,
+	"return": true, ";": true, "clear": true, "bcd": true, "save": true, "load": true,
+	"saveflags": true, "loadflags": true, "sprite": true, "jump": true, "jump0": true,
+	"if": true, "then": true, "begin": true, "else": true, "end": true, "loop": true,
+	"again": true, "while": true, "hires": true, "lores": true, "exit": true,
+	"scroll-up": true, "scroll-down": true, "scroll-left": true, "scroll-right": true,
+	"plane": true, "audio": true, "{": true, "}": true,
+}
+
+// number parses a number literal in decimal, 0x hex or 0b binary
+func number(text string) (int, bool) {
+	value, err := strconv.ParseInt(text, 0, 64)
+	return int(value), err == nil
+}
+
+// register returns the register t names, directly or by :alias
+func (c *compiler) register(t token) (int, bool) {
+	if x, ok := c.aliases[t.text]; ok {
+		return x, true
+	}
+	if len(t.text) == 2 && (t.text[0] == 'v' || t.text[0] == 'V') {
+		if x, err := strconv.ParseUint(t.text[1:], 16, 8); err == nil {
+			return int(x), true
+		}
+	}
+	return 0, false
+}
+
+func (c *compiler) takeRegister() (int, error) {
+	t := c.take()
+	if x, ok := c.register(t); ok {
+		return x, nil
+	}
+	return 0, c.fail(t, "want a register, not %q", t.text)
+}
+
+// value resolves a number, constant or label. The first pass takes
+// labels it has not seen yet to be zero, leaving the second to resolve
+// them.
+func (c *compiler) value(t token) (int, error) {
+	if value, ok := number(t.text); ok {
+		return value, nil
+	}
+	if value, ok := c.constants[t.text]; ok {
+		return int(math.Floor(value)), nil
+	}
+	if addr, ok := c.labels[t.text]; ok {
+		return addr, nil
+	}
+	if addr, ok := c.previous[t.text]; ok {
+		return addr, nil
+	}
+	if c.previous == nil && t.text != "" && !keywords[t.text] {
+		return 0, nil
+	}
+	if _, ok := c.register(t); ok {
+		return 0, c.fail(t, "want a value, not the register %s", t.text)
+	}
+	return 0, c.fail(t, "undefined name %q", t.text)
+}
+
+// takeValue takes a value, failing if it falls outside min to max
+func (c *compiler) takeValue(min, max int) (int, error) {
+	return c.bounded(c.take(), min, max)
+}
+
+// bounded resolves t, failing if it falls outside min to max
+func (c *compiler) bounded(t token, min, max int) (int, error) {
+	value, err := c.value(t)
+	if err != nil {
+		return 0, err
+	}
+	if value < min || value > max {
+		return 0, c.fail(t, "%s is %d, out of range %d to %d", t.text, value, min, max)
+	}
+	return value, nil
+}
+
+// byteOf resolves t as a byte, negative values wrapping around
+func (c *compiler) byteOf(t token) (int, error) {
+	value, err := c.bounded(t, -0x80, 0xFF)
+	return value & 0xFF, err
+}
+
+func (c *compiler) takeByte() (int, error) {
+	return c.byteOf(c.take())
+}
+
+func (c *compiler) takeAddr() (int, error) {
+	return c.takeValue(0, 0xFFF)
+}
+
+// expect takes a token, failing if it is not want
+func (c *compiler) expect(want string) error {
+	if t := c.take(); t.text != want {
+		return c.fail(t, "want %q, not %q", want, t.text)
+	}
+	return nil
+}
+
+// braces takes the tokens of a { } group, nested groups included
+func (c *compiler) braces() ([]token, error) {
+	if err := c.expect("{"); err != nil {
+		return nil, err
+	}
+	var tokens []token
+	for depth := 1; ; {
+		t := c.take()
+		switch t.text {
+		case "":
+			return nil, c.fail(t, "{ is never closed")
+		case "{":
+			depth++
+		case "}":
+			if depth--; depth == 0 {
+				return tokens, nil
+			}
+		}
+		tokens = append(tokens, t)
+	}
+}
+
+func (c *compiler) statement(t token) error {
+	switch t.text {
+	case ":":
+		return c.define(c.take())
+	case ":alias":
+		return c.alias()
+	case ":const":
+		name := c.take()
+		if err := c.checkName(name); err != nil {
+			return err
+		}
+		value, err := c.value(c.take())
+		c.constants[name.text] = float64(value)
+		return err
+	case ":calc":
+		name := c.take()
+		if _, ok := c.constants[name.text]; !ok {
+			if err := c.checkName(name); err != nil {
+				return err
+			}
+		}
+		tokens, err := c.braces()
+		if err != nil {
+			return err
+		}
+		value, err := c.calc(tokens)
+		c.constants[name.text] = value
+		return err
+	case ":macro":
+		return c.macro()
+	case ":next":
+		name := c.take()
+		c.next = &name
+		return nil
+	case ":org":
+		addr, err := c.takeValue(Origin, 0xFFFF)
+		c.here = addr
+		return err
+	case ":byte":
+		if c.pos < len(c.tokens) && c.tokens[c.pos].text == "{" {
+			tokens, err := c.braces()
+			if err != nil {
+				return err
+			}
+			value, err := c.calc(tokens)
+			if err != nil {
+				return err
+			}
+			return c.emit(byte(int(math.Floor(value))))
+		}
+		value, err := c.takeByte()
+		if err != nil {
+			return err
+		}
+		return c.emit(byte(value))
+	case ":pointer":
+		value, err := c.takeValue(0, 0xFFFF)
+		if err != nil {
+			return err
+		}
+		return c.word(value)
+	case ":unpack":
+		return c.unpack()
+	case ":call":
+		addr, err := c.takeAddr()
+		if err != nil {
+			return err
+		}
+		return c.instruction(0x2000 | addr)
+	case ":breakpoint":
+		name := c.take()
+		c.breakpoints = append(c.breakpoints, Breakpoint{name.text, uint16(c.here)})
+		return nil
+	case ":monitor":
+		return c.monitor()
+	case "return", ";":
+		return c.instruction(0x00EE)
+	case "clear":
+		return c.instruction(0x00E0)
+	case "hires":
+		return c.instruction(0x00FF)
+	case "lores":
+		return c.instruction(0x00FE)
+	case "exit":
+		return c.instruction(0x00FD)
+	case "scroll-right":
+		return c.instruction(0x00FB)
+	case "scroll-left":
+		return c.instruction(0x00FC)
+	case "scroll-down", "scroll-up":
+		n, err := c.takeValue(0, 0xF)
+		if err != nil {
+			return err
+		}
+		if t.text == "scroll-down" {
+			return c.instruction(0x00C0 | n)
+		}
+		return c.instruction(0x00D0 | n)
+	case "audio":
+		return c.instruction(0xF002)
+	case "plane":
+		n, err := c.takeValue(0, 0xF)
+		if err != nil {
+			return err
+		}
+		return c.instruction(0xF001 | n<<8)
+	case "bcd", "saveflags", "loadflags":
+		x, err := c.takeRegister()
+		if err != nil {
+			return err
+		}
+		return c.instruction(map[string]int{"bcd": 0xF033, "saveflags": 0xF075, "loadflags": 0xF085}[t.text] | x<<8)
+	case "save", "load":
+		return c.saveLoad(t.text == "save")
+	case "sprite":
+		x, err := c.takeRegister()
+		if err != nil {
+			return err
+		}
+		y, err := c.takeRegister()
+		if err != nil {
+			return err
+		}
+		n, err := c.takeValue(0, 0xF)
+		if err != nil {
+			return err
+		}
+		return c.instruction(0xD000 | x<<8 | y<<4 | n)
+	case "jump", "jump0":
+		addr, err := c.takeAddr()
+		if err != nil {
+			return err
+		}
+		if t.text == "jump0" {
+			return c.instruction(0xB000 | addr)
+		}
+		return c.instruction(0x1000 | addr)
+	case "delay", "buzzer", "pitch":
+		if err := c.expect(":="); err != nil {
+			return err
+		}
+		x, err := c.takeRegister()
+		if err != nil {
+			return err
+		}
+		return c.instruction(map[string]int{"delay": 0xF015, "buzzer": 0xF018, "pitch": 0xF03A}[t.text] | x<<8)
+	case "i":
+		return c.index()
+	case "if":
+		return c.conditional()
+	case "else":
+		return c.elseBlock(t)
+	case "end":
+		return c.endBlock(t)
+	case "loop":
+		c.blocks = append(c.blocks, block{kind: "loop", addr: c.here})
+		return nil
+	case "while":
+		return c.while(t)
+	case "again":
+		return c.again(t)
+	}
+	if m, ok := c.macros[t.text]; ok {
+		return c.expand(t, m)
+	}
+	if x, ok := c.register(t); ok {
+		return c.assign(x)
+	}
+	if value, ok := number(t.text); ok {
+		if value < -0x80 || value > 0xFF {
+			return c.fail(t, "%s is out of range for a byte", t.text)
+		}
+		return c.emit(byte(value))
+	}
+	if keywords[t.text] || strings.HasPrefix(t.text, ":") {
+		return c.fail(t, "unexpected %q", t.text)
+	}
+	// anything else names a subroutine to call
+	addr, err := c.value(t)
+	if err != nil {
+		return err
+	}
+	if addr > 0xFFF {
+		return c.fail(t, "cannot call %s at %X, past 0xFFF", t.text, addr)
+	}
+	return c.instruction(0x2000 | addr)
+}
+
+// define places a label at the current address, main being where the
+// program starts
+func (c *compiler) define(name token) error {
+	if name.text == "main" && c.here == Origin+2 && len(c.labels) == 0 && len(c.rom) == 2 {
+		// main comes first, so needs no jump to it
+		c.rom, c.used, c.here = c.rom[:0], c.used[:0], Origin
+		c.jumpsToMain = false
+	}
+	return c.label(name, c.here)
+}
+
+func (c *compiler) alias() error {
+	name := c.take()
+	if _, ok := c.aliases[name.text]; !ok {
+		if err := c.checkName(name); err != nil {
+			return err
+		}
+	}
+	x, err := c.takeRegister()
+	c.aliases[name.text] = x
+	return err
+}
+
+func (c *compiler) macro() error {
+	name := c.take()
+	if err := c.checkName(name); err != nil {
+		return err
+	}
+	var m macro
+	for c.pos < len(c.tokens) && c.tokens[c.pos].text != "{" {
+		m.args = append(m.args, c.take().text)
+	}
+	body, err := c.braces()
+	m.body = body
+	c.macros[name.text] = m
+	return err
+}
+
+// expand replaces a macro's use with its body, arguments substituted
+func (c *compiler) expand(t token, m macro) error {
+	if c.expansions++; c.expansions > 100000 {
+		return c.fail(t, "macro %s expands without end", t.text)
+	}
+	args := make(map[string]token, len(m.args))
+	for _, arg := range m.args {
+		value := c.take()
+		if value.text == "" {
+			return c.fail(t, "macro %s needs %d arguments", t.text, len(m.args))
+		}
+		args[arg] = value
+	}
+	body := make([]token, 0, len(m.body)+len(c.tokens)-c.pos)
+	for _, b := range m.body {
+		if arg, ok := args[b.text]; ok {
+			b.text = arg.text
+		}
+		body = append(body, b)
+	}
+	c.tokens, c.pos = append(body, c.tokens[c.pos:]...), 0
+	return nil
+}
+
+// unpack loads the 12 bit address of a label into v0 and v1, the high
+// nibble of v0 taken from a constant or, with long, the full 16 bits
+func (c *compiler) unpack() error {
+	var high int
+	if c.pos < len(c.tokens) && c.tokens[c.pos].text == "long" {
+		c.take()
+	} else {
+		nibble, err := c.takeValue(0, 0xF)
+		if err != nil {
+			return err
+		}
+		high = nibble << 12
+	}
+	addr, err := c.takeValue(0, 0xFFFF)
+	if err != nil {
+		return err
+	}
+	addr |= high
+	if err := c.instruction(0x6000 | addr>>8&0xFF); err != nil {
+		return err
+	}
+	return c.instruction(0x6100 | addr&0xFF)
+}
+
+func (c *compiler) monitor() error {
+	at := c.take()
+	addr, err := c.value(at)
+	if err != nil {
+		return err
+	}
+	m := Monitor{Name: at.text, Addr: uint16(addr)}
+	format := c.take()
+	if strings.HasPrefix(format.text, `"`) {
+		m.Format = strings.Trim(format.text, `"`)
+	} else if m.Length, err = c.value(format); err != nil {
+		return err
+	}
+	c.monitors = append(c.monitors, m)
+	return nil
+}
+
+// saveLoad compiles save vx, load vx and XO-CHIP's save vx - vy and
+// load vx - vy
+func (c *compiler) saveLoad(save bool) error {
+	x, err := c.takeRegister()
+	if err != nil {
+		return err
+	}
+	if c.pos < len(c.tokens) && c.tokens[c.pos].text == "-" {
+		c.take()
+		y, err := c.takeRegister()
+		if err != nil {
+			return err
+		}
+		if save {
+			return c.instruction(0x5002 | x<<8 | y<<4)
+		}
+		return c.instruction(0x5003 | x<<8 | y<<4)
+	}
+	if save {
+		return c.instruction(0xF055 | x<<8)
+	}
+	return c.instruction(0xF065 | x<<8)
+}
+
+// index compiles assignments to i
+func (c *compiler) index() error {
+	switch t := c.take(); t.text {
+	case "+=":
+		x, err := c.takeRegister()
+		if err != nil {
+			return err
+		}
+		return c.instruction(0xF01E | x<<8)
+	case ":=":
+	default:
+		return c.fail(t, "want := or += after i, not %q", t.text)
+	}
+	var next string
+	if c.pos < len(c.tokens) {
+		next = c.tokens[c.pos].text
+	}
+	switch next {
+	case "hex", "bighex":
+		c.take()
+		x, err := c.takeRegister()
+		if err != nil {
+			return err
+		}
+		if next == "hex" {
+			return c.instruction(0xF029 | x<<8)
+		}
+		return c.instruction(0xF030 | x<<8)
+	case "long":
+		c.take()
+		addr, err := c.takeValue(0, 0xFFFF)
+		if err != nil {
+			return err
+		}
+		if err := c.placeNext(2); err != nil {
+			return err
+		}
+		if err := c.word(0xF000); err != nil {
+			return err
+		}
+		return c.word(addr)
+	}
+	addr, err := c.takeAddr()
+	if err != nil {
+		return err
+	}
+	return c.instruction(0xA000 | addr)
+}
+
+// alu are the register to register operators, by their 8XYN's N
+var alu = map[string]int{":=": 0x0, "|=": 0x1, "&=": 0x2, "^=": 0x3, "+=": 0x4, "-=": 0x5, ">>=": 0x6, "=-": 0x7, "<<=": 0xE}
+
+// assign compiles the statements that start with a register
+func (c *compiler) assign(x int) error {
+	operator := c.take()
+	if _, ok := alu[operator.text]; !ok {
+		return c.fail(operator, "unknown operator %q", operator.text)
+	}
+	source := c.take()
+	if y, ok := c.register(source); ok {
+		return c.instruction(0x8000 | x<<8 | y<<4 | alu[operator.text])
+	}
+	switch operator.text {
+	case ":=":
+		switch source.text {
+		case "random":
+			mask, err := c.takeByte()
+			if err != nil {
+				return err
+			}
+			return c.instruction(0xC000 | x<<8 | mask)
+		case "key":
+			return c.instruction(0xF00A | x<<8)
+		case "delay":
+			return c.instruction(0xF007 | x<<8)
+		}
+		value, err := c.byteOf(source)
+		if err != nil {
+			return err
+		}
+		return c.instruction(0x6000 | x<<8 | value)
+	case "+=", "-=":
+		value, err := c.byteOf(source)
+		if err != nil {
+			return err
+		}
+		if operator.text == "-=" {
+			value = -value & 0xFF
+		}
+		return c.instruction(0x7000 | x<<8 | value)
+	}
+	return c.fail(source, "%s needs a register, not %q", operator.text, source.text)
+}
+
+// negations pairs each comparison with its opposite
+var negations = map[string]string{
+	"==": "!=", "!=": "==", "key": "-key", "-key": "key",
+	"<": ">=", ">=": "<", ">": "<=", "<=": ">",
+}
+
+// condition compiles a comparison so that the instruction after it only
+// runs if the comparison holds, or if negate, only if it does not
+func (c *compiler) condition(negate bool) error {
+	x, err := c.takeRegister()
+	if err != nil {
+		return err
+	}
+	operator := c.take()
+	comparison := operator.text
+	if _, ok := negations[comparison]; !ok {
+		return c.fail(operator, "unknown comparison %q", comparison)
+	}
+	if negate {
+		comparison = negations[comparison]
+	}
+	switch comparison {
+	case "key":
+		return c.instruction(0xE0A1 | x<<8)
+	case "-key":
+		return c.instruction(0xE09E | x<<8)
+	}
+	source := c.take()
+	y, isRegister := c.register(source)
+	var value int
+	if !isRegister {
+		if value, err = c.byteOf(source); err != nil {
+			return err
+		}
+	}
+	switch comparison {
+	case "==":
+		// skip the next instruction when the comparison fails
+		if isRegister {
+			return c.instruction(0x9000 | x<<8 | y<<4)
+		}
+		return c.instruction(0x4000 | x<<8 | value)
+	case "!=":
+		if isRegister {
+			return c.instruction(0x5000 | x<<8 | y<<4)
+		}
+		return c.instruction(0x3000 | x<<8 | value)
+	}
+	// the ordered comparisons subtract in vf, whose flag then says
+	// whether vx >= y for < and >=, or y >= vx for > and <=
+	load := 0x6F00 | value
+	if isRegister {
+		load = 0x8F00 | y<<4
+	}
+	if err := c.instruction(load); err != nil {
+		return err
+	}
+	subtract, skip := 0x8F07|x<<4, 0x3F01
+	if comparison == ">" || comparison == "<=" {
+		subtract = 0x8F05 | x<<4
+	}
+	if comparison == ">=" || comparison == "<=" {
+		skip = 0x3F00
+	}
+	if err := c.instruction(subtract); err != nil {
+		return err
+	}
+	return c.instruction(skip)
+}
+
+// conditional compiles if ... then and if ... begin
+func (c *compiler) conditional() error {
+	start := c.pos
+	// find out which form it is before compiling the comparison
+	for c.pos < len(c.tokens) && c.tokens[c.pos].text != "then" && c.tokens[c.pos].text != "begin" {
+		c.pos++
+	}
+	form := c.take()
+	c.pos = start
+	switch form.text {
+	case "then":
+		if err := c.condition(false); err != nil {
+			return err
+		}
+		return c.expect("then")
+	case "begin":
+		if err := c.condition(true); err != nil {
+			return err
+		}
+		if err := c.expect("begin"); err != nil {
+			return err
+		}
+		c.blocks = append(c.blocks, block{kind: "begin", addr: c.here})
+		return c.instruction(0x1000)
+	}
+	return c.fail(form, "if needs then or begin")
+}
+
+func (c *compiler) elseBlock(t token) error {
+	if len(c.blocks) == 0 || c.blocks[len(c.blocks)-1].kind != "begin" {
+		return c.fail(t, "else without if ... begin")
+	}
+	b := &c.blocks[len(c.blocks)-1]
+	jump := c.here
+	if err := c.instruction(0x1000); err != nil {
+		return err
+	}
+	c.patch(b.addr, 0x1000|c.here)
+	b.kind, b.addr = "else", jump
+	return nil
+}
+
+func (c *compiler) endBlock(t token) error {
+	if len(c.blocks) == 0 || c.blocks[len(c.blocks)-1].kind == "loop" {
+		return c.fail(t, "end without if ... begin")
+	}
+	b := c.blocks[len(c.blocks)-1]
+	c.blocks = c.blocks[:len(c.blocks)-1]
+	c.patch(b.addr, 0x1000|c.here)
+	return nil
+}
+
+// while leaves the innermost loop unless the comparison holds
+func (c *compiler) while(t token) error {
+	n := len(c.blocks) - 1
+	for n >= 0 && c.blocks[n].kind != "loop" {
+		n--
+	}
+	if n < 0 {
+		return c.fail(t, "while outside a loop")
+	}
+	if err := c.condition(true); err != nil {
+		return err
+	}
+	c.blocks[n].whiles = append(c.blocks[n].whiles, c.here)
+	return c.instruction(0x1000)
+}
+
+func (c *compiler) again(t token) error {
+	if len(c.blocks) == 0 || c.blocks[len(c.blocks)-1].kind != "loop" {
+		return c.fail(t, "again without loop")
+	}
+	b := c.blocks[len(c.blocks)-1]
+	c.blocks = c.blocks[:len(c.blocks)-1]
+	if err := c.instruction(0x1000 | b.addr); err != nil {
+		return err
+	}
+	for _, while := range b.whiles {
+		c.patch(while, 0x1000|c.here)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package octo
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Nuxij/goch8p/asm"
+	"github.com/Nuxij/goch8p/cpu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompile(t *testing.T) {
+	p, err := Compile("game.8o", []byte(`
+# the score lives in v3
+:alias score v3
+:const SPEED 2
+:calc STEP { SPEED * 2 + 1 }   # no precedence, so 2 * ( 2 + 1 )
+:macro draw X Y { sprite X Y 3 }
+
+: main
+	score := SPEED
+	v0 += STEP
+	v0 -= 1
+	i := glyph
+	draw v0 score
+	score += v0
+	score =- v0
+	v1 := random 0x0F
+	vf := key
+	bcd score
+	save v2
+	i := long glyph
+	i += v1
+	i := hex v0
+	v2 >>= v2
+	:unpack 0xA glyph
+	finish
+	jump main
+: finish
+	delay := v0
+	buzzer := v0
+	;
+: glyph
+	0xF0 0x90 -1
+`))
+	assert.NoError(t, err)
+	want := asm.MustAssemble(`
+main:
+    LD V3, 2
+    ADD V0, 6
+    ADD V0, -1
+    LD I, glyph
+    DRW V0, V3, 3
+    ADD V3, V0
+    SUBN V3, V0
+    RND V1, 0x0F
+    LD VF, K
+    LD B, V3
+    LD [I], V2
+    LD I, LONG glyph
+    ADD I, V1
+    LD F, V0
+    SHR V2, V2
+    LD V0, 0xA0 | glyph >> 8
+    LD V1, glyph & 0xFF
+    CALL finish
+    JP main
+finish:
+    LD DT, V0
+    LD ST, V0
+    RET
+glyph:
+    db 0xF0, 0x90, 0xFF
+`, asm.Options{Mode: cpu.ModeXOChip})
+	assert.Equal(t, want.ROM, p.ROM)
+	assert.Equal(t, want.Labels, p.Labels)
+}
+
+func TestCompile_main(t *testing.T) {
+	p, err := Compile("game.8o", []byte(`
+: sub return
+: main sub`))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x12, 0x04, 0x00, 0xEE, 0x22, 0x02}, p.ROM, "a jump to main should start programs not starting with it")
+}
+
+// run compiles and runs source until it reaches an endless loop
+func run(t *testing.T, source string) *cpu.CPU {
+	p, err := Compile("test.8o", []byte(source))
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	c := cpu.NewCPU(cpu.NewRAM(0x1000))
+	assert.NoError(t, c.Memory().Poke(p.Origin, p.ROM))
+	for n := 0; n < 1000; n++ {
+		pc := c.PC()
+		assert.NoError(t, c.Step())
+		if c.PC() == pc {
+			return c
+		}
+	}
+	t.Fatal("program did not finish")
+	return nil
+}
+
+func TestCompile_conditions(t *testing.T) {
+	holds := map[string]func(a, b int) bool{
+		"==": func(a, b int) bool { return a == b },
+		"!=": func(a, b int) bool { return a != b },
+		"<":  func(a, b int) bool { return a < b },
+		">":  func(a, b int) bool { return a > b },
+		"<=": func(a, b int) bool { return a <= b },
+		">=": func(a, b int) bool { return a >= b },
+	}
+	for comparison, want := range holds {
+		for _, pair := range [][2]int{{3, 5}, {5, 5}, {5, 3}, {0, 255}} {
+			a, b := pair[0], pair[1]
+			name := fmt.Sprintf("%d %s %d", a, comparison, b)
+			c := run(t, fmt.Sprintf(`
+: main
+	v0 := %d
+	v1 := %d
+	if v0 %s v1 then v2 := 1
+	if v0 %s %d then v3 := 1
+	if v0 %s v1 begin
+		v4 := 1
+	else
+		v4 := 2
+	end
+	loop again
+`, a, b, comparison, comparison, b, comparison))
+			truth := map[bool]byte{true: 1}[want(a, b)]
+			assert.Equal(t, truth, c.V(0x2), name)
+			assert.Equal(t, truth, c.V(0x3), name+" with a constant")
+			assert.Equal(t, 2-truth, c.V(0x4), name+" with else")
+		}
+	}
+}
+
+func TestCompile_loops(t *testing.T) {
+	c := run(t, `
+: main
+	loop
+		v1 := 0
+		loop
+			while v1 != 3
+			v1 += 1
+			v2 += 1
+		again
+		v0 += 1
+		if v0 == 4 then jump done
+	again
+: done
+	loop again
+`)
+	assert.EqualValues(t, 4, c.V(0x0))
+	assert.EqualValues(t, 12, c.V(0x2))
+}
+
+func TestCompile_next(t *testing.T) {
+	// the classic self-modifying load: :next labels an operand to patch
+	c := run(t, `
+: main
+	i := target
+	v0 := 7
+	save v0
+	:next target v1 := 0
+	loop again
+`)
+	assert.EqualValues(t, 7, c.V(0x1))
+
+	p, err := Compile("long.8o", []byte(": main\n:next far i := long 0"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(0x202), p.Labels["far"], "long loads should be labelled at their address")
+}
+
+func TestCompile_debugMetadata(t *testing.T) {
+	p, err := Compile("game.8o", []byte(`
+: main
+	v0 := 1
+	:breakpoint first
+	v1 := 2
+	:monitor score 2
+	:monitor score "%i points, %x%%"
+	loop again
+: score 0x10 0x20
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, []Breakpoint{{"first", 0x202}}, p.Breakpoints)
+	assert.Equal(t, []Monitor{
+		{Name: "score", Addr: 0x206, Length: 2},
+		{Name: "score", Addr: 0x206, Format: "%i points, %x%%"},
+	}, p.Monitors)
+	assert.Equal(t, "10 20", p.Monitors[0].Render([]byte{0x10, 0x20}))
+	assert.Equal(t, 2, p.Monitors[1].Size())
+	assert.Equal(t, "16 points, 20%", p.Monitors[1].Render([]byte{0x10, 0x20}))
+}
+
+func TestCompile_errors(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{": start", "x.8o:1: there is no main label to start at"},
+		{": main\n\tv0 := nowhere", `x.8o:2: undefined name "nowhere"`},
+		{": main\n\tv0 := 300", "x.8o:2: 300 is 300, out of range -128 to 255"},
+		{": main\n: main", "x.8o:2: main is already defined"},
+		{": main\n:const v1 3", "x.8o:2: v1 is a register"},
+		{": main\n\tloop\n\tv0 += 1", "x.8o:3: loop is never closed"},
+		{": main\n\tagain", "x.8o:2: again without loop"},
+		{": main\n\tif v0 == 1 v0 := 2", "x.8o:2: if needs then or begin"},
+		{": main\n\ti := v0", `x.8o:2: want a value, not the register v0`},
+		{": main\n\tv0 *= v1", `x.8o:2: unknown operator "*="`},
+		{":macro m { m }\n: main m", "x.8o:1: macro m expands without end"},
+		{": main\n\tv0 := 1\n:org 0x200\n\tv0 := 2", "x.8o:4: 200 is compiled twice"},
+		{": main\n\t:calc X { 1 + }", "x.8o:2: expression ends early"},
+	}
+	for _, test := range tests {
+		_, err := Compile("x.8o", []byte(test.source))
+		assert.EqualError(t, err, test.want, test.source)
+	}
+}
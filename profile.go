@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/Nuxij/goch8p/asm"
+	"github.com/Nuxij/goch8p/cpu"
+	"github.com/Nuxij/goch8p/octo"
+	"github.com/Nuxij/goch8p/profile"
+)
+
+// profileCommand runs a ROM and writes where it spent its time as a
+// pprof profile, with subroutines named from the labels of its source
+func profileCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("profile", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	machineFlags := newMachineFlags(flags)
+	frames := flags.Int("frames", 600, "frames to run")
+	symbols := flags.String("symbols", "", "Octo (.8o) or assembly source to name subroutines from")
+	out := flags.String("o", "profile.pb.gz", "profile to write")
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: goch8p profile [flags] rom")
+		flags.PrintDefaults()
+		return 2
+	}
+	m, err := machineFlags.parse()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	labels, err := loadLabels(*symbols, m)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	c, err := m.newCPU(data)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	p := profile.Start(c, labels)
+	for n := 0; n < *frames; n++ {
+		if err := c.RunFrame(); err != nil {
+			if !cpu.IsHalted(err) {
+				fmt.Fprintln(stderr, err)
+			}
+			break
+		}
+	}
+	p.Stop()
+	if err := writeProfile(*out, p); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	return 0
+}
+
+func writeProfile(path string, p *profile.Profiler) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := p.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// loadLabels reads the labels of source, Octo if it has a .8o extension
+// and assembly otherwise
+func loadLabels(source string, m platform) (map[string]uint16, error) {
+	switch {
+	case source == "":
+		return nil, nil
+	case filepath.Ext(source) == ".8o":
+		p, err := octo.CompileFile(source)
+		if err != nil {
+			return nil, err
+		}
+		return p.Labels, nil
+	default:
+		p, err := asm.AssembleFile(source, asm.Options{Mode: m.Mode, Origin: m.Origin})
+		if err != nil {
+			return nil, err
+		}
+		return p.Labels, nil
+	}
+}
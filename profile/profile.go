@@ -0,0 +1,243 @@
+// Package profile counts the instructions a CPU executes by address,
+// opcode class and call stack, and writes them as a pprof profile so
+// `go tool pprof` can show where a ROM spends its time
+package profile
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/Nuxij/goch8p/cpu"
+)
+
+// frame is one level of a call stack: an address, and the entry point of
+// the subroutine it is in
+type frame struct {
+	addr  uint16
+	entry uint16
+}
+
+// root is the entry of code not in any subroutine
+const root = 0x200
+
+// sample is a distinct call stack and opcode class. The stack is encoded
+// four bytes a frame, innermost first, so samples can key a map.
+type sample struct {
+	stack string
+	class byte
+}
+
+// Profiler counts the instructions executed by a CPU
+type Profiler struct {
+	cpu *cpu.CPU
+	// names holds labels by address, for naming subroutines
+	names   map[uint16]string
+	counts  map[uint16]int64
+	classes [16]int64
+	samples map[sample]int64
+	untrace func()
+}
+
+// Start profiles c until Stop is called. Subroutines are named from
+// labels, such as those of an assembled or compiled program, falling
+// back to sub_NNN.
+func Start(c *cpu.CPU, labels map[string]uint16) *Profiler {
+	p := &Profiler{
+		cpu:     c,
+		names:   make(map[uint16]string),
+		counts:  make(map[uint16]int64),
+		samples: make(map[sample]int64),
+	}
+	for name, addr := range labels {
+		// labels sharing an address name it by the first alphabetically,
+		// so profiles are the same every time
+		if other, ok := p.names[addr]; !ok || name < other {
+			p.names[addr] = name
+		}
+	}
+	p.untrace = c.Trace(p.trace)
+	return p
+}
+
+// Stop stops counting
+func (p *Profiler) Stop() {
+	p.untrace()
+}
+
+// Counts returns how many times each address was executed
+func (p *Profiler) Counts() map[uint16]int64 {
+	counts := make(map[uint16]int64, len(p.counts))
+	for pc, n := range p.counts {
+		counts[pc] = n
+	}
+	return counts
+}
+
+// Classes returns how many instructions of each opcode class, by top
+// nibble, were executed
+func (p *Profiler) Classes() [16]int64 {
+	return p.classes
+}
+
+func (p *Profiler) trace(t cpu.Trace) {
+	pc := t.Before.PC
+	p.counts[pc]++
+	var class byte
+	if len(t.Bytes) > 0 {
+		class = t.Bytes[0] >> 4
+	}
+	p.classes[class]++
+
+	// the stack as it was before the instruction ran
+	stack := p.cpu.CallStack()
+	switch {
+	case t.After.SP > t.Before.SP && len(stack) > 0:
+		stack = stack[:len(stack)-1]
+	case t.After.SP < t.Before.SP:
+		stack = append(stack, t.After.PC)
+	}
+	key := make([]byte, 0, 4*(len(stack)+1))
+	addr := pc
+	for n := len(stack) - 1; n >= -1; n-- {
+		entry := uint16(root)
+		if n >= 0 {
+			// each return address follows the call into this subroutine
+			entry = p.callee(stack[n] - 2)
+		}
+		key = append(key, byte(addr>>8), byte(addr), byte(entry>>8), byte(entry))
+		if n >= 0 {
+			addr = stack[n] - 2
+		}
+	}
+	p.samples[sample{string(key), class}]++
+}
+
+// callee returns the subroutine the 2NNN at addr calls
+func (p *Profiler) callee(addr uint16) uint16 {
+	opcode, err := p.cpu.Memory().Peek(addr, 2)
+	if err != nil || opcode[0]>>4 != 0x2 {
+		return addr
+	}
+	return uint16(opcode[0]&0xF)<<8 | uint16(opcode[1])
+}
+
+// name returns what the subroutine at entry is called
+func (p *Profiler) name(entry uint16) string {
+	if name, ok := p.names[entry]; ok {
+		return name
+	}
+	if entry == root {
+		return "start"
+	}
+	return fmt.Sprintf("sub_%03X", entry)
+}
+
+// Write writes the profile to w as gzipped profile.proto. Each sample is
+// labelled with its opcode class, and line numbers are addresses, so
+// pprof's -tags, -lines and -addresses break the time down further.
+func (p *Profiler) Write(w io.Writer) error {
+	index := map[string]int64{"": 0}
+	table := []string{""}
+	str := func(s string) int64 {
+		if n, ok := index[s]; ok {
+			return n
+		}
+		index[s] = int64(len(table))
+		table = append(table, s)
+		return index[s]
+	}
+
+	keys := make([]sample, 0, len(p.samples))
+	for s := range p.samples {
+		keys = append(keys, s)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].stack != keys[j].stack {
+			return keys[i].stack < keys[j].stack
+		}
+		return keys[i].class < keys[j].class
+	})
+
+	var e encoder
+	valueType := func(e *encoder) {
+		e.int64(1, str("instructions"))
+		e.int64(2, str("count"))
+	}
+	e.message(1, valueType)
+
+	functions := make(map[uint16]int64)
+	var functionOrder []uint16
+	locations := make(map[frame]int64)
+	var locationOrder []frame
+	for _, s := range keys {
+		var ids []int64
+		for n := 0; n < len(s.stack); n += 4 {
+			f := frame{
+				addr:  uint16(s.stack[n])<<8 | uint16(s.stack[n+1]),
+				entry: uint16(s.stack[n+2])<<8 | uint16(s.stack[n+3]),
+			}
+			if _, ok := functions[f.entry]; !ok {
+				functions[f.entry] = int64(len(functionOrder) + 1)
+				functionOrder = append(functionOrder, f.entry)
+			}
+			if _, ok := locations[f]; !ok {
+				locations[f] = int64(len(locationOrder) + 1)
+				locationOrder = append(locationOrder, f)
+			}
+			ids = append(ids, locations[f])
+		}
+		count, class := p.samples[s], fmt.Sprintf("%X", s.class)
+		e.message(2, func(e *encoder) {
+			e.packed(1, ids)
+			e.packed(2, []int64{count})
+			e.message(3, func(e *encoder) {
+				e.int64(1, str("class"))
+				e.int64(2, str(class))
+			})
+		})
+	}
+
+	e.message(3, func(e *encoder) {
+		e.int64(1, 1)
+		e.int64(3, int64(p.cpu.Mode().MemorySize()))
+		e.int64(5, str("rom"))
+		e.int64(7, 1)
+		e.int64(9, 1)
+	})
+	for _, f := range locationOrder {
+		id, addr, function := locations[f], int64(f.addr), functions[f.entry]
+		e.message(4, func(e *encoder) {
+			e.int64(1, id)
+			e.int64(2, 1)
+			e.int64(3, addr)
+			e.message(4, func(e *encoder) {
+				e.int64(1, function)
+				e.int64(2, addr)
+			})
+		})
+	}
+	for _, entry := range functionOrder {
+		id, name := functions[entry], str(p.name(entry))
+		e.message(5, func(e *encoder) {
+			e.int64(1, id)
+			e.int64(2, name)
+			e.int64(3, name)
+			e.int64(4, str("rom"))
+			e.int64(5, int64(entry))
+		})
+	}
+	e.message(11, valueType)
+	e.int64(12, 1)
+	// the string table goes last, once everything has added its strings
+	for _, s := range table {
+		e.bytes(6, []byte(s))
+	}
+
+	z := gzip.NewWriter(w)
+	if _, err := z.Write(e.Bytes()); err != nil {
+		return err
+	}
+	return z.Close()
+}
@@ -0,0 +1,118 @@
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Nuxij/goch8p/asm"
+	"github.com/Nuxij/goch8p/cpu"
+)
+
+// program draws twice through nested calls, then waits in a loop
+var program = asm.MustAssemble(`
+    CALL draw           ; 200
+    CALL wait           ; 202
+    JP 0x200            ; 204
+draw:
+    LD I, 0             ; 206
+    CALL sprite         ; 208
+    CALL sprite         ; 20A
+    RET                 ; 20C
+sprite:
+    DRW V0, V1, 5       ; 20E
+    RET                 ; 210
+wait:
+    LD V2, 3            ; 212
+loop:
+    ADD V2, 0xFF        ; 214
+    SE V2, 0            ; 216
+    JP loop             ; 218
+    RET                 ; 21A
+`, asm.Options{})
+
+func newProfiler(t *testing.T) (*cpu.CPU, *Profiler) {
+	c := cpu.NewCPU(cpu.NewRAM(0x1000))
+	assert.NoError(t, c.Memory().Poke(0x200, program.ROM))
+	return c, Start(c, program.Labels)
+}
+
+// stacks collapses the samples into `leaf;caller;...` strings
+func (p *Profiler) stacks() map[string]int64 {
+	stacks := make(map[string]int64)
+	for s, n := range p.samples {
+		var names []string
+		for i := 0; i < len(s.stack); i += 4 {
+			entry := uint16(s.stack[i+2])<<8 | uint16(s.stack[i+3])
+			names = append(names, p.name(entry))
+		}
+		stacks[strings.Join(names, ";")] += n
+	}
+	return stacks
+}
+
+func TestProfiler(t *testing.T) {
+	c, p := newProfiler(t)
+	// one pass: 3 in start, 4 in draw, 2 in each sprite and 10 in wait
+	for n := 0; n < 21; n++ {
+		assert.NoError(t, c.Step())
+	}
+	p.Stop()
+	assert.NoError(t, c.Step())
+
+	counts := p.Counts()
+	assert.EqualValues(t, 1, counts[0x200], "stopped profilers should not count")
+	assert.EqualValues(t, 2, counts[0x20E])
+	assert.EqualValues(t, 3, counts[0x214])
+	assert.EqualValues(t, 1, counts[0x204])
+
+	classes := p.Classes()
+	assert.EqualValues(t, 2, classes[0xD])
+	assert.EqualValues(t, 4, classes[0x2])
+
+	assert.Equal(t, map[string]int64{
+		"start":             3,
+		"draw;start":        4,
+		"sprite;draw;start": 4,
+		"wait;start":        10,
+	}, p.stacks())
+}
+
+func TestProfiler_unnamed(t *testing.T) {
+	c := cpu.NewCPU(cpu.NewRAM(0x1000))
+	assert.NoError(t, c.Memory().Poke(0x200, program.ROM))
+	p := Start(c, nil)
+	for n := 0; n < 5; n++ {
+		assert.NoError(t, c.Step())
+	}
+	assert.Equal(t, map[string]int64{
+		"start":                 1,
+		"sub_206;start":         2,
+		"sub_20E;sub_206;start": 2,
+	}, p.stacks())
+}
+
+func TestProfiler_Write(t *testing.T) {
+	c, p := newProfiler(t)
+	for n := 0; n < 100; n++ {
+		assert.NoError(t, c.Step())
+	}
+	var a, b bytes.Buffer
+	assert.NoError(t, p.Write(&a))
+	assert.NoError(t, p.Write(&b))
+	assert.Equal(t, a.Bytes(), b.Bytes(), "profiles should be reproducible")
+
+	z, err := gzip.NewReader(&a)
+	if !assert.NoError(t, err) {
+		return
+	}
+	proto, err := io.ReadAll(z)
+	assert.NoError(t, err)
+	for _, name := range []string{"instructions", "class", "draw", "sprite", "wait"} {
+		assert.Contains(t, string(proto), name)
+	}
+}
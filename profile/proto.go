@@ -0,0 +1,48 @@
+package profile
+
+import "bytes"
+
+// encoder writes the few protocol buffer wire types profile.proto uses
+type encoder struct {
+	bytes.Buffer
+}
+
+func (e *encoder) uvarint(v uint64) {
+	for v >= 0x80 {
+		e.WriteByte(byte(v) | 0x80)
+		v >>= 7
+	}
+	e.WriteByte(byte(v))
+}
+
+// int64 writes a varint field, skipping zero as proto3 does
+func (e *encoder) int64(field int, v int64) {
+	if v == 0 {
+		return
+	}
+	e.uvarint(uint64(field) << 3)
+	e.uvarint(uint64(v))
+}
+
+// bytes writes a length delimited field
+func (e *encoder) bytes(field int, data []byte) {
+	e.uvarint(uint64(field)<<3 | 2)
+	e.uvarint(uint64(len(data)))
+	e.Write(data)
+}
+
+// packed writes a repeated varint field
+func (e *encoder) packed(field int, vs []int64) {
+	var p encoder
+	for _, v := range vs {
+		p.uvarint(uint64(v))
+	}
+	e.bytes(field, p.Bytes())
+}
+
+// message writes a nested message field, built by m
+func (e *encoder) message(field int, m func(*encoder)) {
+	var nested encoder
+	m(&nested)
+	e.bytes(field, nested.Bytes())
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfileCommand(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "spin.s")
+	rom := filepath.Join(dir, "spin.ch8")
+	out := filepath.Join(dir, "profile.pb.gz")
+	assert.NoError(t, os.WriteFile(source, []byte("start:\n    CALL spin\nspin:\n    JP spin\n"), 0644))
+	assert.NoError(t, os.WriteFile(rom, []byte{0x22, 0x02, 0x12, 0x02}, 0644))
+
+	var stdout, stderr strings.Builder
+	assert.Equal(t, 0, profileCommand([]string{"-frames", "2", "-symbols", source, "-o", out, rom}, &stdout, &stderr), stderr.String())
+	profile, err := os.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x1F, 0x8B}, profile[:2], "profiles are gzipped")
+
+	stderr.Reset()
+	assert.Equal(t, 2, profileCommand([]string{"-symbols", filepath.Join(dir, "missing.s"), rom}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "no such file")
+}
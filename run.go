@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Nuxij/goch8p/cpu"
+	"github.com/Nuxij/goch8p/headless"
+	"github.com/Nuxij/goch8p/octo"
+	"github.com/Nuxij/goch8p/terminal"
+)
+
+// runCommand runs a ROM on a frontend: tea plays it in the terminal,
+// imgui in a window when built with -tags imgui, and headless writes the
+// final screen as a PNG and the machine's state as JSON. It exits 1 if
+// the ROM faulted and 2 if it could not be run at all. A ROM exiting with
+// 00FD is not a fault. Holding backspace in tea or imgui rewinds. Octo
+// source is compiled first, its monitors shown under the terminal screen
+// and in the headless state, and a headless run stopping at the first of
+// its breakpoints reached.
+func runCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	machineFlags := newMachineFlags(flags)
+	playFlags := newPlayFlags(flags)
+	frontend := flags.String("frontend", "tea", "where to run: tea, imgui or headless")
+	headlessRun := flags.Bool("headless", false, "shorthand for --frontend=headless")
+	frames := flags.Int("frames", 600, "frames to run headless")
+	screenshot := flags.String("png", "", "screenshot to write headless, the ROM with a .png extension if empty")
+	state := flags.String("state", "", "state dump to write headless, the ROM with a .json extension if empty")
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: goch8p run [--frontend=tea|imgui|headless] [flags] rom")
+		flags.PrintDefaults()
+		return 2
+	}
+	if *headlessRun {
+		*frontend = "headless"
+	}
+	play, ok := frontends[*frontend]
+	if !ok {
+		if *frontend == "imgui" {
+			fmt.Fprintln(stderr, "the imgui frontend needs building with -tags imgui")
+		} else {
+			fmt.Fprintf(stderr, "unknown frontend %q\n", *frontend)
+		}
+		return 2
+	}
+	m, err := machineFlags.parse()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	keymap, err := playFlags.keymap()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	rom := positional[0]
+	base := strings.TrimSuffix(rom, filepath.Ext(rom))
+	if *screenshot == "" {
+		*screenshot = base + ".png"
+	}
+	if *state == "" {
+		*state = base + ".json"
+	}
+	options := []cpu.Option{cpu.WithInstructionsPerFrame(*playFlags.speed)}
+	if *frontend != "headless" {
+		// the players rewind while backspace is held
+		options = append(options, cpu.WithRewind(cpu.DefaultRewindInterval, cpu.DefaultRewindDepth))
+	}
+	c, program, err := m.load(rom, options...)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	s := session{
+		rom:        rom,
+		cpu:        c,
+		program:    program,
+		scale:      *playFlags.scale,
+		keymap:     keymap,
+		frames:     *frames,
+		screenshot: *screenshot,
+		state:      *state,
+	}
+	runErr, err := play(s)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if runErr != nil && !cpu.IsHalted(runErr) {
+		fmt.Fprintf(stderr, "%s: frame %d: %v\n", rom, c.Frame().Number+1, runErr)
+		return 1
+	}
+	return 0
+}
+
+// session is a ROM loaded for a frontend to run, with the flags that
+// apply to it
+type session struct {
+	rom string
+	cpu *cpu.CPU
+	// program is the Octo program the ROM was compiled from, if it was
+	program *octo.Program
+	scale   int
+	keymap  terminal.Keymap
+	// frames, screenshot and state are for headless runs
+	frames     int
+	screenshot string
+	state      string
+}
+
+// frontend runs a session, returning the error the ROM stopped with, if
+// any, and separately any error of its own
+type frontend func(s session) (runErr, err error)
+
+// frontends are the frontends run can use. The imgui one adds itself
+// when built with -tags imgui, as it needs cgo and a display.
+var frontends = map[string]frontend{
+	"tea":      playTerminal,
+	"headless": runHeadless,
+}
+
+// playTerminal plays the session in the terminal until escape is pressed
+func playTerminal(s session) (runErr, err error) {
+	options := terminal.Options{Title: filepath.Base(s.rom), Keymap: s.keymap, Scale: s.scale}
+	if s.program != nil && len(s.program.Monitors) > 0 {
+		options.Status = func() string {
+			var status []string
+			for _, m := range inspect(s.cpu, s.program.Monitors) {
+				status = append(status, m.Name+" "+m.Value)
+			}
+			return strings.Join(status, ", ")
+		}
+	}
+	return terminal.Play(s.cpu, options), nil
+}
+
+// runDump is the headless state dump, with what an Octo program asks for
+type runDump struct {
+	headless.Dump
+	// Breakpoint names the breakpoint the run stopped at, if it did
+	Breakpoint string          `json:"breakpoint,omitempty"`
+	Monitors   []monitorReport `json:"monitors,omitempty"`
+}
+
+// monitorReport is a monitor's memory as it shows it
+type monitorReport struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// inspect renders each of monitors, without triggering watchpoints
+func inspect(c *cpu.CPU, monitors []octo.Monitor) []monitorReport {
+	reports := make([]monitorReport, len(monitors))
+	for i, m := range monitors {
+		reports[i].Name = m.Name
+		if data, err := c.Memory().Peek(m.Addr, uint16(m.Size())); err == nil {
+			reports[i].Value = m.Render(data)
+		}
+	}
+	return reports
+}
+
+// runHeadless runs the session's frames, or until a breakpoint, then
+// writes its screenshot and state dump
+func runHeadless(s session) (runErr, err error) {
+	dump := runDump{}
+	if s.program != nil {
+		dump.Breakpoint, runErr = runToBreakpoint(s.cpu, s.frames, s.program.Breakpoints)
+		dump.Monitors = inspect(s.cpu, s.program.Monitors)
+	} else {
+		runErr = headless.Run(s.cpu, s.frames)
+	}
+	if err := writeScreenshot(s.screenshot, s.cpu.Frame(), s.scale); err != nil {
+		return runErr, err
+	}
+	dump.Dump = headless.NewDump(s.cpu, runErr)
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err == nil {
+		err = os.WriteFile(s.state, append(data, '\n'), 0644)
+	}
+	return runErr, err
+}
+
+// runToBreakpoint runs frames frames like headless.Run, stopping short
+// before executing any of breakpoints and returning its name
+func runToBreakpoint(c *cpu.CPU, frames int, breakpoints []octo.Breakpoint) (string, error) {
+	names := make(map[uint16]string)
+	for _, b := range breakpoints {
+		names[b.Addr] = b.Name
+	}
+	for end := c.Frame().Number + uint64(frames); c.Frame().Number < end; {
+		if name, ok := names[c.PC()]; ok {
+			return name, nil
+		}
+		if err := c.Step(); err != nil {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+func writeScreenshot(path string, frame cpu.Frame, scale int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := headless.WritePNG(f, frame, scale); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
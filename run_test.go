@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Nuxij/goch8p/headless"
+)
+
+func TestRunCommand_headless(t *testing.T) {
+	dir := t.TempDir()
+	rom := filepath.Join(dir, "glyph.ch8")
+	// draw the 0 glyph at 5,5 then spin
+	assert.NoError(t, os.WriteFile(rom, []byte{0x60, 0x05, 0xF0, 0x29, 0xD0, 0x05, 0x12, 0x06}, 0644))
+
+	var stdout, stderr strings.Builder
+	assert.Equal(t, 0, runCommand([]string{"--headless", rom, "--frames", "5", "--scale", "4"}, &stdout, &stderr), stderr.String())
+	f, err := os.Open(filepath.Join(dir, "glyph.png"))
+	assert.NoError(t, err)
+	defer f.Close()
+	img, err := png.Decode(f)
+	assert.NoError(t, err)
+	assert.Equal(t, 256, img.Bounds().Dx())
+
+	var dump headless.Dump
+	data, err := os.ReadFile(filepath.Join(dir, "glyph.json"))
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, &dump))
+	assert.EqualValues(t, 5, dump.Frames)
+	assert.EqualValues(t, 0x206, dump.Registers.PC)
+	assert.Empty(t, dump.Error)
+}
+
+func TestRunCommand_fault(t *testing.T) {
+	dir := t.TempDir()
+	rom := filepath.Join(dir, "fault.ch8")
+	state := filepath.Join(dir, "state.json")
+	// LD V0, 1 then an unknown instruction
+	assert.NoError(t, os.WriteFile(rom, []byte{0x60, 0x01, 0x00, 0x00}, 0644))
+
+	var stdout, stderr strings.Builder
+	assert.Equal(t, 1, runCommand([]string{"--headless", "--state", state, rom}, &stdout, &stderr))
+	assert.Equal(t, rom+": frame 1: unknown instruction: 0\n", stderr.String())
+	data, err := os.ReadFile(state)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"error": "unknown instruction: 0"`)
+
+}
+
+func TestRunCommand_flags(t *testing.T) {
+	rom := filepath.Join(t.TempDir(), "spin.ch8")
+	assert.NoError(t, os.WriteFile(rom, []byte{0x12, 0x00}, 0644))
+
+	for _, test := range []struct {
+		args   []string
+		stderr string
+	}{
+		{[]string{"--frontend=sdl", rom}, "unknown frontend \"sdl\"\n"},
+		{[]string{"--frontend=imgui", rom}, "the imgui frontend needs building with -tags imgui\n"},
+		{[]string{"--headless", "--quirks", "amiga", rom}, "unknown quirk preset \"amiga\"\n"},
+		{[]string{"--headless", "--keys", "qwerty", rom}, "key layout \"qwerty\" has 6 keys, not 16\n"},
+	} {
+		if _, ok := frontends["imgui"]; ok && test.args[0] == "--frontend=imgui" {
+			continue
+		}
+		var stdout, stderr strings.Builder
+		assert.Equal(t, 2, runCommand(test.args, &stdout, &stderr), test.args)
+		assert.Equal(t, test.stderr, stderr.String(), test.args)
+	}
+}
+
+func TestRunCommand_origin(t *testing.T) {
+	dir := t.TempDir()
+	rom := filepath.Join(dir, "eti.ch8")
+	state := filepath.Join(dir, "eti.json")
+	// LD V3, 3 then spin, loaded and run at 0x600
+	assert.NoError(t, os.WriteFile(rom, []byte{0x63, 0x03, 0x16, 0x02}, 0644))
+
+	var stdout, stderr strings.Builder
+	assert.Equal(t, 0, runCommand([]string{"--headless", "--origin", "0x600", "--frames", "1", rom}, &stdout, &stderr), stderr.String())
+	var dump headless.Dump
+	data, err := os.ReadFile(state)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, &dump))
+	assert.EqualValues(t, 0x602, dump.Registers.PC)
+	assert.EqualValues(t, 3, dump.Registers.V[3])
+}
+
+func TestRunCommand_octo(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "count.8o")
+	assert.NoError(t, os.WriteFile(source, []byte(`
+: main
+	v0 := 0
+	loop
+		v0 += 1
+		i := counter
+		save v0
+		if v0 == 3 then jump done
+	again
+: done
+	:breakpoint counted
+	loop again
+: counter 0
+:monitor counter 1
+`), 0644))
+
+	var stdout, stderr strings.Builder
+	assert.Equal(t, 0, runCommand([]string{"--headless", source}, &stdout, &stderr), stderr.String())
+	var dump runDump
+	data, err := os.ReadFile(filepath.Join(dir, "count.json"))
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, &dump))
+	assert.Equal(t, "counted", dump.Breakpoint, "the run should stop at the breakpoint")
+	assert.EqualValues(t, 3, dump.Registers.V[0])
+	assert.Equal(t, []monitorReport{{"counter", "03"}}, dump.Monitors)
+}
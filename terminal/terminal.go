@@ -0,0 +1,178 @@
+// Package terminal plays a CPU in a terminal, drawing the screen in half
+// blocks and feeding the keyboard to the keypad
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Nuxij/goch8p/cpu"
+)
+
+// DefaultLayout maps the left of a QWERTY keyboard onto the keypad, as
+// most emulators lay it out. It lists the keyboard key for each keypad
+// key from 0 to F.
+const DefaultLayout = "x123qweasdzc4rfv"
+
+// Keymap maps keyboard keys onto keypad keys
+type Keymap map[rune]byte
+
+// LayoutInvalid is an error ParseLayout returns for a layout it cannot use
+type LayoutInvalid struct {
+	layout string
+	reason string
+}
+
+func (l LayoutInvalid) Error() string {
+	return fmt.Sprintf("key layout %q %s", l.layout, l.reason)
+}
+
+// ParseLayout reads a layout such as DefaultLayout, sixteen different
+// keyboard keys for the keypad keys 0 to F
+func ParseLayout(layout string) (Keymap, error) {
+	runes := []rune(strings.ToLower(layout))
+	if len(runes) != cpu.Keys {
+		return nil, LayoutInvalid{layout, fmt.Sprintf("has %d keys, not %d", len(runes), cpu.Keys)}
+	}
+	keymap := make(Keymap)
+	for key, r := range runes {
+		if _, ok := keymap[r]; ok {
+			return nil, LayoutInvalid{layout, fmt.Sprintf("uses %q twice", r)}
+		}
+		keymap[r] = byte(key)
+	}
+	return keymap, nil
+}
+
+// Options tune how a CPU plays
+type Options struct {
+	// Title is shown under the screen
+	Title string
+	// Keymap maps the keyboard onto the keypad, DefaultLayout if nil
+	Keymap Keymap
+	// Scale widens each pixel to that many columns and each line of
+	// half blocks to that many lines
+	Scale int
+	// Status, if set, adds to the line under the screen each frame
+	Status func() string
+}
+
+// holdFrames is how long a key stays down after the terminal sends it.
+// Terminals send no releases, only repeats while a key is held.
+const holdFrames = 6
+
+type tick struct{}
+
+// player plays a CPU in the terminal, a frame every tick
+type player struct {
+	cpu     *cpu.CPU
+	options Options
+	// held counts down the frames each pressed key has left, and
+	// rewinding those the rewind key has
+	held      map[byte]int
+	rewinding int
+	err       error
+}
+
+// Play runs c a frame every 1/TimerRate seconds until escape is pressed,
+// returning nil, or a frame fails, returning its error. A program
+// exiting with 00FD stops it with a cpu.Halted error. Holding backspace
+// rewinds a frame a tick in place of running one, if c was built with
+// cpu.WithRewind.
+func Play(c *cpu.CPU, options Options) error {
+	if options.Keymap == nil {
+		options.Keymap, _ = ParseLayout(DefaultLayout)
+	}
+	if options.Scale < 1 {
+		options.Scale = 1
+	}
+	p := &player{cpu: c, options: options, held: make(map[byte]int)}
+	if err := tea.NewProgram(p, tea.WithAltScreen()).Start(); err != nil {
+		return err
+	}
+	return p.err
+}
+
+func (p *player) Init() tea.Cmd {
+	return p.next()
+}
+
+func (p *player) next() tea.Cmd {
+	return tea.Tick(time.Second/cpu.TimerRate, func(time.Time) tea.Msg {
+		return tick{}
+	})
+}
+
+func (p *player) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if k := msg.String(); k == "ctrl+c" || k == "esc" {
+			return p, tea.Quit
+		}
+		if msg.Type == tea.KeyBackspace {
+			p.rewinding = holdFrames
+			return p, nil
+		}
+		if msg.Type != tea.KeyRunes || len(msg.Runes) != 1 {
+			return p, nil
+		}
+		if key, ok := p.options.Keymap[msg.Runes[0]]; ok {
+			p.cpu.Keypad().Press(key)
+			p.held[key] = holdFrames
+		}
+	case tick:
+		for key := range p.held {
+			if p.held[key]--; p.held[key] <= 0 {
+				p.cpu.Keypad().Release(key)
+				delete(p.held, key)
+			}
+		}
+		if p.rewinding > 0 {
+			p.rewinding--
+			// with no history the machine just stays paused
+			var nothing cpu.NothingToRewind
+			if err := p.cpu.Rewind(1); err != nil && !errors.As(err, &nothing) {
+				p.err = err
+				return p, tea.Quit
+			}
+			return p, p.next()
+		}
+		if p.err = p.cpu.RunFrame(); p.err != nil {
+			return p, tea.Quit
+		}
+		return p, p.next()
+	}
+	return p, nil
+}
+
+func (p *player) View() string {
+	status := p.options.Title
+	if p.options.Status != nil {
+		status += ": " + p.options.Status()
+	}
+	return Render(p.cpu.Frame(), p.options.Scale) + status + " (esc quits)\n"
+}
+
+// Render draws f as text, scaled up by scale
+func Render(f cpu.Frame, scale int) string {
+	if scale <= 1 {
+		return f.String()
+	}
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(f.String(), "\n") {
+		if line == "" {
+			continue
+		}
+		var wide strings.Builder
+		for _, r := range strings.TrimSuffix(line, "\n") {
+			wide.WriteString(strings.Repeat(string(r), scale))
+		}
+		wide.WriteByte('\n')
+		b.WriteString(strings.Repeat(wide.String(), scale))
+	}
+	return b.String()
+}
@@ -0,0 +1,74 @@
+package terminal
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Nuxij/goch8p/cpu"
+)
+
+func TestParseLayout(t *testing.T) {
+	keymap, err := ParseLayout(DefaultLayout)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0x0, keymap['x'])
+	assert.EqualValues(t, 0xC, keymap['4'])
+	assert.EqualValues(t, 0xF, keymap['v'])
+
+	_, err = ParseLayout("x123")
+	assert.EqualError(t, err, `key layout "x123" has 4 keys, not 16`)
+	_, err = ParseLayout("x123qweasdzc4rfx")
+	assert.EqualError(t, err, `key layout "x123qweasdzc4rfx" uses 'x' twice`)
+}
+
+func TestRender(t *testing.T) {
+	f := cpu.Frame{Width: 2, Height: 2, Planes: [cpu.Planes][]byte{{0x90}, {0}}}
+	assert.Equal(t, "▀▄\n", Render(f, 1))
+	assert.Equal(t, "▀▀▄▄\n▀▀▄▄\n", Render(f, 2))
+}
+
+func TestPlayer(t *testing.T) {
+	// SKNP V0 with V0 holding 5, then spin
+	c := cpu.NewCPU(cpu.NewRAM(0x1000))
+	assert.NoError(t, c.Memory().Poke(0x200, []byte{0x60, 0x05, 0xE0, 0xA1, 0x12, 0x04, 0x12, 0x06}))
+	keymap, _ := ParseLayout(DefaultLayout)
+	p := &player{cpu: c, options: Options{Title: "skip", Keymap: keymap, Scale: 1}, held: make(map[byte]int)}
+
+	p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'w'}})
+	assert.True(t, c.Keypad().Pressed(0x5))
+	for n := 0; n < holdFrames; n++ {
+		_, cmd := p.Update(tick{})
+		assert.NotNil(t, cmd)
+	}
+	assert.False(t, c.Keypad().Pressed(0x5), "keys should be let go of once no repeats come")
+	assert.EqualValues(t, 0x204, c.PC(), "the skip should not have been taken while 5 was held")
+	assert.Contains(t, p.View(), "skip (esc quits)")
+
+	_, cmd := p.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, tea.Quit(), cmd())
+}
+
+func TestPlayer_rewind(t *testing.T) {
+	// add 1 to V0 each frame, forever
+	c := cpu.NewCPU(cpu.NewRAM(0x1000), cpu.WithInstructionsPerFrame(2), cpu.WithRewind(1, 100))
+	assert.NoError(t, c.Memory().Poke(0x200, []byte{0x70, 0x01, 0x12, 0x00}))
+	p := &player{cpu: c, held: make(map[byte]int)}
+	for n := 0; n < 10; n++ {
+		p.Update(tick{})
+	}
+	assert.EqualValues(t, 10, c.Registers().V[0])
+
+	p.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	_, cmd := p.Update(tick{})
+	assert.NotNil(t, cmd)
+	assert.NoError(t, p.err)
+	assert.EqualValues(t, 9, c.Frame().Number)
+	assert.EqualValues(t, 9, c.Registers().V[0], "holding backspace should go back a frame a tick")
+	for n := 1; n < holdFrames; n++ {
+		p.Update(tick{})
+	}
+	assert.EqualValues(t, 10-holdFrames, c.Registers().V[0])
+	p.Update(tick{})
+	assert.EqualValues(t, 11-holdFrames, c.Registers().V[0], "letting go should run again")
+}
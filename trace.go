@@ -0,0 +1,153 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Nuxij/goch8p/trace"
+)
+
+// traceCommand records the instructions a ROM executes, or compares two
+// traces such as ours against a reference emulator's, exiting 1 if they
+// diverge
+func traceCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "run":
+			return traceRun(args[1:], stdout, stderr)
+		case "diff":
+			return traceDiff(args[1:], stdout, stderr)
+		}
+	}
+	fmt.Fprintln(stderr, "usage: goch8p trace run [flags] rom | goch8p trace diff a b")
+	return 2
+}
+
+func traceRun(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("trace run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	machineFlags := newMachineFlags(flags)
+	frames := flags.Int("frames", 60, "frames to run")
+	format := flags.String("format", "json", "trace format: json or text")
+	from := flags.String("from", "0", "lowest address to trace")
+	to := flags.String("to", "0", "highest address to trace, 0 for no bound")
+	classes := flags.String("class", "", "opcode classes to trace by top hex digit, such as 1,2,D")
+	out := flags.String("o", "", "file to write, stdout if empty")
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: goch8p trace run [flags] rom")
+		flags.PrintDefaults()
+		return 2
+	}
+	m, err := machineFlags.parse()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	f, ok := trace.Formats[*format]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown format %q\n", *format)
+		return 2
+	}
+	filter, err := parseFilter(*from, *to, *classes)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	c, err := m.newCPU(data)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	w := stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		defer file.Close()
+		w = file
+	}
+	writer := trace.NewWriter(w, f, filter)
+	c.Trace(writer.Trace)
+	for n := 0; n < *frames; n++ {
+		// faults end the program, and show in the trace
+		if err := c.RunFrame(); err != nil {
+			break
+		}
+	}
+	if err := writer.Err(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	return 0
+}
+
+func parseFilter(from, to, classes string) (trace.Filter, error) {
+	var filter trace.Filter
+	low, err := strconv.ParseUint(from, 0, 16)
+	if err != nil {
+		return filter, fmt.Errorf("bad address %q", from)
+	}
+	high, err := strconv.ParseUint(to, 0, 16)
+	if err != nil {
+		return filter, fmt.Errorf("bad address %q", to)
+	}
+	filter.From, filter.To = uint16(low), uint16(high)
+	for _, class := range strings.Split(classes, ",") {
+		if class == "" {
+			continue
+		}
+		c, err := strconv.ParseUint(class, 16, 4)
+		if err != nil {
+			return filter, fmt.Errorf("bad opcode class %q", class)
+		}
+		filter.Classes = append(filter.Classes, byte(c))
+	}
+	return filter, nil
+}
+
+func traceDiff(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 2 {
+		fmt.Fprintln(stderr, "usage: goch8p trace diff a b")
+		return 2
+	}
+	d, err := diffTraces(args[0], args[1])
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if d == nil {
+		fmt.Fprintln(stdout, "traces match")
+		return 0
+	}
+	fmt.Fprintln(stdout, d)
+	return 1
+}
+
+func diffTraces(a, b string) (*trace.Divergence, error) {
+	ra, err := os.Open(a)
+	if err != nil {
+		return nil, err
+	}
+	defer ra.Close()
+	rb, err := os.Open(b)
+	if err != nil {
+		return nil, err
+	}
+	defer rb.Close()
+	return trace.Diff(ra, rb)
+}
@@ -0,0 +1,114 @@
+package trace
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Divergence is where two traces first differ
+type Divergence struct {
+	// Line is the line number of the first record to differ
+	Line int
+	// Field names what differs, such as after.V3, or "length" if one
+	// trace ends first
+	Field string
+	// A and B are the differing lines, empty for a trace that has ended
+	A string
+	B string
+}
+
+func (d Divergence) String() string {
+	return fmt.Sprintf("traces diverge at line %d: %s differs\n< %s\n> %s", d.Line, d.Field, d.A, d.B)
+}
+
+// Diff compares two traces a record at a time, returning where they
+// first diverge or nil if they match. JSON Lines records are compared
+// by value, ignoring mnemonics so traces from other emulators compare
+// too, and anything else as text.
+func Diff(a, b io.Reader) (*Divergence, error) {
+	as, bs := bufio.NewScanner(a), bufio.NewScanner(b)
+	for line := 1; ; line++ {
+		moreA, moreB := as.Scan(), bs.Scan()
+		if err := as.Err(); err != nil {
+			return nil, err
+		}
+		if err := bs.Err(); err != nil {
+			return nil, err
+		}
+		switch {
+		case !moreA && !moreB:
+			return nil, nil
+		case !moreA || !moreB:
+			return &Divergence{line, "length", as.Text(), bs.Text()}, nil
+		}
+		field, err := compare(as.Text(), bs.Text())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if field != "" {
+			return &Divergence{line, field, as.Text(), bs.Text()}, nil
+		}
+	}
+}
+
+// compare returns the first field that differs between two lines, or ""
+// if they match
+func compare(a, b string) (string, error) {
+	if !strings.HasPrefix(a, "{") || !strings.HasPrefix(b, "{") {
+		if a != b {
+			return "line", nil
+		}
+		return "", nil
+	}
+	var ra, rb Record
+	if err := json.Unmarshal([]byte(a), &ra); err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal([]byte(b), &rb); err != nil {
+		return "", err
+	}
+	switch {
+	case ra.PC != rb.PC:
+		return "pc", nil
+	case !strings.EqualFold(ra.Opcode, rb.Opcode):
+		return "opcode", nil
+	}
+	if field := compareRegisters(ra.Before, rb.Before); field != "" {
+		return "before." + field, nil
+	}
+	if field := compareRegisters(ra.After, rb.After); field != "" {
+		return "after." + field, nil
+	}
+	switch {
+	case !reflect.DeepEqual(ra.Writes, rb.Writes):
+		return "writes", nil
+	case ra.Error != rb.Error:
+		return "error", nil
+	}
+	return "", nil
+}
+
+func compareRegisters(a, b Registers) string {
+	for x := range a.V {
+		if a.V[x] != b.V[x] {
+			return fmt.Sprintf("V%X", x)
+		}
+	}
+	switch {
+	case a.PC != b.PC:
+		return "PC"
+	case a.I != b.I:
+		return "I"
+	case a.SP != b.SP:
+		return "SP"
+	case a.DT != b.DT:
+		return "DT"
+	case a.ST != b.ST:
+		return "ST"
+	}
+	return ""
+}
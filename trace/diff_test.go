@@ -0,0 +1,36 @@
+package trace
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	a := record(t, program, 10, FormatJSON, Filter{})
+	d, err := Diff(strings.NewReader(a), strings.NewReader(a))
+	assert.NoError(t, err)
+	assert.Nil(t, d)
+
+	lines := strings.Split(a, "\n")
+	changed := strings.Replace(lines[3], `"v":[0,5,`, `"v":[0,6,`, 2)
+	changed = strings.Replace(changed, `"mnemonic":"LD [I], V1",`, "", 1)
+	b := strings.Join(append(append(lines[:3:3], changed), lines[4:]...), "\n")
+	d, err = Diff(strings.NewReader(a), strings.NewReader(b))
+	assert.NoError(t, err)
+	assert.Equal(t, &Divergence{4, "before.V1", lines[3], changed}, d)
+
+	d, _ = Diff(strings.NewReader(a), strings.NewReader(strings.Join(lines[:2], "\n")))
+	assert.Equal(t, 3, d.Line)
+	assert.Equal(t, "length", d.Field)
+	assert.Equal(t, "", d.B)
+
+	text := record(t, program, 10, FormatText, Filter{})
+	d, _ = Diff(strings.NewReader(text), strings.NewReader(strings.Replace(text, "V1=05", "V1=06", 1)))
+	assert.Equal(t, 1, d.Line)
+	assert.Contains(t, d.String(), "traces diverge at line 1: line differs")
+
+	_, err = Diff(strings.NewReader("{"), strings.NewReader("{"))
+	assert.Error(t, err)
+}
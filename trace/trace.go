@@ -0,0 +1,179 @@
+// Package trace writes the instructions a CPU executes as JSON Lines or
+// compact text, and compares traces to find where two runs diverge
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Nuxij/goch8p/cpu"
+)
+
+// Format picks how traces are written
+type Format int
+
+const (
+	// FormatJSON writes a Record as JSON per line
+	FormatJSON Format = iota
+	// FormatText writes a line per instruction showing only what it
+	// changed, as `0202 6105     LD V1, 0x05          V1=05`
+	FormatText
+)
+
+// Formats names each of the formats, for picking one from configuration
+var Formats = map[string]Format{
+	"json": FormatJSON,
+	"text": FormatText,
+}
+
+// Filter picks the instructions to trace
+type Filter struct {
+	// From and To bound the addresses traced, inclusive, To being
+	// unbounded if zero
+	From uint16
+	To   uint16
+	// Classes lists the opcode classes traced, by their top nibble, all
+	// being traced if empty
+	Classes []byte
+}
+
+// Match reports whether the filter lets t through
+func (f Filter) Match(t cpu.Trace) bool {
+	pc := t.Before.PC
+	if pc < f.From || f.To != 0 && pc > f.To {
+		return false
+	}
+	if len(f.Classes) == 0 {
+		return true
+	}
+	if len(t.Bytes) == 0 {
+		return false
+	}
+	for _, class := range f.Classes {
+		if t.Bytes[0]>>4 == class {
+			return true
+		}
+	}
+	return false
+}
+
+// Registers is the register file in a Record
+type Registers struct {
+	PC uint16   `json:"pc"`
+	I  uint16   `json:"i"`
+	SP int      `json:"sp"`
+	V  [16]byte `json:"v"`
+	DT byte     `json:"dt"`
+	ST byte     `json:"st"`
+}
+
+// Write is a byte an instruction wrote to memory
+type Write struct {
+	Addr  uint16 `json:"addr"`
+	Value byte   `json:"value"`
+}
+
+// Record is one instruction in a JSON Lines trace
+type Record struct {
+	PC uint16 `json:"pc"`
+	// Opcode is the instruction's bytes in hex
+	Opcode   string    `json:"opcode"`
+	Mnemonic string    `json:"mnemonic,omitempty"`
+	Before   Registers `json:"before"`
+	After    Registers `json:"after"`
+	Writes   []Write   `json:"writes,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// NewRecord records t
+func NewRecord(t cpu.Trace) Record {
+	r := Record{
+		PC:     t.Before.PC,
+		Opcode: fmt.Sprintf("%X", t.Bytes),
+		Before: Registers(t.Before),
+		After:  Registers(t.After),
+	}
+	if t.Instruction != nil {
+		r.Mnemonic = cpu.Format(t.Instruction, t.Bytes, nil)
+	}
+	for _, w := range t.Writes {
+		r.Writes = append(r.Writes, Write{w.Addr, w.Value})
+	}
+	if t.Err != nil {
+		r.Error = t.Err.Error()
+	}
+	return r
+}
+
+// Text writes the record as a line of FormatText, without the newline
+func (r Record) Text() string {
+	var changes []string
+	for x := range r.After.V {
+		if r.Before.V[x] != r.After.V[x] {
+			changes = append(changes, fmt.Sprintf("V%X=%02X", x, r.After.V[x]))
+		}
+	}
+	if r.Before.I != r.After.I {
+		changes = append(changes, fmt.Sprintf("I=%04X", r.After.I))
+	}
+	if r.Before.SP != r.After.SP {
+		changes = append(changes, fmt.Sprintf("SP=%d", r.After.SP))
+	}
+	if r.Before.DT != r.After.DT {
+		changes = append(changes, fmt.Sprintf("DT=%02X", r.After.DT))
+	}
+	if r.Before.ST != r.After.ST {
+		changes = append(changes, fmt.Sprintf("ST=%02X", r.After.ST))
+	}
+	// only jumps, calls, returns and skips move the PC unexpectedly
+	if r.After.PC != r.PC+uint16(len(r.Opcode)/2) {
+		changes = append(changes, fmt.Sprintf("PC=%04X", r.After.PC))
+	}
+	for _, w := range r.Writes {
+		changes = append(changes, fmt.Sprintf("[%04X]=%02X", w.Addr, w.Value))
+	}
+	if r.Error != "" {
+		changes = append(changes, "error: "+r.Error)
+	}
+	return strings.TrimRight(fmt.Sprintf("%04X %-8s %-20s %s", r.PC, r.Opcode, r.Mnemonic, strings.Join(changes, " ")), " ")
+}
+
+// Writer writes the traces a CPU sends it that pass its filter
+type Writer struct {
+	w      io.Writer
+	format Format
+	filter Filter
+	// err is the first write to fail, after which nothing more is written
+	err error
+}
+
+// NewWriter writes traces to w in format, filtered by filter. Hand its
+// Trace method to cpu.CPU.Trace.
+func NewWriter(w io.Writer, format Format, filter Filter) *Writer {
+	return &Writer{w: w, format: format, filter: filter}
+}
+
+// Trace writes t, if it passes the filter
+func (w *Writer) Trace(t cpu.Trace) {
+	if w.err != nil || !w.filter.Match(t) {
+		return
+	}
+	r := NewRecord(t)
+	if w.format == FormatText {
+		_, w.err = fmt.Fprintln(w.w, r.Text())
+		return
+	}
+	line, err := json.Marshal(r)
+	if err != nil {
+		w.err = err
+		return
+	}
+	_, w.err = w.w.Write(append(line, '\n'))
+}
+
+// Err returns the first error writing the trace
+func (w *Writer) Err() error {
+	return w.err
+}
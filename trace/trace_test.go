@@ -0,0 +1,72 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Nuxij/goch8p/asm"
+	"github.com/Nuxij/goch8p/cpu"
+	"github.com/stretchr/testify/assert"
+)
+
+// record runs program for steps instructions, tracing to a writer
+func record(t *testing.T, program string, steps int, format Format, filter Filter) string {
+	c := cpu.NewCPU(cpu.NewRAM(0x1000))
+	p := asm.MustAssemble(program, asm.Options{})
+	assert.NoError(t, c.Memory().Poke(p.Origin, p.ROM))
+	var out bytes.Buffer
+	w := NewWriter(&out, format, filter)
+	c.Trace(w.Trace)
+	for n := 0; n < steps; n++ {
+		if c.Step() != nil {
+			break
+		}
+	}
+	assert.NoError(t, w.Err())
+	return out.String()
+}
+
+const program = `
+    LD V1, 5
+    LD I, 0x300
+    CALL store
+    db 0, 0
+store:
+    LD [I], V1
+    RET
+`
+
+func TestWriter_text(t *testing.T) {
+	want := `0200 6105     LD V1, 0x05          V1=05
+0202 A300     LD I, 0x300          I=0300
+0204 2208     CALL 0x208           SP=1 PC=0208
+0208 F155     LD [I], V1           I=0302 [0300]=00 [0301]=05
+020A 00EE     RET                  SP=0 PC=0206
+0206 0000                          error: unknown instruction: 0
+`
+	assert.Equal(t, want, record(t, program, 10, FormatText, Filter{}))
+}
+
+func TestWriter_json(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(record(t, program, 10, FormatJSON, Filter{})), "\n")
+	assert.Len(t, lines, 6)
+	var r Record
+	assert.NoError(t, json.Unmarshal([]byte(lines[3]), &r))
+	assert.Equal(t, Record{
+		PC:       0x208,
+		Opcode:   "F155",
+		Mnemonic: "LD [I], V1",
+		Before:   Registers{PC: 0x208, I: 0x300, SP: 1, V: [16]byte{1: 5}},
+		After:    Registers{PC: 0x20A, I: 0x302, SP: 1, V: [16]byte{1: 5}},
+		Writes:   []Write{{0x300, 0}, {0x301, 5}},
+	}, r)
+}
+
+func TestFilter(t *testing.T) {
+	assert.Equal(t, "0208 F155     LD [I], V1           I=0302 [0300]=00 [0301]=05\n020A 00EE     RET                  SP=0 PC=0206\n",
+		record(t, program, 10, FormatText, Filter{From: 0x208, To: 0x20A}))
+	assert.Equal(t, "0202 A300     LD I, 0x300          I=0300\n0204 2208     CALL 0x208           SP=1 PC=0208\n",
+		record(t, program, 10, FormatText, Filter{Classes: []byte{0xA, 0x2}}))
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceCommand(t *testing.T) {
+	dir := t.TempDir()
+	rom := filepath.Join(dir, "count.ch8")
+	// ADD V0, 1 then loop, loaded at 0x600
+	assert.NoError(t, os.WriteFile(rom, []byte{0x70, 0x01, 0x16, 0x00}, 0644))
+	ours, theirs := filepath.Join(dir, "ours.txt"), filepath.Join(dir, "theirs.txt")
+
+	var stdout, stderr strings.Builder
+	assert.Equal(t, 0, traceCommand([]string{"run", rom, "-origin", "0x600", "-format", "text", "-frames", "1", "-class", "7", "-o", ours}, &stdout, &stderr), stderr.String())
+	trace, err := os.ReadFile(ours)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(trace), "0600 7001"), string(trace))
+	assert.NotContains(t, string(trace), "1600", "only 7XNN should be traced")
+
+	assert.Equal(t, 0, traceCommand([]string{"diff", ours, ours}, &stdout, &stderr))
+	assert.Equal(t, "traces match\n", stdout.String())
+	assert.NoError(t, os.WriteFile(theirs, []byte("0600 7001\n"), 0644))
+	assert.Equal(t, 1, traceCommand([]string{"diff", ours, theirs}, &stdout, &stderr))
+
+	stderr.Reset()
+	assert.Equal(t, 2, traceCommand([]string{"run", "-class", "G", rom}, &stdout, &stderr))
+	assert.Equal(t, "bad opcode class \"G\"\n", stderr.String())
+	stderr.Reset()
+	assert.Equal(t, 2, traceCommand(nil, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "usage: goch8p trace")
+}